@@ -0,0 +1,237 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fmstephe/flib/funsafe"
+	"github.com/fmstephe/memorymanager/offheap/internal/pointerstore"
+)
+
+const defaultArenaChunkSize = 1 << 16
+
+// An Arena bump-allocates objects, slices and strings from large chunks of
+// memory acquired from a Store. The allocations can't be freed individually,
+// instead every allocation made by the Arena is released at once by calling
+// Reset() or Free().
+//
+// This is useful for groups of allocations, like request scoped graphs of
+// objects, which are built and then thrown away as a unit. Releasing the
+// entire group costs one operation per chunk, rather than one Free*() call per
+// allocation.
+//
+// The References returned by an Arena are the same RefObject, RefSlice and
+// RefString types returned by a Store. Every Reference shares the generation
+// of the chunk it was allocated from. Reset() and Free() invalidate the
+// chunks, so using a Reference after either call will panic. Like a Store
+// this is best effort, after 256 calls to Reset() the generation wraps around
+// and stale References will no longer be detected.
+//
+// References allocated by an Arena must never be passed to any of the Free*()
+// functions. Nor can they be passed to functions which resize or invalidate a
+// RefSlice or RefString, such as Append, Truncate or AppendString, because
+// those functions free or re-allocate the underlying allocation. Vectors must
+// not be allocated from an Arena for the same reason.
+//
+// An Arena is not safe for concurrent use.
+type Arena struct {
+	store     *Store
+	chunkSize int
+	// Chunks of chunkSize, chunks[current] is being allocated from and
+	// chunks after it are waiting to be reused following a Reset
+	chunks  []arenaChunk
+	current int
+	offset  int
+	// Chunks created for single allocations larger than chunkSize
+	largeChunks []arenaChunk
+}
+
+type arenaChunk struct {
+	ref pointerstore.RefPointer
+	idx int
+}
+
+// Returns a new Arena which acquires its chunks from s.
+func NewArena(s *Store) *Arena {
+	return NewArenaSized(s, defaultArenaChunkSize)
+}
+
+// Returns a new Arena which acquires its chunks from s.
+//
+// Each chunk will be chunkSize bytes. If chunkSize is not a power of two, then
+// chunkSize will be rounded up to the nearest power of two and then used.
+// Allocations which are larger than chunkSize will be given a dedicated chunk
+// of their own.
+func NewArenaSized(s *Store, chunkSize int) *Arena {
+	if chunkSize <= 0 {
+		panic(fmt.Errorf("arena chunk size must be positive, got %d", chunkSize))
+	}
+
+	return &Arena{
+		store:     s,
+		chunkSize: residentObjectSize(chunkSize),
+	}
+}
+
+// Allocates an object of type T in the Arena. The type T must not contain any
+// pointers in any part of its type. If the type T is found to contain pointers
+// this function will panic.
+//
+// The values of fields in the newly allocated object will be arbitrary, in the
+// same way as objects acquired via AllocObject.
+func ArenaAllocObject[T any](a *Arena) RefObject[T] {
+	if err := containsNoPointers[T](); err != nil {
+		panic(fmt.Errorf("cannot allocate generic type containing pointers %w", err))
+	}
+
+	t := reflect.TypeFor[T]()
+	pRef := a.alloc(int(t.Size()), t.Align())
+	return newRefObject[T](pRef)
+}
+
+// Allocates a new slice in the Arena with the desired length and capacity.
+// Unlike AllocSlice the capacity of the slice will be exactly
+// requestedCapacity.
+//
+// The contents of the slice will be arbitrary, in the same way as slices
+// acquired via AllocSlice.
+func ArenaAllocSlice[T any](a *Arena, length, requestedCapacity int) RefSlice[T] {
+	if err := containsNoPointers[T](); err != nil {
+		panic(fmt.Errorf("cannot allocate generic type containing pointers %w", err))
+	}
+	if length < 0 || length > requestedCapacity {
+		panic(fmt.Errorf("slice length %d out of range for capacity %d", length, requestedCapacity))
+	}
+
+	t := reflect.TypeFor[T]()
+	size := int(t.Size()) * requestedCapacity
+	if t.Size() != 0 && size/int(t.Size()) != requestedCapacity {
+		panic(fmt.Errorf("slice capacity %d of %s has overflowed int", requestedCapacity, t))
+	}
+
+	pRef := a.alloc(size, t.Align())
+	return newRefSlice[T](length, requestedCapacity, pRef)
+}
+
+// Allocates a new string in the Arena whose size and contents will be the same
+// as found in str.
+func ArenaAllocStringFromString(a *Arena, str string) RefString {
+	return ArenaAllocStringFromBytes(a, funsafe.StringToBytes(str))
+}
+
+// Allocates a new string in the Arena whose size and contents will be the same
+// as found in bytes.
+func ArenaAllocStringFromBytes(a *Arena, bytes []byte) RefString {
+	pRef := a.alloc(len(bytes), 1)
+	sRef := newRefString(len(bytes), pRef)
+
+	copy(sRef.ref.Bytes(len(bytes)), bytes)
+
+	return sRef
+}
+
+// Invalidates every allocation made by the Arena. After this call returns all
+// References allocated by the Arena must never be used again, and the Arena
+// can be used to make new allocations.
+//
+// The Arena keeps its chunks to be reused by new allocations. Dedicated
+// chunks for large allocations are returned to the Store.
+func (a *Arena) Reset() {
+	for i := range a.chunks {
+		a.chunks[i].ref = a.chunks[i].ref.Realloc()
+	}
+	a.current = 0
+	a.offset = 0
+
+	a.freeLargeChunks()
+}
+
+// Returns every chunk held by the Arena to the Store. After this call returns
+// all References allocated by the Arena must never be used again.
+//
+// The Arena can be used to make new allocations after Free, but it will need
+// to acquire new chunks from the Store.
+func (a *Arena) Free() {
+	for _, chunk := range a.chunks {
+		a.store.free(chunk.idx, chunk.ref)
+	}
+	a.chunks = a.chunks[:0]
+	a.current = 0
+	a.offset = 0
+
+	a.freeLargeChunks()
+}
+
+// Returns the number of chunks currently held by the Arena, including
+// dedicated chunks for large allocations.
+func (a *Arena) Chunks() int {
+	return len(a.chunks) + len(a.largeChunks)
+}
+
+func (a *Arena) freeLargeChunks() {
+	for _, chunk := range a.largeChunks {
+		a.store.free(chunk.idx, chunk.ref)
+	}
+	a.largeChunks = a.largeChunks[:0]
+}
+
+// Returns a reference to size bytes of memory, aligned to align bytes.
+func (a *Arena) alloc(size, align int) pointerstore.RefPointer {
+	// Zero sized allocations still occupy a byte, so that they point
+	// inside a chunk
+	size = max(size, 1)
+
+	if size > a.chunkSize {
+		return a.allocLarge(size)
+	}
+
+	offset := alignUp(a.offset, align)
+	if len(a.chunks) == 0 || offset+size > a.chunkSize {
+		a.nextChunk()
+		offset = 0
+	}
+	a.offset = offset + size
+
+	return a.chunks[a.current].ref.Interior(uint64(offset))
+}
+
+// Makes a new chunk the current chunk. Chunks retained by Reset are reused
+// before new chunks are acquired from the Store.
+func (a *Arena) nextChunk() {
+	if len(a.chunks) == 0 {
+		a.acquireChunk()
+		a.current = 0
+		return
+	}
+
+	if a.current+1 == len(a.chunks) {
+		a.acquireChunk()
+	}
+	a.current++
+}
+
+func (a *Arena) acquireChunk() {
+	idx := indexForSize(a.chunkSize)
+	a.chunks = append(a.chunks, arenaChunk{
+		ref: a.store.alloc(idx),
+		idx: idx,
+	})
+}
+
+func (a *Arena) allocLarge(size int) pointerstore.RefPointer {
+	idx := indexForSize(residentObjectSize(size))
+	chunk := arenaChunk{
+		ref: a.store.alloc(idx),
+		idx: idx,
+	}
+	a.largeChunks = append(a.largeChunks, chunk)
+	return chunk.ref.Interior(0)
+}
+
+func alignUp(offset, align int) int {
+	return (offset + align - 1) &^ (align - 1)
+}
@@ -0,0 +1,286 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type arenaNode struct {
+	value int64
+	next  RefObject[arenaNode]
+	name  RefString
+	data  RefSlice[int32]
+}
+
+func assertNoLiveAllocations(t *testing.T, s *Store) {
+	for _, stats := range s.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Demonstrate that we can build a linked structure of objects, strings and
+// slices in an Arena and read it back
+func Test_Arena_AllocateModifyAndGet(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<10)
+
+	var head RefObject[arenaNode]
+	for i := range 1000 {
+		r := ArenaAllocObject[arenaNode](a)
+		node := r.Value()
+		node.value = int64(i)
+		node.next = head
+		node.name = ArenaAllocStringFromString(a, fmt.Sprintf("node-%d", i))
+		node.data = ArenaAllocSlice[int32](a, i%10, 10)
+		for j := range node.data.Value() {
+			node.data.Value()[j] = int32(i + j)
+		}
+		head = r
+	}
+
+	// Many chunks were needed for all of these allocations
+	assert.Greater(t, a.Chunks(), 1)
+
+	for i := 999; i >= 0; i-- {
+		node := head.Value()
+		assert.Equal(t, int64(i), node.value)
+		assert.Equal(t, fmt.Sprintf("node-%d", i), node.name.Value())
+		assert.Equal(t, i%10, len(node.data.Value()))
+		assert.Equal(t, 10, cap(node.data.Value()))
+		for j, v := range node.data.Value() {
+			assert.Equal(t, int32(i+j), v)
+		}
+		head = node.next
+	}
+	assert.True(t, head.IsNil())
+
+	a.Free()
+	assertNoLiveAllocations(t, s)
+}
+
+// Demonstrate that every allocation is correctly aligned for its type
+func Test_Arena_Alignment(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<8)
+
+	for range 100 {
+		b := ArenaAllocObject[byte](a)
+		assert.Equal(t, uintptr(0), uintptr(unsafe.Pointer(b.Value()))%unsafe.Alignof(byte(0)))
+
+		i := ArenaAllocObject[int64](a)
+		assert.Equal(t, uintptr(0), uintptr(unsafe.Pointer(i.Value()))%unsafe.Alignof(int64(0)))
+
+		str := ArenaAllocStringFromString(a, "abc")
+		assert.Equal(t, "abc", str.Value())
+
+		slice := ArenaAllocSlice[int32](a, 3, 3)
+		assert.Equal(t, uintptr(0), uintptr(unsafe.Pointer(&slice.Value()[0]))%unsafe.Alignof(int32(0)))
+	}
+}
+
+// Demonstrate that allocations larger than the chunk size are given their own
+// chunk, which is released on Reset
+func Test_Arena_LargeAllocations(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<8)
+
+	small := ArenaAllocObject[int64](a)
+	*small.Value() = 1
+	large := ArenaAllocSlice[int64](a, 1000, 1000)
+	for i := range large.Value() {
+		large.Value()[i] = int64(i)
+	}
+	assert.Equal(t, 2, a.Chunks())
+
+	// The large allocation didn't disturb the current chunk
+	small2 := ArenaAllocObject[int64](a)
+	*small2.Value() = 2
+	assert.Equal(t, 2, a.Chunks())
+	assert.Equal(t, int64(1), *small.Value())
+	for i, v := range large.Value() {
+		assert.Equal(t, int64(i), v)
+	}
+
+	a.Reset()
+	assert.Equal(t, 1, a.Chunks())
+	assert.Panics(t, func() { large.Value() })
+
+	a.Free()
+	assertNoLiveAllocations(t, s)
+}
+
+// Demonstrate that zero sized allocations, empty slices and empty strings can
+// be allocated
+func Test_Arena_EmptyAllocations(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<8)
+
+	obj := ArenaAllocObject[struct{}](a)
+	assert.False(t, obj.IsNil())
+	assert.NotNil(t, obj.Value())
+
+	slice := ArenaAllocSlice[int64](a, 0, 0)
+	assert.False(t, slice.IsNil())
+	assert.Empty(t, slice.Value())
+
+	str := ArenaAllocStringFromString(a, "")
+	assert.False(t, str.IsNil())
+	assert.Equal(t, "", str.Value())
+}
+
+// Demonstrate that References allocated before a Reset panic when used
+func Test_Arena_Reset_StaleReferencesPanic(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<8)
+
+	objects := []RefObject[int64]{}
+	slices := []RefSlice[int64]{}
+	strs := []RefString{}
+	for i := range 100 {
+		objects = append(objects, ArenaAllocObject[int64](a))
+		slices = append(slices, ArenaAllocSlice[int64](a, 2, 2))
+		strs = append(strs, ArenaAllocStringFromString(a, fmt.Sprintf("%d", i)))
+	}
+
+	a.Reset()
+
+	for i := range objects {
+		assert.Panics(t, func() { objects[i].Value() })
+		assert.Panics(t, func() { slices[i].Value() })
+		assert.Panics(t, func() { strs[i].Value() })
+	}
+
+	// New allocations after the reset are valid
+	r := ArenaAllocObject[int64](a)
+	*r.Value() = 10
+	assert.Equal(t, int64(10), *r.Value())
+}
+
+// Demonstrate that References allocated before a Free panic when used
+func Test_Arena_Free_StaleReferencesPanic(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<8)
+
+	obj := ArenaAllocObject[int64](a)
+	str := ArenaAllocStringFromString(a, "freed")
+	large := ArenaAllocSlice[int64](a, 100, 100)
+
+	a.Free()
+
+	assert.Panics(t, func() { obj.Value() })
+	assert.Panics(t, func() { str.Value() })
+	assert.Panics(t, func() { large.Value() })
+	assertNoLiveAllocations(t, s)
+}
+
+// Demonstrate that Reset reuses the Arena's chunks, rather than acquiring new
+// chunks from the Store
+func Test_Arena_Reset_ReusesChunks(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArenaSized(s, 1<<8)
+
+	fill := func() {
+		for range 1000 {
+			ArenaAllocObject[int64](a)
+		}
+	}
+
+	fill()
+	chunks := a.Chunks()
+	allocs := StatsForSlice[byte](s, 1<<8).Allocs
+
+	for range 10 {
+		a.Reset()
+		fill()
+		assert.Equal(t, chunks, a.Chunks())
+		assert.Equal(t, allocs, StatsForSlice[byte](s, 1<<8).Allocs)
+	}
+
+	a.Free()
+	assertNoLiveAllocations(t, s)
+
+	// The arena can still be used after Free
+	r := ArenaAllocObject[int64](a)
+	*r.Value() = 3
+	assert.Equal(t, int64(3), *r.Value())
+	a.Free()
+}
+
+func Test_Arena_BadArguments_Panic(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	assert.Panics(t, func() { NewArenaSized(s, 0) })
+
+	a := NewArena(s)
+	assert.Panics(t, func() { ArenaAllocObject[*int](a) })
+	assert.Panics(t, func() { ArenaAllocSlice[string](a, 1, 1) })
+	assert.Panics(t, func() { ArenaAllocSlice[int](a, 2, 1) })
+	assert.Panics(t, func() { ArenaAllocSlice[int](a, -1, 1) })
+}
+
+// Demonstrate that a RefObject allocated in an Arena can be converted to and
+// from a RefRaw
+func Test_Arena_RefRaw(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArena(s)
+
+	r := ArenaAllocObject[int64](a)
+	*r.Value() = 42
+
+	raw := r.Raw()
+	r2, ok := AsObject[int64](raw)
+	require.True(t, ok)
+	assert.Equal(t, int64(42), *r2.Value())
+}
+
+// Assert that allocating from an Arena with available chunks does not
+// allocate on the Go heap
+func Test_Arena_NoAllocations(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	a := NewArena(s)
+	// Acquire the first chunk
+	ArenaAllocObject[int64](a)
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		ArenaAllocStringFromString(a, "string")
+		a.Reset()
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
@@ -0,0 +1,285 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The columns package provides a columnar, struct-of-arrays, table whose
+// column data is allocated in an offheap.Store. Each column is a RefSlice of a
+// fixed-width, pointer free, type and every column in a table always has the
+// same number of rows.
+//
+// Because the column data lives in an offheap.Store, very large tables have
+// no impact on the cost of garbage collection.
+package columns
+
+import (
+	"fmt"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A Table is a set of named columns which all have the same number of rows.
+//
+// The Table itself, and the Column values it returns, live on the Go heap. The
+// row data for each column is allocated in the offheap.Store.
+type Table struct {
+	store   *offheap.Store
+	rows    int
+	columns []column
+	byName  map[string]int
+}
+
+// The untyped behaviour that a Table needs from each of its columns.
+type column interface {
+	name() string
+	grow(s *offheap.Store, extra int)
+	free(s *offheap.Store)
+}
+
+// Creates a new empty Table. All column data will be allocated in store.
+func New(store *offheap.Store) *Table {
+	return &Table{
+		store:  store,
+		byName: make(map[string]int),
+	}
+}
+
+// Adds a new column of type T named name to table. If table already contains
+// rows then the new column will be populated with the zero value of T for
+// each existing row.
+//
+// An error is returned if table already contains a column with this name. If
+// T contains pointers this function will panic.
+func AddColumn[T any](table *Table, name string) (*Column[T], error) {
+	if _, ok := table.byName[name]; ok {
+		return nil, fmt.Errorf("column %q already exists", name)
+	}
+
+	slice := offheap.AllocSlice[T](table.store, table.rows, table.rows)
+	clear(slice.Value())
+
+	col := &Column[T]{
+		colName: name,
+		slice:   slice,
+	}
+
+	table.byName[name] = len(table.columns)
+	table.columns = append(table.columns, col)
+	return col, nil
+}
+
+// Returns the column named name. An error is returned if there is no column
+// with this name, or if the column does not have type T.
+func GetColumn[T any](table *Table, name string) (*Column[T], error) {
+	idx, ok := table.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("column %q does not exist", name)
+	}
+
+	col, ok := table.columns[idx].(*Column[T])
+	if !ok {
+		return nil, fmt.Errorf("column %q has type %T, not %T", name, table.columns[idx], col)
+	}
+	return col, nil
+}
+
+// Appends a single row to the table and returns its index. Every column has
+// the zero value of its type appended.
+func (t *Table) AppendRow() int {
+	return t.AppendRows(1)
+}
+
+// Appends count rows to the table and returns the index of the first new
+// row. Every column has the zero value of its type appended for each new row.
+//
+// To bulk append from Go slices use AppendColumns.
+func (t *Table) AppendRows(count int) int {
+	if count < 0 {
+		panic(fmt.Errorf("cannot append negative (%d) rows", count))
+	}
+
+	first := t.rows
+	for _, col := range t.columns {
+		col.grow(t.store, count)
+	}
+	t.rows += count
+	return first
+}
+
+// Appends rows to the table from Go slices, and returns the index of the first
+// new row, e.g.
+//
+//	first := table.AppendColumns(
+//		columns.Values(timestamps, []int64{10, 20, 30}),
+//		columns.Values(prices, []float64{1.5, 2.5, 3.5}),
+//	)
+//
+// Each slice is copied directly onto the end of its column. Every column of
+// the table without values has the zero value of its type appended for each
+// new row.
+//
+// All of values must have the same length, and each column must belong to
+// this table and appear at most once, otherwise this method will panic.
+func (t *Table) AppendColumns(values ...ColumnValues) int {
+	count := 0
+	if len(values) > 0 {
+		count = values[0].length()
+	}
+
+	appended := make([]bool, len(t.columns))
+	for _, v := range values {
+		col := v.column()
+		if v.length() != count {
+			panic(fmt.Errorf("cannot append %d values to column %q, expected %d", v.length(), col.name(), count))
+		}
+
+		idx, ok := t.byName[col.name()]
+		if !ok || t.columns[idx] != col {
+			panic(fmt.Errorf("column %q does not belong to this table", col.name()))
+		}
+		if appended[idx] {
+			panic(fmt.Errorf("column %q has values appended more than once", col.name()))
+		}
+		appended[idx] = true
+	}
+
+	first := t.rows
+	for _, v := range values {
+		v.appendTo(t.store)
+	}
+	for idx, col := range t.columns {
+		if !appended[idx] {
+			col.grow(t.store, count)
+		}
+	}
+	t.rows += count
+	return first
+}
+
+// Returns the number of rows in the table.
+func (t *Table) Len() int {
+	return t.rows
+}
+
+// Returns the names of the columns in this table, in the order they were
+// added.
+func (t *Table) Columns() []string {
+	names := make([]string, 0, len(t.columns))
+	for _, col := range t.columns {
+		names = append(names, col.name())
+	}
+	return names
+}
+
+// Frees all of the column data for this table. After this call returns the
+// table, and all of its columns, must never be used again.
+func (t *Table) Free() {
+	for _, col := range t.columns {
+		col.free(t.store)
+	}
+	t.columns = nil
+	t.byName = nil
+	t.rows = 0
+}
+
+// The values to be appended to a single column by Table.AppendColumns.
+type ColumnValues interface {
+	column() column
+	length() int
+	appendTo(s *offheap.Store)
+}
+
+// Pairs col with the values to be appended to it by Table.AppendColumns.
+func Values[T any](col *Column[T], values []T) ColumnValues {
+	return columnValues[T]{
+		col:    col,
+		values: values,
+	}
+}
+
+type columnValues[T any] struct {
+	col    *Column[T]
+	values []T
+}
+
+func (v columnValues[T]) column() column {
+	return v.col
+}
+
+func (v columnValues[T]) length() int {
+	return len(v.values)
+}
+
+func (v columnValues[T]) appendTo(s *offheap.Store) {
+	v.col.slice = offheap.AppendSlice(s, v.col.slice, v.values)
+}
+
+// A typed column in a Table.
+type Column[T any] struct {
+	colName string
+	slice   offheap.RefSlice[T]
+}
+
+// Returns the name of this column.
+func (c *Column[T]) Name() string {
+	return c.colName
+}
+
+// Returns the value of this column at row.
+func (c *Column[T]) Get(row int) T {
+	return c.slice.Value()[row]
+}
+
+// Sets the value of this column at row.
+func (c *Column[T]) Set(row int, value T) {
+	c.slice.Value()[row] = value
+}
+
+// Copies values into this column starting at first. If there are not enough
+// rows in the table to hold all of the values this method will panic.
+func (c *Column[T]) SetRange(first int, values []T) {
+	rows := c.slice.Value()
+	if first < 0 || first+len(values) > len(rows) {
+		panic(fmt.Errorf("cannot set %d values from row %d in column with %d rows", len(values), first, len(rows)))
+	}
+	copy(rows[first:], values)
+}
+
+// Returns the raw slice holding the data for this column. The slice has one
+// element for each row in the table.
+//
+// This is the most efficient way to iterate over, or mutate, a column. A
+// simple loop over the slice is easy for the compiler to optimise.
+//
+// Care must be taken not to use this slice after rows are appended to the
+// table, or after the table is freed.
+func (c *Column[T]) Values() []T {
+	return c.slice.Value()
+}
+
+func (c *Column[T]) name() string {
+	return c.colName
+}
+
+func (c *Column[T]) grow(s *offheap.Store, extra int) {
+	values := c.slice.Value()
+	oldLen := len(values)
+	newLen := oldLen + extra
+
+	if newLen <= cap(values) {
+		c.slice = offheap.SetLen(s, c.slice, newLen)
+	} else {
+		// Allocating with length equal to capacity lets AllocSlice
+		// round the capacity up to a power of two, giving us
+		// amortised growth
+		newSlice := offheap.AllocSlice[T](s, newLen, newLen)
+		copy(newSlice.Value(), values)
+		offheap.FreeSlice(s, c.slice)
+		c.slice = newSlice
+	}
+
+	clear(c.slice.Value()[oldLen:])
+}
+
+func (c *Column[T]) free(s *offheap.Store) {
+	offheap.FreeSlice(s, c.slice)
+}
@@ -0,0 +1,284 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package columns
+
+import (
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type metric struct {
+	count int64
+	sum   float64
+}
+
+// Show that a new table has no rows or columns
+func TestTable_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	assert.Equal(t, 0, table.Len())
+	assert.Equal(t, []string{}, table.Columns())
+}
+
+// Show that we can add columns of different types and append rows to them
+func TestTable_AppendRow(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	timestamps, err := AddColumn[int64](table, "timestamp")
+	require.NoError(t, err)
+	values, err := AddColumn[float64](table, "value")
+	require.NoError(t, err)
+	metrics, err := AddColumn[metric](table, "metric")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"timestamp", "value", "metric"}, table.Columns())
+
+	for i := range 1000 {
+		row := table.AppendRow()
+		require.Equal(t, i, row)
+
+		// New rows always contain zero values
+		require.Equal(t, int64(0), timestamps.Get(row))
+		require.Equal(t, 0.0, values.Get(row))
+		require.Equal(t, metric{}, metrics.Get(row))
+
+		timestamps.Set(row, int64(i))
+		values.Set(row, float64(i)/2)
+		metrics.Set(row, metric{count: int64(i), sum: float64(i)})
+	}
+
+	assert.Equal(t, 1000, table.Len())
+	for i := range 1000 {
+		assert.Equal(t, int64(i), timestamps.Get(i))
+		assert.Equal(t, float64(i)/2, values.Get(i))
+		assert.Equal(t, metric{count: int64(i), sum: float64(i)}, metrics.Get(i))
+	}
+
+	// Every column has a value for every row
+	assert.Equal(t, 1000, len(timestamps.Values()))
+	assert.Equal(t, 1000, len(values.Values()))
+	assert.Equal(t, 1000, len(metrics.Values()))
+}
+
+// Show that we can append empty rows and then fill them using SetRange
+func TestTable_AppendRows(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	timestamps, err := AddColumn[int64](table, "timestamp")
+	require.NoError(t, err)
+	values, err := AddColumn[float64](table, "value")
+	require.NoError(t, err)
+
+	timestampBatch := []int64{10, 20, 30}
+	valueBatch := []float64{1.5, 2.5, 3.5}
+
+	for range 10 {
+		first := table.AppendRows(len(timestampBatch))
+		timestamps.SetRange(first, timestampBatch)
+		values.SetRange(first, valueBatch)
+	}
+
+	assert.Equal(t, 30, table.Len())
+
+	sum := 0.0
+	for _, value := range values.Values() {
+		sum += value
+	}
+	assert.Equal(t, 75.0, sum)
+
+	total := int64(0)
+	for _, timestamp := range timestamps.Values() {
+		total += timestamp
+	}
+	assert.Equal(t, int64(600), total)
+
+	// Can't set a range beyond the end of the table
+	assert.Panics(t, func() { timestamps.SetRange(29, timestampBatch) })
+	assert.Panics(t, func() { table.AppendRows(-1) })
+}
+
+// Show that we can bulk append rows directly from Go slices
+func TestTable_AppendColumns(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	timestamps, err := AddColumn[int64](table, "timestamp")
+	require.NoError(t, err)
+	values, err := AddColumn[float64](table, "value")
+	require.NoError(t, err)
+	metrics, err := AddColumn[metric](table, "metric")
+	require.NoError(t, err)
+
+	expectedTimestamps := []int64{}
+	expectedValues := []float64{}
+	for i := range 100 {
+		timestampBatch := []int64{int64(i), int64(i) + 1, int64(i) + 2}
+		valueBatch := []float64{float64(i) / 2, float64(i) / 3, float64(i) / 4}
+
+		first := table.AppendColumns(
+			Values(timestamps, timestampBatch),
+			Values(values, valueBatch),
+		)
+		require.Equal(t, i*3, first)
+
+		expectedTimestamps = append(expectedTimestamps, timestampBatch...)
+		expectedValues = append(expectedValues, valueBatch...)
+	}
+
+	assert.Equal(t, 300, table.Len())
+	assert.Equal(t, expectedTimestamps, timestamps.Values())
+	assert.Equal(t, expectedValues, values.Values())
+	// Columns without values are populated with zero values
+	assert.Equal(t, make([]metric, 300), metrics.Values())
+
+	// Appending no values appends no rows
+	assert.Equal(t, 300, table.AppendColumns())
+	assert.Equal(t, 300, table.Len())
+}
+
+// Show that AppendColumns panics if the values would leave columns with
+// different numbers of rows
+func TestTable_AppendColumns_Invalid(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	timestamps, err := AddColumn[int64](table, "timestamp")
+	require.NoError(t, err)
+	values, err := AddColumn[float64](table, "value")
+	require.NoError(t, err)
+
+	other := New(store)
+	defer other.Free()
+	otherTimestamps, err := AddColumn[int64](other, "timestamp")
+	require.NoError(t, err)
+
+	// Different lengths
+	assert.Panics(t, func() {
+		table.AppendColumns(Values(timestamps, []int64{1, 2}), Values(values, []float64{1}))
+	})
+	// The same column twice
+	assert.Panics(t, func() {
+		table.AppendColumns(Values(timestamps, []int64{1}), Values(timestamps, []int64{2}))
+	})
+	// A column from another table
+	assert.Panics(t, func() {
+		table.AppendColumns(Values(otherTimestamps, []int64{1}))
+	})
+
+	// The table is unchanged
+	assert.Equal(t, 0, table.Len())
+	assert.Empty(t, timestamps.Values())
+	assert.Empty(t, values.Values())
+}
+
+// Show that columns added to a table which already has rows are populated with
+// zero values
+func TestTable_AddColumnWithRows(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	first, err := AddColumn[int64](table, "first")
+	require.NoError(t, err)
+	table.AppendRows(10)
+	for i := range 10 {
+		first.Set(i, int64(i+1))
+	}
+
+	second, err := AddColumn[int32](table, "second")
+	require.NoError(t, err)
+	assert.Equal(t, make([]int32, 10), second.Values())
+
+	table.AppendRow()
+	assert.Equal(t, 11, len(first.Values()))
+	assert.Equal(t, 11, len(second.Values()))
+}
+
+func TestTable_GetColumn(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	col, err := AddColumn[int64](table, "col")
+	require.NoError(t, err)
+
+	// Adding a duplicate column fails
+	_, err = AddColumn[int64](table, "col")
+	assert.Error(t, err)
+
+	// We get the same column back
+	found, err := GetColumn[int64](table, "col")
+	assert.NoError(t, err)
+	assert.Same(t, col, found)
+	assert.Equal(t, "col", found.Name())
+
+	// Missing column
+	_, err = GetColumn[int64](table, "missing")
+	assert.Error(t, err)
+
+	// Wrong type
+	_, err = GetColumn[float64](table, "col")
+	assert.Error(t, err)
+}
+
+// Show that freeing a table frees all of its column allocations
+func TestTable_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	col, err := AddColumn[int64](table, "col")
+	require.NoError(t, err)
+	table.AppendRows(100)
+
+	values := offheap.StatsForSlice[int64](store, 128)
+	assert.Equal(t, 1, values.Live)
+
+	table.Free()
+
+	values = offheap.StatsForSlice[int64](store, 128)
+	assert.Equal(t, 0, values.Live)
+	assert.Panics(t, func() { col.Values() })
+}
+
+// Pointerful column types can't be added
+func TestTable_AddColumn_Pointers(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	table := New(store)
+
+	assert.Panics(t, func() {
+		_, _ = AddColumn[*int](table, "pointers")
+	})
+}
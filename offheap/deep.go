@@ -0,0 +1,341 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap/internal/pointerstore"
+)
+
+// Frees the object referenced by r, and every allocation reachable from it.
+//
+// The fields of T, including fields of nested structs and arrays, are searched
+// for RefObject, RefSlice, RefString and RefRaw values. Each non-nil
+// Reference found is freed, after recursively freeing everything reachable
+// from the object or slice elements it references. Allocations which are
+// reachable along more than one path, including cycles, are freed exactly
+// once.
+//
+// Every Reference reachable from r must be either nil or valid. Because
+// AllocObject and AllocSlice do not zero out their allocations, Reference
+// fields which are never assigned will hold arbitrary values and must be set
+// to nil before calling FreeDeep. The allocation referenced by a RefRaw is
+// freed, but it is not searched because its type is unknown.
+//
+// After this call returns r, and every Reference reachable from r, must never
+// be used again. References allocated by an Arena must never be passed to
+// FreeDeep.
+func FreeDeep[T any](s *Store, r RefObject[T]) {
+	f := &deepFreer{
+		store:   s,
+		visited: map[pointerstore.RefPointer]struct{}{},
+	}
+	r.freeDeep(f)
+}
+
+// Allocates a copy of the object referenced by r, and of every allocation
+// reachable from it. The copies reference each other in the same way the
+// originals do, so shared allocations and cycles are preserved in the copy.
+//
+// The fields of T are searched for References in the same way as FreeDeep,
+// and the same requirements apply. The allocation referenced by a RefRaw is
+// copied byte for byte, but it is not searched because its type is unknown.
+//
+// The original graph remains valid after this function returns.
+func CloneDeep[T any](s *Store, r RefObject[T]) RefObject[T] {
+	c := &deepCloner{
+		store:  s,
+		cloned: map[pointerstore.RefPointer]pointerstore.RefPointer{},
+	}
+	clone := r
+	clone.cloneDeep(c)
+	return clone
+}
+
+// Implemented by the Reference types. When a field of one of these types is
+// found in an allocation FreeDeep and CloneDeep will follow it.
+type deepReference interface {
+	freeDeep(f *deepFreer)
+	cloneDeep(c *deepCloner)
+	// Returns the walkers for References with the same type as the
+	// receiver. The receiver itself is not used.
+	deepWalkers() deepWalkers
+}
+
+// Functions which call freeDeep or cloneDeep on the Reference at ptr. Calling
+// these avoids converting each Reference found into an interface value, which
+// would allocate.
+type deepWalkers struct {
+	free  func(ptr unsafe.Pointer, f *deepFreer)
+	clone func(ptr unsafe.Pointer, c *deepCloner)
+}
+
+type deepFreer struct {
+	store *Store
+	// References to the same live allocation are always equal, so we can
+	// identify allocations by their Reference. This allows us to recognise
+	// allocations which have already been freed without accessing them.
+	visited map[pointerstore.RefPointer]struct{}
+}
+
+// Returns true if ref has not been visited before, and marks it as visited.
+func (f *deepFreer) visit(ref *pointerstore.RefPointer) bool {
+	if _, ok := f.visited[*ref]; ok {
+		return false
+	}
+	f.visited[*ref] = struct{}{}
+	return true
+}
+
+type deepCloner struct {
+	store *Store
+	// Maps the Reference of each original allocation to its clone
+	cloned map[pointerstore.RefPointer]pointerstore.RefPointer
+}
+
+// Returns the clone of ref if it has already been cloned.
+func (c *deepCloner) lookup(ref *pointerstore.RefPointer) (pointerstore.RefPointer, bool) {
+	clone, ok := c.cloned[*ref]
+	return clone, ok
+}
+
+func (c *deepCloner) record(ref *pointerstore.RefPointer, clone pointerstore.RefPointer) {
+	c.cloned[*ref] = clone
+}
+
+func (r *RefObject[T]) deepWalkers() deepWalkers {
+	return deepWalkers{
+		free: func(ptr unsafe.Pointer, f *deepFreer) {
+			(*RefObject[T])(ptr).freeDeep(f)
+		},
+		clone: func(ptr unsafe.Pointer, c *deepCloner) {
+			(*RefObject[T])(ptr).cloneDeep(c)
+		},
+	}
+}
+
+func (r *RefObject[T]) freeDeep(f *deepFreer) {
+	if r.IsNil() || !f.visit(&r.ref) {
+		return
+	}
+
+	freeDeepFields[T](unsafe.Pointer(r.Value()), 1, f)
+	FreeObject(f.store, *r)
+}
+
+// Replaces r with a reference to a deep clone of the object it references.
+func (r *RefObject[T]) cloneDeep(c *deepCloner) {
+	if r.IsNil() {
+		return
+	}
+	if clone, ok := c.lookup(&r.ref); ok {
+		r.ref = clone
+		return
+	}
+
+	clone := AllocObject[T](c.store)
+	c.record(&r.ref, clone.ref)
+	*clone.Value() = *r.Value()
+	r.ref = clone.ref
+
+	cloneDeepFields[T](unsafe.Pointer(clone.Value()), 1, c)
+}
+
+func (r *RefSlice[T]) deepWalkers() deepWalkers {
+	return deepWalkers{
+		free: func(ptr unsafe.Pointer, f *deepFreer) {
+			(*RefSlice[T])(ptr).freeDeep(f)
+		},
+		clone: func(ptr unsafe.Pointer, c *deepCloner) {
+			(*RefSlice[T])(ptr).cloneDeep(c)
+		},
+	}
+}
+
+func (r *RefSlice[T]) freeDeep(f *deepFreer) {
+	if r.IsNil() || !f.visit(&r.ref) {
+		return
+	}
+
+	if slice := r.Value(); len(slice) > 0 {
+		freeDeepFields[T](unsafe.Pointer(&slice[0]), len(slice), f)
+	}
+	FreeSlice(f.store, *r)
+}
+
+// Replaces r with a reference to a deep clone of the slice it references. The
+// clone has the same length and capacity as the original.
+func (r *RefSlice[T]) cloneDeep(c *deepCloner) {
+	if r.IsNil() {
+		return
+	}
+	if clone, ok := c.lookup(&r.ref); ok {
+		r.ref = clone
+		return
+	}
+
+	clone := AllocSlice[T](c.store, r.length, r.capacity)
+	c.record(&r.ref, clone.ref)
+	copy(clone.Value(), r.Value())
+	r.ref = clone.ref
+
+	if slice := clone.Value(); len(slice) > 0 {
+		cloneDeepFields[T](unsafe.Pointer(&slice[0]), len(slice), c)
+	}
+}
+
+func (r *RefString) deepWalkers() deepWalkers {
+	return deepWalkers{
+		free: func(ptr unsafe.Pointer, f *deepFreer) {
+			(*RefString)(ptr).freeDeep(f)
+		},
+		clone: func(ptr unsafe.Pointer, c *deepCloner) {
+			(*RefString)(ptr).cloneDeep(c)
+		},
+	}
+}
+
+func (r *RefString) freeDeep(f *deepFreer) {
+	if r.IsNil() || !f.visit(&r.ref) {
+		return
+	}
+
+	FreeString(f.store, *r)
+}
+
+// Replaces r with a reference to a clone of the string it references.
+func (r *RefString) cloneDeep(c *deepCloner) {
+	if r.IsNil() {
+		return
+	}
+	if clone, ok := c.lookup(&r.ref); ok {
+		r.ref = clone
+		return
+	}
+
+	clone := AllocStringFromString(c.store, r.Value())
+	c.record(&r.ref, clone.ref)
+	r.ref = clone.ref
+}
+
+func (r *RefRaw) deepWalkers() deepWalkers {
+	return deepWalkers{
+		free: func(ptr unsafe.Pointer, f *deepFreer) {
+			(*RefRaw)(ptr).freeDeep(f)
+		},
+		clone: func(ptr unsafe.Pointer, c *deepCloner) {
+			(*RefRaw)(ptr).cloneDeep(c)
+		},
+	}
+}
+
+func (r *RefRaw) freeDeep(f *deepFreer) {
+	if r.IsNil() || !f.visit(&r.ref) {
+		return
+	}
+
+	Free(f.store, *r)
+}
+
+// Replaces r with a reference to a byte for byte clone of the allocation it
+// references.
+func (r *RefRaw) cloneDeep(c *deepCloner) {
+	if r.IsNil() {
+		return
+	}
+	if clone, ok := c.lookup(&r.ref); ok {
+		r.ref = clone
+		return
+	}
+
+	size := 1 << r.idx
+	clone := c.store.alloc(int(r.idx))
+	c.record(&r.ref, clone)
+	copy(clone.Bytes(size), r.ref.Bytes(size))
+	r.ref = clone
+}
+
+// Calls freeDeep on every Reference found in count consecutive values of type
+// T, starting at ptr.
+func freeDeepFields[T any](ptr unsafe.Pointer, count int, f *deepFreer) {
+	t := reflect.TypeFor[T]()
+	fields := deepFieldsFor(t)
+	if len(fields) == 0 {
+		return
+	}
+
+	for i := range count {
+		base := unsafe.Add(ptr, uintptr(i)*t.Size())
+		for _, field := range fields {
+			field.walkers.free(unsafe.Add(base, field.offset), f)
+		}
+	}
+}
+
+// Calls cloneDeep on every Reference found in count consecutive values of
+// type T, starting at ptr.
+func cloneDeepFields[T any](ptr unsafe.Pointer, count int, c *deepCloner) {
+	t := reflect.TypeFor[T]()
+	fields := deepFieldsFor(t)
+	if len(fields) == 0 {
+		return
+	}
+
+	for i := range count {
+		base := unsafe.Add(ptr, uintptr(i)*t.Size())
+		for _, field := range fields {
+			field.walkers.clone(unsafe.Add(base, field.offset), c)
+		}
+	}
+}
+
+// Describes the location of a Reference inside another type, and how to walk
+// it.
+type deepField struct {
+	offset  uintptr
+	walkers deepWalkers
+}
+
+var (
+	deepReferenceType = reflect.TypeFor[deepReference]()
+	// Caches the []deepField for each type searched
+	deepFieldsCache sync.Map
+)
+
+// Returns the location of every Reference found in the type t. The result is
+// cached, so each type is only searched once.
+func deepFieldsFor(t reflect.Type) []deepField {
+	if fields, ok := deepFieldsCache.Load(t); ok {
+		return fields.([]deepField)
+	}
+
+	fields := collectDeepFields(t, 0, nil)
+	deepFieldsCache.Store(t, fields)
+	return fields
+}
+
+func collectDeepFields(t reflect.Type, offset uintptr, fields []deepField) []deepField {
+	if reflect.PointerTo(t).Implements(deepReferenceType) {
+		walkers := reflect.New(t).Interface().(deepReference).deepWalkers()
+		return append(fields, deepField{offset: offset, walkers: walkers})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := range t.NumField() {
+			field := t.Field(i)
+			fields = collectDeepFields(field.Type, offset+field.Offset, fields)
+		}
+	case reflect.Array:
+		elem := t.Elem()
+		for i := range t.Len() {
+			fields = collectDeepFields(elem, offset+uintptr(i)*elem.Size(), fields)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,314 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deepLeaf struct {
+	value int64
+	name  RefString
+}
+
+type deepNode struct {
+	value    int64
+	name     RefString
+	children RefSlice[RefObject[deepNode]]
+	// References nested inside arrays and structs are found
+	leaves [2]struct {
+		leaf RefObject[deepLeaf]
+	}
+	raw RefRaw
+	// Used to create cycles and shared references
+	other RefObject[deepNode]
+}
+
+// Builds a tree of nodes, depth levels deep, where every node has width
+// children. Every Reference field is either set or nil.
+func buildDeepTree(s *Store, depth, width int, prefix string) RefObject[deepNode] {
+	r := AllocObject[deepNode](s)
+	n := r.Value()
+	*n = deepNode{}
+	n.value = int64(len(prefix))
+	n.name = AllocStringFromString(s, prefix)
+
+	for i := range n.leaves {
+		leaf := AllocObject[deepLeaf](s)
+		*leaf.Value() = deepLeaf{
+			value: int64(i),
+			name:  AllocStringFromString(s, fmt.Sprintf("%s-leaf-%d", prefix, i)),
+		}
+		n.leaves[i].leaf = leaf
+	}
+
+	raw := AllocObject[int64](s)
+	*raw.Value() = 99
+	n.raw = raw.Raw()
+
+	if depth > 0 {
+		n.children = AllocSlice[RefObject[deepNode]](s, width, width)
+		for i := range width {
+			n.children.Value()[i] = buildDeepTree(s, depth-1, width, fmt.Sprintf("%s/%d", prefix, i))
+		}
+	}
+	return r
+}
+
+// Asserts that the tree at r has the structure built by buildDeepTree
+func assertDeepTree(t *testing.T, r RefObject[deepNode], depth, width int, prefix string) {
+	n := r.Value()
+	assert.Equal(t, int64(len(prefix)), n.value)
+	assert.Equal(t, prefix, n.name.Value())
+
+	for i := range n.leaves {
+		leaf := n.leaves[i].leaf.Value()
+		assert.Equal(t, int64(i), leaf.value)
+		assert.Equal(t, fmt.Sprintf("%s-leaf-%d", prefix, i), leaf.name.Value())
+	}
+
+	raw, ok := AsObject[int64](n.raw)
+	require.True(t, ok)
+	assert.Equal(t, int64(99), *raw.Value())
+
+	if depth == 0 {
+		assert.True(t, n.children.IsNil())
+		return
+	}
+	for i, child := range n.children.Value() {
+		assertDeepTree(t, child, depth-1, width, fmt.Sprintf("%s/%d", prefix, i))
+	}
+}
+
+func assertNoLiveDeepAllocations(t *testing.T, s *Store) {
+	for _, stats := range s.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+func Test_Deep_FreeTree(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := buildDeepTree(s, 3, 3, "root")
+
+	FreeDeep(s, r)
+	assertNoLiveDeepAllocations(t, s)
+	assert.Panics(t, func() { r.Value() })
+}
+
+// Demonstrate that allocations reachable along several paths, and cycles, are
+// freed exactly once
+func Test_Deep_FreeSharedAndCycles(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := buildDeepTree(s, 2, 2, "root")
+
+	// Every node points back at the root, creating many cycles
+	root := r.Value()
+	for _, child := range root.children.Value() {
+		child.Value().other = r
+		for _, grandchild := range child.Value().children.Value() {
+			grandchild.Value().other = r
+		}
+	}
+	// The root points to itself
+	root.other = r
+
+	FreeDeep(s, r)
+	assertNoLiveDeepAllocations(t, s)
+}
+
+func Test_Deep_FreeNil(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	assert.NotPanics(t, func() { FreeDeep(s, RefObject[deepNode]{}) })
+}
+
+// Demonstrate that walking the elements of a slice doesn't allocate, so the
+// Go heap allocations made by FreeDeep and CloneDeep don't grow with the
+// length of the slices they walk
+func Test_Deep_NoAllocationsPerElement(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+
+	allocsForLength := func(length int) float64 {
+		return testing.AllocsPerRun(10, func() {
+			r := AllocObject[RefSlice[deepLeaf]](s)
+			*r.Value() = AllocSlice[deepLeaf](s, length, length)
+			// Every leaf's name is nil, so no Reference is visited
+			clear(r.Value().Value())
+
+			clone := CloneDeep(s, r)
+			FreeDeep(s, clone)
+			FreeDeep(s, r)
+		})
+	}
+
+	assert.Equal(t, allocsForLength(1), allocsForLength(10_000))
+	assertNoLiveDeepAllocations(t, s)
+}
+
+// Demonstrate that types without any References are freed
+func Test_Deep_FreeNoReferences(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := AllocObject[[4]int64](s)
+
+	FreeDeep(s, r)
+	assertNoLiveDeepAllocations(t, s)
+}
+
+func Test_Deep_CloneTree(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := buildDeepTree(s, 3, 3, "root")
+	live := s.Stats()
+
+	clone := CloneDeep(s, r)
+	assert.NotEqual(t, r, clone)
+	assertDeepTree(t, clone, 3, 3, "root")
+
+	// Every allocation was copied
+	for i, stats := range s.Stats() {
+		assert.Equal(t, live[i].Live*2, stats.Live)
+	}
+
+	// The original remains valid, and is unaffected by changes to the
+	// clone
+	clone.Value().value = -1
+	clone.Value().leaves[0].leaf.Value().value = -1
+	assertDeepTree(t, r, 3, 3, "root")
+
+	// Freeing the original leaves the clone valid
+	FreeDeep(s, r)
+	clone.Value().value = int64(len("root"))
+	clone.Value().leaves[0].leaf.Value().value = 0
+	assertDeepTree(t, clone, 3, 3, "root")
+
+	FreeDeep(s, clone)
+	assertNoLiveDeepAllocations(t, s)
+}
+
+// Demonstrate that shared allocations and cycles are preserved in the clone
+func Test_Deep_CloneSharedAndCycles(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := buildDeepTree(s, 1, 2, "root")
+
+	root := r.Value()
+	children := root.children.Value()
+	// The root points to itself
+	root.other = r
+	// Both children point to the first child
+	children[0].Value().other = children[0]
+	children[1].Value().other = children[0]
+
+	clone := CloneDeep(s, r)
+	cloneRoot := clone.Value()
+	cloneChildren := cloneRoot.children.Value()
+
+	assert.Equal(t, clone, cloneRoot.other)
+	assert.Equal(t, cloneChildren[0], cloneChildren[0].Value().other)
+	assert.Equal(t, cloneChildren[0], cloneChildren[1].Value().other)
+	assert.NotEqual(t, children[0], cloneChildren[0])
+
+	FreeDeep(s, r)
+	FreeDeep(s, clone)
+	assertNoLiveDeepAllocations(t, s)
+}
+
+func Test_Deep_CloneNil(t *testing.T) {
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	clone := CloneDeep(s, RefObject[deepNode]{})
+	assert.True(t, clone.IsNil())
+}
+
+// Demonstrate that references held in slices of structs, and slices of
+// slices, are found
+func Test_Deep_NestedSlices(t *testing.T) {
+	type holder struct {
+		strs RefSlice[RefSlice[RefString]]
+	}
+
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := AllocObject[holder](s)
+	r.Value().strs = AllocSlice[RefSlice[RefString]](s, 3, 3)
+	for i := range 3 {
+		inner := AllocSlice[RefString](s, i, i)
+		for j := range i {
+			inner.Value()[j] = AllocStringFromString(s, fmt.Sprintf("%d-%d", i, j))
+		}
+		r.Value().strs.Value()[i] = inner
+	}
+
+	clone := CloneDeep(s, r)
+	for i, inner := range clone.Value().strs.Value() {
+		require.Len(t, inner.Value(), i)
+		for j, str := range inner.Value() {
+			assert.Equal(t, fmt.Sprintf("%d-%d", i, j), str.Value())
+		}
+	}
+
+	FreeDeep(s, r)
+	FreeDeep(s, clone)
+	assertNoLiveDeepAllocations(t, s)
+}
+
+// Demonstrate that the Vector type, which holds a Reference internally, is
+// freed and cloned correctly
+func Test_Deep_Vector(t *testing.T) {
+	type holder struct {
+		vector Vector[int64]
+	}
+
+	s := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, s.Destroy())
+	}()
+	r := AllocObject[holder](s)
+	r.Value().vector = AllocVector[int64](s, 1)
+	for i := range 100 {
+		r.Value().vector.Push(s, int64(i))
+	}
+
+	clone := CloneDeep(s, r)
+	cloneVector := clone.Value().vector
+	require.Equal(t, 100, cloneVector.Len())
+	for i := range 100 {
+		assert.Equal(t, int64(i), cloneVector.Get(i))
+	}
+
+	// Pushing to the clone does not affect the original
+	cloneVector.Push(s, 100)
+	assert.Equal(t, 100, r.Value().vector.Len())
+
+	FreeDeep(s, r)
+	FreeDeep(s, clone)
+	assertNoLiveDeepAllocations(t, s)
+}
@@ -53,6 +53,25 @@
 // The Reference types contain no conventional Go pointers which are recognised
 // by the garbage collector.
 //
+// Functions which resize a RefSlice, such as Append, invalidate the RefSlice
+// passed in and return a new one. If a RefSlice is stored in a field then that
+// field must be updated after every resize. Vector wraps a growable slice
+// behind a stable reference, which never needs to be updated as the vector
+// grows.
+//
+// Groups of allocations which are always thrown away together can be
+// allocated from an Arena. The Arena bump-allocates from large chunks acquired
+// from a Store, and releases every allocation at once with Arena.Reset() or
+// Arena.Free().
+//
+//	var store *offheap.Store = offheap.New()
+//	var arena *offheap.Arena = offheap.NewArena(store)
+//
+//	var ref offheap.RefObject[Node] = offheap.ArenaAllocObject[Node](arena)
+//
+//	arena.Reset()
+//	// You must never use ref again
+//
 // It is important to note that the objects managed by a Store do not exist on
 // the managed Go heap. They live in a series of manually mapped memory regions
 // which are managed separately by the Store. This means that the amount of
@@ -154,3 +154,17 @@ func (r *RefPointer) Realloc() RefPointer {
 	newRef.setGen(meta.gen)
 	return newRef
 }
+
+// Returns a reference to a location offset bytes into the allocation pointed
+// to by r. The returned reference shares r's metadata and generation, so it
+// will become invalid whenever r does, i.e. when r is freed or re-allocated.
+//
+// The caller is responsible for ensuring that offset lies within the
+// allocation. The returned reference must never be used to free the
+// allocation.
+func (r *RefPointer) Interior(offset uint64) RefPointer {
+	return RefPointer{
+		dataAddress: (r.dataAddress & pointerMask) + offset,
+		metaAddress: r.metaAddress,
+	}
+}
@@ -92,3 +92,26 @@ func TestRealloc(t *testing.T) {
 	assert.Panics(t, func() { r1.DataPtr() })
 	assert.NotPanics(t, func() { r2.DataPtr() })
 }
+
+func TestInterior(t *testing.T) {
+	allocConfig := NewAllocConfigBySize(64, 32*64)
+	objects, metadatas := MmapSlab(allocConfig)
+
+	r := NewReference(objects[0], metadatas[0])
+	interior := r.Interior(24)
+
+	// The interior reference points into the allocation, but shares its
+	// metadata and generation
+	assert.Equal(t, objects[0]+24, interior.DataPtr())
+	assert.Equal(t, r.metadataPtr(), interior.metadataPtr())
+	assert.Equal(t, r.Gen(), interior.Gen())
+
+	// Re-allocating the original reference invalidates the interior
+	// reference
+	r2 := r.Realloc()
+	assert.Panics(t, func() { interior.DataPtr() })
+
+	// Interior references created from the new reference are valid
+	interior2 := r2.Interior(24)
+	assert.Equal(t, objects[0]+24, interior2.DataPtr())
+}
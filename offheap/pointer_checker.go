@@ -35,6 +35,14 @@ func (p *typePaths) String() string {
 	return result[:len(result)-1]
 }
 
+// Returns an error if the type T contains pointers in any part of its type,
+// and so can't be allocated in a Store. Data structures built on a Store can
+// use this to reject their type parameters when they are constructed, rather
+// than when they first allocate.
+func CheckNoPointers[T any]() error {
+	return containsNoPointers[T]()
+}
+
 func containsNoPointers[O any]() error {
 	t := reflect.TypeFor[O]()
 	paths := &typePaths{}
@@ -0,0 +1,153 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"fmt"
+
+	"github.com/fmstephe/memorymanager/offheap/internal/pointerstore"
+)
+
+// Indicates which kind of Reference a RefRaw was created from.
+type RefKind uint8
+
+const (
+	// The kind of a nil RefRaw
+	NilKind RefKind = iota
+	ObjectKind
+	SliceKind
+	StringKind
+)
+
+func (k RefKind) String() string {
+	switch k {
+	case NilKind:
+		return "nil"
+	case ObjectKind:
+		return "object"
+	case SliceKind:
+		return "slice"
+	case StringKind:
+		return "string"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(k))
+	}
+}
+
+// An untyped reference to an allocation. A RefRaw can be created from any
+// RefObject, RefSlice or RefString and can be converted back into the
+// Reference type it was created from.
+//
+// RefRaw is useful where a field needs to hold "a reference to something",
+// for example in polymorphic node types, without the generic type parameter
+// forced on us by the Reference constraint.
+//
+// Along with the pointer to the allocation a RefRaw records the kind of
+// Reference it was created from and the allocation size class. Conversions
+// back to typed References are checked against this information. It is
+// important to note that these checks are best effort, a RefObject[int64]
+// converted to a RefRaw can be converted into a RefObject[uint64] because both
+// types have the same size.
+//
+// Like the typed References, a RefRaw holds no conventional Go pointers, so
+// it may be stored in objects allocated in a Store, e.g. as the child field of
+// a node type whose children have different types.
+type RefRaw struct {
+	ref      pointerstore.RefPointer
+	length   int
+	capacity int
+	idx      uint8
+	kind     RefKind
+}
+
+// Returns an untyped RefRaw pointing to the same allocation as r.
+func (r *RefObject[T]) Raw() RefRaw {
+	if r.IsNil() {
+		return RefRaw{}
+	}
+	return RefRaw{
+		ref:  r.ref,
+		idx:  uint8(indexForType[T]()),
+		kind: ObjectKind,
+	}
+}
+
+// Returns an untyped RefRaw pointing to the same allocation as r.
+func (r *RefSlice[T]) Raw() RefRaw {
+	if r.IsNil() {
+		return RefRaw{}
+	}
+	return RefRaw{
+		ref:      r.ref,
+		length:   r.length,
+		capacity: r.capacity,
+		idx:      uint8(indexForSlice[T](r.capacity)),
+		kind:     SliceKind,
+	}
+}
+
+// Returns an untyped RefRaw pointing to the same allocation as r.
+func (r *RefString) Raw() RefRaw {
+	if r.IsNil() {
+		return RefRaw{}
+	}
+	return RefRaw{
+		ref:    r.ref,
+		length: r.length,
+		idx:    uint8(indexForSize(r.length)),
+		kind:   StringKind,
+	}
+}
+
+// Returns the kind of Reference this RefRaw was created from.
+func (r *RefRaw) Kind() RefKind {
+	return r.kind
+}
+
+// Returns true if this RefRaw does not point to an allocation, false
+// otherwise.
+func (r *RefRaw) IsNil() bool {
+	return r.ref.IsNil()
+}
+
+// Converts r back into a RefObject[T]. If r was not created from a RefObject,
+// or the size of T doesn't match the size of the original allocation, then a
+// nil RefObject and false are returned.
+func AsObject[T any](r RefRaw) (RefObject[T], bool) {
+	if r.kind != ObjectKind || int(r.idx) != indexForType[T]() {
+		return RefObject[T]{}, false
+	}
+	return newRefObject[T](r.ref), true
+}
+
+// Converts r back into a RefSlice[T]. If r was not created from a RefSlice,
+// or the size of a []T with the original capacity doesn't match the size of
+// the original allocation, then a nil RefSlice and false are returned.
+func AsSlice[T any](r RefRaw) (RefSlice[T], bool) {
+	if r.kind != SliceKind || int(r.idx) != indexForSlice[T](r.capacity) {
+		return RefSlice[T]{}, false
+	}
+	return newRefSlice[T](r.length, r.capacity, r.ref), true
+}
+
+// Converts r back into a RefString. If r was not created from a RefString
+// then a nil RefString and false are returned.
+func AsString(r RefRaw) (RefString, bool) {
+	if r.kind != StringKind {
+		return RefString{}, false
+	}
+	return newRefString(r.length, r.ref), true
+}
+
+// Frees the allocation referenced by r, regardless of the kind of Reference
+// it was created from. After this call returns r, and the Reference it was
+// created from, must never be used again. Any use of the allocation will have
+// unpredicatable behaviour.
+func Free(s *Store, r RefRaw) {
+	if r.kind == NilKind {
+		panic("cannot free nil RefRaw")
+	}
+	s.free(int(r.idx), r.ref)
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RefRaw_Nil(t *testing.T) {
+	var r RefRaw
+	assert.True(t, r.IsNil())
+	assert.Equal(t, NilKind, r.Kind())
+
+	_, ok := AsObject[MutableStruct](r)
+	assert.False(t, ok)
+	_, ok = AsSlice[MutableStruct](r)
+	assert.False(t, ok)
+	_, ok = AsString(r)
+	assert.False(t, ok)
+
+	// Nil references convert to nil RefRaw
+	var objRef RefObject[MutableStruct]
+	assert.Equal(t, RefRaw{}, objRef.Raw())
+	var sliceRef RefSlice[MutableStruct]
+	assert.Equal(t, RefRaw{}, sliceRef.Raw())
+	var strRef RefString
+	assert.Equal(t, RefRaw{}, strRef.Raw())
+}
+
+func Test_RefRaw_Object(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	ref := AllocObject[MutableStruct](os)
+	ref.Value().Field = 42
+
+	raw := ref.Raw()
+	assert.False(t, raw.IsNil())
+	assert.Equal(t, ObjectKind, raw.Kind())
+
+	// Converts back to the original type
+	objRef, ok := AsObject[MutableStruct](raw)
+	assert.True(t, ok)
+	assert.Equal(t, ref, objRef)
+	assert.Equal(t, 42, objRef.Value().Field)
+
+	// Conversions to the wrong kind fail
+	_, ok = AsSlice[MutableStruct](raw)
+	assert.False(t, ok)
+	_, ok = AsString(raw)
+	assert.False(t, ok)
+
+	// Conversions to a type of a different size fail
+	_, ok = AsObject[[64]byte](raw)
+	assert.False(t, ok)
+
+	Free(os, raw)
+	assert.Panics(t, func() { ref.Value() })
+}
+
+func Test_RefRaw_Slice(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	ref := ConcatSlices(os, []int64{1, 2, 3})
+
+	raw := ref.Raw()
+	assert.False(t, raw.IsNil())
+	assert.Equal(t, SliceKind, raw.Kind())
+
+	// Converts back to the original type
+	sliceRef, ok := AsSlice[int64](raw)
+	assert.True(t, ok)
+	assert.Equal(t, ref, sliceRef)
+	assert.Equal(t, []int64{1, 2, 3}, sliceRef.Value())
+
+	// Conversions to the wrong kind fail
+	_, ok = AsObject[int64](raw)
+	assert.False(t, ok)
+	_, ok = AsString(raw)
+	assert.False(t, ok)
+
+	// Conversions to a type of a different size fail
+	_, ok = AsSlice[[64]byte](raw)
+	assert.False(t, ok)
+
+	Free(os, raw)
+	assert.Panics(t, func() { ref.Value() })
+}
+
+func Test_RefRaw_String(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	ref := AllocStringFromString(os, "raw string")
+
+	raw := ref.Raw()
+	assert.False(t, raw.IsNil())
+	assert.Equal(t, StringKind, raw.Kind())
+
+	// Converts back to the original type
+	strRef, ok := AsString(raw)
+	assert.True(t, ok)
+	assert.Equal(t, ref, strRef)
+	assert.Equal(t, "raw string", strRef.Value())
+
+	// Conversions to the wrong kind fail
+	_, ok = AsObject[int64](raw)
+	assert.False(t, ok)
+	_, ok = AsSlice[byte](raw)
+	assert.False(t, ok)
+
+	Free(os, raw)
+	assert.Panics(t, func() { ref.Value() })
+}
+
+// Demonstrate that RefRaw can be stored in a type managed by a Store
+func Test_RefRaw_StoredInObject(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	type polymorphicNode struct {
+		children [3]RefRaw
+	}
+
+	objRef := AllocObject[MutableStruct](os)
+	sliceRef := AllocSlice[int64](os, 2, 2)
+	strRef := AllocStringFromString(os, "child")
+
+	nodeRef := AllocObject[polymorphicNode](os)
+	node := nodeRef.Value()
+	node.children[0] = objRef.Raw()
+	node.children[1] = sliceRef.Raw()
+	node.children[2] = strRef.Raw()
+
+	for _, child := range node.children {
+		Free(os, child)
+	}
+
+	assert.Panics(t, func() { objRef.Value() })
+	assert.Panics(t, func() { sliceRef.Value() })
+	assert.Panics(t, func() { strRef.Value() })
+}
+
+func Test_RefRaw_FreeNil_Panic(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	assert.Panics(t, func() { Free(os, RefRaw{}) })
+}
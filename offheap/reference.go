@@ -8,6 +8,9 @@ package offheap
 // Reference type.  There is an awkward problem if your type is RefString,
 // because you are forced to include an _unused_ parameterised type. We may
 // learn to live with this peacefully in time.
+//
+// If you need to store a reference to an allocation of any kind, without
+// knowing its type, then RefRaw may be a better fit.
 type Reference[T any] interface {
 	RefString | RefSlice[T] | RefObject[T]
 }
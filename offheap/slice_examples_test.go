@@ -129,3 +129,48 @@ func ExampleAppendSlice_oldRef() {
 	ref1.Value()
 	// Output: The RefSlice passed into AppendSlice cannot be used after
 }
+
+// You can insert an element into a RefSlice at any index. Like Append the
+// RefSlice passed in is no longer valid after the call.
+func ExampleInsertAt() {
+	var store *offheap.Store = offheap.New()
+
+	var ref1 offheap.RefSlice[int] = offheap.ConcatSlices(store, []int{1, 3})
+
+	var ref2 offheap.RefSlice[int] = offheap.InsertAt(store, ref1, 1, 2)
+
+	var s2 []int = ref2.Value()
+
+	fmt.Printf("Slice of %v with length %d and capacity %d", s2, len(s2), cap(s2))
+	// Output: Slice of [1 2 3] with length 3 and capacity 4
+}
+
+// You can delete an element from a RefSlice at any index. The capacity of the
+// slice is unchanged.
+func ExampleDeleteAt() {
+	var store *offheap.Store = offheap.New()
+
+	var ref1 offheap.RefSlice[int] = offheap.ConcatSlices(store, []int{1, 2, 3})
+
+	var ref2 offheap.RefSlice[int] = offheap.DeleteAt(store, ref1, 1)
+
+	var s2 []int = ref2.Value()
+
+	fmt.Printf("Slice of %v with length %d and capacity %d", s2, len(s2), cap(s2))
+	// Output: Slice of [1 3] with length 2 and capacity 4
+}
+
+// You can reduce the capacity of a RefSlice to the smallest capacity which
+// can hold its elements.
+func ExampleShrinkToFit() {
+	var store *offheap.Store = offheap.New()
+
+	var ref1 offheap.RefSlice[int] = offheap.AllocSlice[int](store, 2, 16)
+
+	var ref2 offheap.RefSlice[int] = offheap.ShrinkToFit(store, ref1)
+
+	var s2 []int = ref2.Value()
+
+	fmt.Printf("Slice with length %d and capacity %d", len(s2), cap(s2))
+	// Output: Slice with length 2 and capacity 2
+}
@@ -92,6 +92,124 @@ func AppendSlice[T any](s *Store, into RefSlice[T], fromSlice []T) RefSlice[T] {
 	return newRef
 }
 
+// Returns a new RefSlice pointing to a slice whose size and contents is the
+// same as slices.Insert(into.Value(), idx, value).
+//
+// idx must be in the range [0, len(into.Value())], otherwise this function
+// will panic.
+//
+// After this function returns into is no longer a valid RefSlice, and will
+// behave as if Free(...) was called on it.  Internally there is an
+// optimisation which _may_ reuse the existing allocation slot if possible. But
+// externally this function behaves as if a new allocation is made and the old
+// one freed.
+func InsertAt[T any](s *Store, into RefSlice[T], idx int, value T) RefSlice[T] {
+	if idx < 0 || idx > into.length {
+		panic(fmt.Errorf("insert index %d out of range for slice of length %d", idx, into.length))
+	}
+
+	pRef, newCapacity := resizeAndInvalidate[T](s, into.ref, into.capacity, into.length, 1)
+
+	// We have the capacity available, shift the tail and insert the element
+	newRef := newRefSlice[T](into.length+1, newCapacity, pRef)
+	slice := newRef.Value()
+	copy(slice[idx+1:], slice[idx:into.length])
+	slice[idx] = value
+
+	return newRef
+}
+
+// Returns a new RefSlice pointing to a slice whose size and contents is the
+// same as slices.Delete(from.Value(), idx, idx+1).
+//
+// idx must be in the range [0, len(from.Value())), otherwise this function
+// will panic. The capacity of the slice is unchanged.
+//
+// After this function returns from is no longer a valid RefSlice, and will
+// behave as if Free(...) was called on it. The existing allocation slot is
+// always reused.
+func DeleteAt[T any](s *Store, from RefSlice[T], idx int) RefSlice[T] {
+	if idx < 0 || idx >= from.length {
+		panic(fmt.Errorf("delete index %d out of range for slice of length %d", idx, from.length))
+	}
+
+	slice := from.Value()
+	copy(slice[idx:], slice[idx+1:])
+
+	return newRefSlice[T](from.length-1, from.capacity, from.ref.Realloc())
+}
+
+// Returns a new RefSlice pointing to a slice whose size and contents is the
+// same as from.Value()[:length].
+//
+// length must be in the range [0, len(from.Value())], otherwise this function
+// will panic. The capacity of the slice is unchanged.
+//
+// After this function returns from is no longer a valid RefSlice, and will
+// behave as if Free(...) was called on it. The existing allocation slot is
+// always reused.
+func Truncate[T any](s *Store, from RefSlice[T], length int) RefSlice[T] {
+	if length < 0 || length > from.length {
+		panic(fmt.Errorf("truncate length %d out of range for slice of length %d", length, from.length))
+	}
+
+	return newRefSlice[T](length, from.capacity, from.ref.Realloc())
+}
+
+// Returns a new RefSlice pointing to a slice whose contents is the same as
+// from.Value()[:length]. Unlike Truncate the length may be larger than the
+// current length, so long as it does not exceed the capacity of the slice.
+//
+// length must be in the range [0, cap(from.Value())], otherwise this function
+// will panic. Any elements exposed by growing the length have arbitrary
+// values, in the same way that elements in a newly allocated slice do.
+//
+// After this function returns from is no longer a valid RefSlice, and will
+// behave as if Free(...) was called on it. The existing allocation slot is
+// always reused.
+func SetLen[T any](s *Store, from RefSlice[T], length int) RefSlice[T] {
+	if length < 0 || length > from.capacity {
+		panic(fmt.Errorf("length %d out of range for slice of capacity %d", length, from.capacity))
+	}
+
+	return newRefSlice[T](length, from.capacity, from.ref.Realloc())
+}
+
+// Returns a new RefSlice pointing to a slice whose size and contents is the
+// same as from.Value(), but whose capacity is the smallest capacity able to
+// hold those elements.
+//
+// After this function returns from is no longer a valid RefSlice, and will
+// behave as if Free(...) was called on it.  If the capacity of the slice can't
+// be reduced, the existing allocation slot is reused. But externally this
+// function behaves as if a new allocation is made and the old one freed.
+func ShrinkToFit[T any](s *Store, from RefSlice[T]) RefSlice[T] {
+	newCapacity := capacityForSlice(from.length)
+
+	if newCapacity >= from.capacity {
+		return newRefSlice[T](from.length, from.capacity, from.ref.Realloc())
+	}
+
+	newRef := AllocSlice[T](s, from.length, newCapacity)
+	copy(newRef.Value(), from.Value())
+	FreeSlice(s, from)
+
+	return newRef
+}
+
+// Allocates a new slice whose size and contents is the same as from.Value().
+// The capacity of the new slice is the smallest capacity able to hold those
+// elements.
+//
+// Unlike the other slice functions from remains a valid RefSlice after this
+// function returns.
+func CloneSlice[T any](s *Store, from RefSlice[T]) RefSlice[T] {
+	newRef := AllocSlice[T](s, from.length, from.length)
+	copy(newRef.Value(), from.Value())
+
+	return newRef
+}
+
 // Frees the allocation referenced by r. After this call returns r must never
 // be used again. Any use of the slice referenced by r will have unpredicatable
 // behaviour.
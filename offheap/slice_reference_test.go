@@ -460,3 +460,205 @@ func Test_Slice_ConcatSlices(t *testing.T) {
 		assert.Equal(t, expectedSlice, r.Value())
 	}
 }
+
+func Test_Slice_InsertAt(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, length := range testSizeRanges {
+		for _, idx := range []int{0, length / 2, length} {
+			t.Run(fmt.Sprintf("length %d insert at %d", length, idx), func(t *testing.T) {
+				refInit, expectedSlice := allocTestSlice(os, length)
+
+				refResult := InsertAt(os, refInit, idx, -1)
+				expectedSlice = append(expectedSlice[:idx], append([]int64{-1}, expectedSlice[idx:]...)...)
+
+				require.Equal(t, expectedSlice, refResult.Value())
+				require.Equal(t, capacityForSlice(length+1), cap(refResult.Value()))
+
+				// Assert that the original reference has been invalidated
+				require.Panics(t, func() { refInit.Value() })
+			})
+		}
+	}
+}
+
+func Test_Slice_InsertAt_OutOfRange(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	ref, _ := allocTestSlice(os, 4)
+	assert.Panics(t, func() { InsertAt(os, ref, -1, 0) })
+	assert.Panics(t, func() { InsertAt(os, ref, 5, 0) })
+}
+
+func Test_Slice_DeleteAt(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, length := range testSizeRanges {
+		if length == 0 {
+			continue
+		}
+		for _, idx := range []int{0, length / 2, length - 1} {
+			t.Run(fmt.Sprintf("length %d delete at %d", length, idx), func(t *testing.T) {
+				refInit, expectedSlice := allocTestSlice(os, length)
+
+				refResult := DeleteAt(os, refInit, idx)
+				expectedSlice = append(expectedSlice[:idx], expectedSlice[idx+1:]...)
+
+				require.Equal(t, expectedSlice, refResult.Value())
+				// Capacity is unchanged
+				require.Equal(t, capacityForSlice(length), cap(refResult.Value()))
+
+				// Assert that the original reference has been invalidated
+				require.Panics(t, func() { refInit.Value() })
+			})
+		}
+	}
+}
+
+func Test_Slice_DeleteAt_OutOfRange(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	ref, _ := allocTestSlice(os, 4)
+	assert.Panics(t, func() { DeleteAt(os, ref, -1) })
+	assert.Panics(t, func() { DeleteAt(os, ref, 4) })
+}
+
+func Test_Slice_Truncate(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, length := range testSizeRanges {
+		for _, newLength := range []int{0, length / 2, length} {
+			t.Run(fmt.Sprintf("length %d truncate to %d", length, newLength), func(t *testing.T) {
+				refInit, expectedSlice := allocTestSlice(os, length)
+
+				refResult := Truncate(os, refInit, newLength)
+
+				require.Equal(t, expectedSlice[:newLength], refResult.Value())
+				// Capacity is unchanged
+				require.Equal(t, capacityForSlice(length), cap(refResult.Value()))
+
+				// Assert that the original reference has been invalidated
+				require.Panics(t, func() { refInit.Value() })
+			})
+		}
+	}
+
+	ref, _ := allocTestSlice(os, 4)
+	assert.Panics(t, func() { Truncate(os, ref, -1) })
+	assert.Panics(t, func() { Truncate(os, ref, 5) })
+}
+
+func Test_Slice_SetLen(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, length := range testSizeRanges {
+		capacity := capacityForSlice(length)
+		for _, newLength := range []int{0, length / 2, length, capacity} {
+			t.Run(fmt.Sprintf("length %d set length to %d", length, newLength), func(t *testing.T) {
+				refInit, expectedSlice := allocTestSlice(os, length)
+
+				refResult := SetLen(os, refInit, newLength)
+				resultSlice := refResult.Value()
+
+				require.Equal(t, newLength, len(resultSlice))
+				require.Equal(t, capacity, cap(resultSlice))
+				// The original elements are preserved
+				commonLength := min(length, newLength)
+				require.Equal(t, expectedSlice[:commonLength], resultSlice[:commonLength])
+
+				// Assert that the original reference has been invalidated
+				require.Panics(t, func() { refInit.Value() })
+			})
+		}
+	}
+
+	ref, _ := allocTestSlice(os, 4)
+	assert.Panics(t, func() { SetLen(os, ref, -1) })
+	assert.Panics(t, func() { SetLen(os, ref, 5) })
+}
+
+func Test_Slice_ShrinkToFit(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, length := range testSizeRanges {
+		for _, extraCapacity := range testSizeRanges {
+			t.Run(fmt.Sprintf("length %d extra capacity %d", length, extraCapacity), func(t *testing.T) {
+				refInit := AllocSlice[int64](os, length, length+extraCapacity)
+				expectedSlice := make([]int64, length)
+				for i := range refInit.Value() {
+					refInit.Value()[i] = int64(i)
+					expectedSlice[i] = int64(i)
+				}
+
+				refResult := ShrinkToFit(os, refInit)
+
+				require.Equal(t, expectedSlice, refResult.Value())
+				require.Equal(t, capacityForSlice(length), cap(refResult.Value()))
+
+				// Assert that the original reference has been invalidated
+				require.Panics(t, func() { refInit.Value() })
+			})
+		}
+	}
+}
+
+func Test_Slice_CloneSlice(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, length := range testSizeRanges {
+		t.Run(fmt.Sprintf("length %d", length), func(t *testing.T) {
+			refInit, expectedSlice := allocTestSlice(os, length)
+
+			refClone := CloneSlice(os, refInit)
+
+			require.Equal(t, expectedSlice, refClone.Value())
+			require.Equal(t, capacityForSlice(length), cap(refClone.Value()))
+
+			// The original reference is still valid and unchanged
+			require.Equal(t, expectedSlice, refInit.Value())
+
+			// The clone does not share memory with the original
+			if length > 0 {
+				refClone.Value()[0] = -1
+				require.Equal(t, expectedSlice, refInit.Value())
+			}
+		})
+	}
+}
+
+// Allocates a slice of int64 values 0..length-1 and returns the RefSlice along
+// with a conventional Go slice of the same values for comparison
+func allocTestSlice(os *Store, length int) (RefSlice[int64], []int64) {
+	ref := AllocSlice[int64](os, length, length)
+	expected := make([]int64, length)
+	slice := ref.Value()
+	for i := range slice {
+		slice[i] = int64(i)
+		expected[i] = int64(i)
+	}
+	return ref, expected
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+// Allocates a new, empty, Vector. The backing slice of the vector will have a
+// capacity of at least requestedCapacity.
+//
+// Unlike a RefSlice, a Vector remains valid as elements are pushed and popped.
+// This makes Vector a convenient type to store in fields of other types
+// managed by a Store, because the field never needs to be updated as the
+// vector grows.
+func AllocVector[T any](s *Store, requestedCapacity int) Vector[T] {
+	headerRef := AllocObject[vectorHeader[T]](s)
+	header := headerRef.Value()
+	header.slice = AllocSlice[T](s, 0, requestedCapacity)
+
+	return Vector[T]{
+		ref: headerRef,
+	}
+}
+
+// Frees the vector, including its backing slice. After this call returns v
+// must never be used again. Any use of the vector will have unpredicatable
+// behaviour.
+func FreeVector[T any](s *Store, v Vector[T]) {
+	header := v.ref.Value()
+	FreeSlice(s, header.slice)
+	FreeObject(s, v.ref)
+}
+
+// The header of a vector is allocated as an object in a Store, it holds the
+// RefSlice which is replaced each time the backing slice is resized.
+type vectorHeader[T any] struct {
+	slice RefSlice[T]
+}
+
+// A growable slice with a stable reference. A Vector is a reference to an
+// allocated header object, which in turn references the backing slice.
+// Because the header is updated in place when the backing slice is resized,
+// the Vector itself is never invalidated by Push or Pop.
+//
+// A Vector holds only a RefObject, and no conventional Go pointers, so it can
+// be embedded in other objects allocated in a Store. Because Push never
+// replaces the Vector, such a field only needs to be written once, when the
+// Vector is allocated.
+type Vector[T any] struct {
+	ref RefObject[vectorHeader[T]]
+}
+
+// Appends value to the end of the vector.
+func (v *Vector[T]) Push(s *Store, value T) {
+	header := v.ref.Value()
+	header.slice = Append(s, header.slice, value)
+}
+
+// Removes the last element of the vector and returns it. If the vector is
+// empty the zero value of T and false are returned.
+//
+// The capacity of the backing slice is not reduced.
+func (v *Vector[T]) Pop(s *Store) (T, bool) {
+	header := v.ref.Value()
+	slice := header.slice.Value()
+	if len(slice) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	value := slice[len(slice)-1]
+	header.slice = Truncate(s, header.slice, len(slice)-1)
+	return value, true
+}
+
+// Returns the element at idx. Panics if idx is out of range.
+func (v *Vector[T]) Get(idx int) T {
+	header := v.ref.Value()
+	return header.slice.Value()[idx]
+}
+
+// Sets the element at idx to value. Panics if idx is out of range.
+func (v *Vector[T]) Set(idx int, value T) {
+	header := v.ref.Value()
+	header.slice.Value()[idx] = value
+}
+
+// Returns the number of elements in the vector.
+func (v *Vector[T]) Len() int {
+	header := v.ref.Value()
+	return header.slice.length
+}
+
+// This method iterates over every element in the vector, in order. For each
+// element the function is called with its index and a pointer to the element.
+// It is possible to mutate the elements of the vector via these pointers.
+//
+// If fun returns false the iteration stops and Range returns false, otherwise
+// Range returns true.
+//
+// The vector must not be pushed or popped during the iteration. Client code
+// should not retain the pointers outside the scope of the Range call.
+func (v *Vector[T]) Range(fun func(idx int, value *T) bool) bool {
+	header := v.ref.Value()
+	slice := header.slice.Value()
+	for i := range slice {
+		if !fun(i, &slice[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if this Vector has not been allocated, false otherwise.
+func (v *Vector[T]) IsNil() bool {
+	return v.ref.IsNil()
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package offheap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Demonstrate that a Vector can be pushed past its initial capacity, and that
+// the same Vector value remains valid throughout.
+func Test_Vector_PushGetLen(t *testing.T) {
+	os := New()
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	for _, capacity := range testSizeRanges {
+		t.Run(fmt.Sprintf("initial capacity %d", capacity), func(t *testing.T) {
+			v := AllocVector[MutableStruct](os, capacity)
+			assert.Equal(t, 0, v.Len())
+
+			for i := range 100 {
+				v.Push(os, MutableStruct{Field: i})
+				require.Equal(t, i+1, v.Len())
+			}
+
+			for i := range 100 {
+				require.Equal(t, MutableStruct{Field: i}, v.Get(i))
+			}
+
+			FreeVector(os, v)
+		})
+	}
+}
+
+func Test_Vector_Set(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	v := AllocVector[int](os, 0)
+	for i := range 10 {
+		v.Push(os, i)
+	}
+
+	for i := range 10 {
+		v.Set(i, i*10)
+	}
+
+	for i := range 10 {
+		assert.Equal(t, i*10, v.Get(i))
+	}
+
+	assert.Panics(t, func() { v.Get(10) })
+	assert.Panics(t, func() { v.Set(10, 0) })
+}
+
+func Test_Vector_Pop(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	v := AllocVector[int](os, 0)
+
+	// Popping an empty vector returns false
+	_, ok := v.Pop(os)
+	assert.False(t, ok)
+
+	for i := range 10 {
+		v.Push(os, i)
+	}
+
+	for i := 9; i >= 0; i-- {
+		value, ok := v.Pop(os)
+		assert.True(t, ok)
+		assert.Equal(t, i, value)
+		assert.Equal(t, i, v.Len())
+	}
+
+	_, ok = v.Pop(os)
+	assert.False(t, ok)
+}
+
+func Test_Vector_Range(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	v := AllocVector[int](os, 0)
+	for i := range 10 {
+		v.Push(os, i)
+	}
+
+	// Mutate every element via Range
+	assert.True(t, v.Range(func(idx int, value *int) bool {
+		assert.Equal(t, idx, *value)
+		*value = idx * 2
+		return true
+	}))
+
+	for i := range 10 {
+		assert.Equal(t, i*2, v.Get(i))
+	}
+
+	// Range stops when fun returns false
+	count := 0
+	assert.False(t, v.Range(func(idx int, value *int) bool {
+		count++
+		return idx < 4
+	}))
+	assert.Equal(t, 5, count)
+}
+
+// Demonstrate that a Vector can be stored in an object managed by the Store,
+// and that growing the vector does not require updating that object.
+func Test_Vector_StoredInObject(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	type holder struct {
+		values Vector[int]
+	}
+
+	ref := AllocObject[holder](os)
+	ref.Value().values = AllocVector[int](os, 0)
+
+	for i := range 1000 {
+		values := ref.Value().values
+		values.Push(os, i)
+	}
+
+	values := ref.Value().values
+	assert.Equal(t, 1000, values.Len())
+	assert.Equal(t, 999, values.Get(999))
+}
+
+func Test_Vector_FreeGet_Panic(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	v := AllocVector[int](os, 0)
+	v.Push(os, 1)
+	FreeVector(os, v)
+
+	assert.Panics(t, func() { v.Get(0) })
+	assert.Panics(t, func() { v.Len() })
+}
+
+func Test_Vector_IsNil(t *testing.T) {
+	os := NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, os.Destroy())
+	}()
+
+	var v Vector[int]
+	assert.True(t, v.IsNil())
+
+	v = AllocVector[int](os, 0)
+	assert.False(t, v.IsNil())
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The bitset package provides sets of uint32 values whose memory is allocated
+// in an offheap.Store. This allows very large membership sets, such as sets of
+// user or document IDs, to be built without impacting garbage collection.
+//
+// Bitset is a dense bitset, using one bit for every value up to the largest
+// value in the set. It is the best choice when the set contains a large
+// fraction of the values in its range.
+//
+// SparseBitset is a compressed bitset in the style of roaring bitmaps. Values
+// are partitioned by their high 16 bits into containers, and each container
+// stores its low 16 bits either as a sorted array or as a bitmap, depending
+// on how many values it holds. It is the best choice when the set is sparse,
+// or clustered, across a large range.
+//
+// Both types can be iterated, using NextSet or Range, without allocating.
+//
+// Neither type is safe for concurrent use.
+package bitset
+
+import (
+	"math/bits"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A dense bitset. The bitset grows as needed to hold the largest value which
+// has been set.
+type Bitset struct {
+	store *offheap.Store
+	words offheap.RefSlice[uint64]
+}
+
+// Creates a new empty Bitset whose memory will be allocated in store.
+func New(store *offheap.Store) *Bitset {
+	return &Bitset{
+		store: store,
+		words: offheap.AllocSlice[uint64](store, 0, 0),
+	}
+}
+
+func wordIndex(value uint32) int {
+	return int(value / 64)
+}
+
+func bitMask(value uint32) uint64 {
+	return 1 << (value % 64)
+}
+
+// Adds value to the set.
+func (b *Bitset) Set(value uint32) {
+	idx := wordIndex(value)
+	b.grow(idx + 1)
+	b.words.Value()[idx] |= bitMask(value)
+}
+
+// Removes value from the set.
+func (b *Bitset) Clear(value uint32) {
+	words := b.words.Value()
+	if idx := wordIndex(value); idx < len(words) {
+		words[idx] &^= bitMask(value)
+	}
+}
+
+// Returns true if value is in the set, false otherwise.
+func (b *Bitset) Test(value uint32) bool {
+	words := b.words.Value()
+	idx := wordIndex(value)
+	return idx < len(words) && words[idx]&bitMask(value) != 0
+}
+
+// Returns the number of values in the set.
+func (b *Bitset) Count() int {
+	count := 0
+	for _, word := range b.words.Value() {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// Returns the smallest value in the set which is >= from, and true. If there
+// is no such value false is returned.
+//
+// Together with Test this allows the set to be iterated without allocating
+//
+//	for v, ok := b.NextSet(0); ok; v, ok = b.NextSet(v + 1) {
+//		...
+//	}
+//
+// Care must be taken when the set contains math.MaxUint32, because v+1 will
+// wrap around to 0.
+func (b *Bitset) NextSet(from uint32) (uint32, bool) {
+	words := b.words.Value()
+	idx := wordIndex(from)
+	if idx >= len(words) {
+		return 0, false
+	}
+
+	// Mask out the bits below from in the first word
+	word := words[idx] &^ (bitMask(from) - 1)
+	for {
+		if word != 0 {
+			return uint32(idx*64 + bits.TrailingZeros64(word)), true
+		}
+		idx++
+		if idx >= len(words) {
+			return 0, false
+		}
+		word = words[idx]
+	}
+}
+
+// Calls fun for each value in the set, in ascending order. If fun returns
+// false the iteration stops.
+//
+// The set must not be modified during the iteration.
+func (b *Bitset) Range(fun func(value uint32) bool) {
+	for idx, word := range b.words.Value() {
+		for word != 0 {
+			value := uint32(idx*64 + bits.TrailingZeros64(word))
+			if !fun(value) {
+				return
+			}
+			// Clear the lowest set bit
+			word &= word - 1
+		}
+	}
+}
+
+// Sets b to the intersection of b and other.
+func (b *Bitset) And(other *Bitset) {
+	words := b.words.Value()
+	otherWords := other.words.Value()
+	for i := range words {
+		if i < len(otherWords) {
+			words[i] &= otherWords[i]
+		} else {
+			words[i] = 0
+		}
+	}
+}
+
+// Sets b to the union of b and other.
+func (b *Bitset) Or(other *Bitset) {
+	otherWords := other.words.Value()
+	b.grow(len(otherWords))
+	words := b.words.Value()
+	for i := range otherWords {
+		words[i] |= otherWords[i]
+	}
+}
+
+// Sets b to the difference of b and other, i.e. the values in b which are not
+// in other.
+func (b *Bitset) AndNot(other *Bitset) {
+	words := b.words.Value()
+	otherWords := other.words.Value()
+	for i := range min(len(words), len(otherWords)) {
+		words[i] &^= otherWords[i]
+	}
+}
+
+// Frees the memory used by the set. After this call returns the set must
+// never be used again.
+func (b *Bitset) Free() {
+	offheap.FreeSlice(b.store, b.words)
+	b.words = offheap.RefSlice[uint64]{}
+}
+
+// Ensures that the set has at least length words. New words are zeroed.
+func (b *Bitset) grow(length int) {
+	oldWords := b.words.Value()
+	if length <= len(oldWords) {
+		return
+	}
+
+	// Grow by at least double, to amortise the cost of copying
+	newLength := max(length, 2*len(oldWords))
+	newWords := offheap.AllocSlice[uint64](b.store, newLength, newLength)
+	copy(newWords.Value(), oldWords)
+	clear(newWords.Value()[len(oldWords):])
+
+	offheap.FreeSlice(b.store, b.words)
+	b.words = newWords
+}
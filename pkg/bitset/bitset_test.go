@@ -0,0 +1,211 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package bitset
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Builds a Bitset and a map containing the same values
+func randomBitset(r *rand.Rand, store *offheap.Store, count int, maxValue uint32) (*Bitset, map[uint32]bool) {
+	b := New(store)
+	expected := map[uint32]bool{}
+	for range count {
+		value := uint32(r.Int63n(int64(maxValue)))
+		b.Set(value)
+		expected[value] = true
+	}
+	return b, expected
+}
+
+func sortedValues(values map[uint32]bool) []uint32 {
+	sorted := []uint32{}
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	slices.Sort(sorted)
+	return sorted
+}
+
+// Collects the values in the set using NextSet
+func collectNextSet(nextSet func(from uint32) (uint32, bool)) []uint32 {
+	values := []uint32{}
+	for value, ok := nextSet(0); ok; value, ok = nextSet(value + 1) {
+		values = append(values, value)
+		if value == math.MaxUint32 {
+			break
+		}
+	}
+	return values
+}
+
+// Show that an empty bitset behaves sensibly
+func TestBitset_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := New(store)
+
+	assert.Equal(t, 0, b.Count())
+	assert.False(t, b.Test(0))
+	assert.False(t, b.Test(math.MaxUint32))
+	b.Clear(100)
+
+	_, ok := b.NextSet(0)
+	assert.False(t, ok)
+
+	b.Range(func(value uint32) bool {
+		t.Errorf("unexpected value %d", value)
+		return true
+	})
+}
+
+func TestBitset_SetClearTest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b, expected := randomBitset(r, store, 10_000, 1<<20)
+
+	assert.Equal(t, len(expected), b.Count())
+	for value := range uint32(1 << 20) {
+		require.Equal(t, expected[value], b.Test(value))
+	}
+
+	// Clear half of the values
+	for value := range expected {
+		if value%2 == 0 {
+			b.Clear(value)
+			delete(expected, value)
+		}
+	}
+	assert.Equal(t, len(expected), b.Count())
+	for value := range uint32(1 << 20) {
+		require.Equal(t, expected[value], b.Test(value))
+	}
+}
+
+// Show that the bitset grows to hold large values
+func TestBitset_LargeValues(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := New(store)
+
+	b.Set(1 << 24)
+	b.Set(0)
+	assert.True(t, b.Test(1<<24))
+	assert.True(t, b.Test(0))
+	assert.False(t, b.Test(1<<24-1))
+	assert.Equal(t, 2, b.Count())
+
+	assert.Equal(t, []uint32{0, 1 << 24}, collectNextSet(b.NextSet))
+}
+
+func TestBitset_Iteration(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b, expected := randomBitset(r, store, 1000, 100_000)
+	sorted := sortedValues(expected)
+
+	assert.Equal(t, sorted, collectNextSet(b.NextSet))
+
+	ranged := []uint32{}
+	b.Range(func(value uint32) bool {
+		ranged = append(ranged, value)
+		return true
+	})
+	assert.Equal(t, sorted, ranged)
+
+	// Iteration stops when fun returns false
+	count := 0
+	b.Range(func(value uint32) bool {
+		count++
+		return count < 10
+	})
+	assert.Equal(t, 10, count)
+}
+
+func TestBitset_SetOperations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	r := rand.New(rand.NewSource(1))
+
+	for _, maxValues := range [][2]uint32{{1000, 1000}, {1000, 100_000}, {100_000, 1000}} {
+		a, expectedA := randomBitset(r, store, 500, maxValues[0])
+		b, expectedB := randomBitset(r, store, 500, maxValues[1])
+
+		and := New(store)
+		and.Or(a)
+		and.And(b)
+
+		or := New(store)
+		or.Or(a)
+		or.Or(b)
+
+		andNot := New(store)
+		andNot.Or(a)
+		andNot.AndNot(b)
+
+		for value := range max(maxValues[0], maxValues[1]) {
+			inA, inB := expectedA[value], expectedB[value]
+			require.Equal(t, inA && inB, and.Test(value))
+			require.Equal(t, inA || inB, or.Test(value))
+			require.Equal(t, inA && !inB, andNot.Test(value))
+		}
+	}
+}
+
+// Show that freeing a bitset releases its memory
+func TestBitset_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	r := rand.New(rand.NewSource(1))
+	b, _ := randomBitset(r, store, 1000, 1<<20)
+
+	b.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Assert that iterating over a bitset does not allocate
+func TestBitset_Iteration_NoAllocations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b, _ := randomBitset(r, store, 1000, 1<<20)
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		sum := uint32(0)
+		for value, ok := b.NextSet(0); ok; value, ok = b.NextSet(value + 1) {
+			sum += value
+		}
+		b.Range(func(value uint32) bool {
+			sum += value
+			return true
+		})
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
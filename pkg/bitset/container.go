@@ -0,0 +1,252 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package bitset
+
+import (
+	"math/bits"
+	"slices"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The maximum number of values held in an array container. An array of 4096
+// uint16 values uses the same 8KB as a bitmap container, beyond this a bitmap
+// is smaller.
+const arrayMaxSize = 4096
+
+// The number of words needed for a bitmap of every uint16 value.
+const bitmapWords = 1 << 16 / 64
+
+type containerKind uint8
+
+const (
+	arrayContainer containerKind = iota
+	bitmapContainer
+)
+
+// Holds the low 16 bits of every value in a SparseBitset whose high 16 bits
+// are key.
+type container struct {
+	key   uint16
+	kind  containerKind
+	count int
+	// A sorted array of values, only used by array containers
+	array offheap.RefSlice[uint16]
+	// A bitmap of values, only used by bitmap containers
+	bitmap offheap.RefSlice[uint64]
+}
+
+func newArrayContainer(store *offheap.Store, key uint16) container {
+	return container{
+		key:   key,
+		kind:  arrayContainer,
+		array: offheap.AllocSlice[uint16](store, 0, 4),
+	}
+}
+
+// Builds a container from a bitmap. The most compact representation is
+// chosen. If the bitmap is empty false is returned and no container is
+// allocated.
+func containerFromBitmap(store *offheap.Store, key uint16, words *[bitmapWords]uint64) (container, bool) {
+	count := 0
+	for _, word := range words {
+		count += bits.OnesCount64(word)
+	}
+	if count == 0 {
+		return container{}, false
+	}
+
+	if count > arrayMaxSize {
+		bitmap := offheap.AllocSlice[uint64](store, bitmapWords, bitmapWords)
+		copy(bitmap.Value(), words[:])
+		return container{key: key, kind: bitmapContainer, count: count, bitmap: bitmap}, true
+	}
+
+	array := offheap.AllocSlice[uint16](store, count, count)
+	bitmapToArray(array.Value(), words[:])
+	return container{key: key, kind: arrayContainer, count: count, array: array}, true
+}
+
+// Writes the value of every set bit in words into values, in ascending order.
+// values must have exactly enough space for every set bit.
+func bitmapToArray(values []uint16, words []uint64) {
+	i := 0
+	for idx, word := range words {
+		for word != 0 {
+			values[i] = uint16(idx*64 + bits.TrailingZeros64(word))
+			i++
+			word &= word - 1
+		}
+	}
+}
+
+// Clears words, and then sets the bit for each of values.
+func arrayToBitmap(words []uint64, values []uint16) {
+	clear(words)
+	for _, low := range values {
+		words[low/64] |= 1 << (low % 64)
+	}
+}
+
+func (c *container) contains(low uint16) bool {
+	if c.kind == bitmapContainer {
+		return c.bitmap.Value()[low/64]&(1<<(low%64)) != 0
+	}
+	_, found := slices.BinarySearch(c.array.Value(), low)
+	return found
+}
+
+// Adds low to the container. Returns true if low was not already in the
+// container.
+func (c *container) add(store *offheap.Store, low uint16) bool {
+	if c.kind == bitmapContainer {
+		words := c.bitmap.Value()
+		mask := uint64(1) << (low % 64)
+		if words[low/64]&mask != 0 {
+			return false
+		}
+		words[low/64] |= mask
+		c.count++
+		return true
+	}
+
+	idx, found := slices.BinarySearch(c.array.Value(), low)
+	if found {
+		return false
+	}
+	if c.count == arrayMaxSize {
+		c.convertToBitmap(store)
+		return c.add(store, low)
+	}
+	c.array = offheap.InsertAt(store, c.array, idx, low)
+	c.count++
+	return true
+}
+
+// Removes low from the container. Returns true if low was in the container.
+func (c *container) remove(store *offheap.Store, low uint16) bool {
+	if c.kind == bitmapContainer {
+		words := c.bitmap.Value()
+		mask := uint64(1) << (low % 64)
+		if words[low/64]&mask == 0 {
+			return false
+		}
+		words[low/64] &^= mask
+		c.count--
+		if c.count == arrayMaxSize {
+			c.convertToArray(store)
+		}
+		return true
+	}
+
+	idx, found := slices.BinarySearch(c.array.Value(), low)
+	if !found {
+		return false
+	}
+	c.array = offheap.DeleteAt(store, c.array, idx)
+	c.count--
+	return true
+}
+
+// Returns the smallest value in the container which is >= from, and true. If
+// there is no such value false is returned.
+func (c *container) next(from uint16) (uint16, bool) {
+	if c.kind == bitmapContainer {
+		words := c.bitmap.Value()
+		idx := int(from / 64)
+		word := words[idx] &^ ((1 << (from % 64)) - 1)
+		for {
+			if word != 0 {
+				return uint16(idx*64 + bits.TrailingZeros64(word)), true
+			}
+			idx++
+			if idx >= len(words) {
+				return 0, false
+			}
+			word = words[idx]
+		}
+	}
+
+	values := c.array.Value()
+	idx, _ := slices.BinarySearch(values, from)
+	if idx == len(values) {
+		return 0, false
+	}
+	return values[idx], true
+}
+
+// Calls fun with each value in the container, combined with the container's
+// key, in ascending order. Returns false if fun returned false.
+func (c *container) rangeValues(fun func(value uint32) bool) bool {
+	high := uint32(c.key) << 16
+
+	if c.kind == bitmapContainer {
+		for idx, word := range c.bitmap.Value() {
+			for word != 0 {
+				if !fun(high | uint32(idx*64+bits.TrailingZeros64(word))) {
+					return false
+				}
+				word &= word - 1
+			}
+		}
+		return true
+	}
+
+	for _, low := range c.array.Value() {
+		if !fun(high | uint32(low)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Writes the contents of the container into words as a bitmap.
+func (c *container) toBitmap(words *[bitmapWords]uint64) {
+	if c.kind == bitmapContainer {
+		copy(words[:], c.bitmap.Value())
+		return
+	}
+
+	arrayToBitmap(words[:], c.array.Value())
+}
+
+func (c *container) convertToBitmap(store *offheap.Store) {
+	bitmap := offheap.AllocSlice[uint64](store, bitmapWords, bitmapWords)
+	arrayToBitmap(bitmap.Value(), c.array.Value())
+
+	offheap.FreeSlice(store, c.array)
+	c.array = offheap.RefSlice[uint16]{}
+	c.bitmap = bitmap
+	c.kind = bitmapContainer
+}
+
+func (c *container) convertToArray(store *offheap.Store) {
+	array := offheap.AllocSlice[uint16](store, c.count, c.count)
+	bitmapToArray(array.Value(), c.bitmap.Value())
+
+	offheap.FreeSlice(store, c.bitmap)
+	c.bitmap = offheap.RefSlice[uint64]{}
+	c.array = array
+	c.kind = arrayContainer
+}
+
+// Allocates an independent copy of the container.
+func (c *container) clone(store *offheap.Store) container {
+	cloned := *c
+	if c.kind == bitmapContainer {
+		cloned.bitmap = offheap.CloneSlice(store, c.bitmap)
+	} else {
+		cloned.array = offheap.CloneSlice(store, c.array)
+	}
+	return cloned
+}
+
+func (c *container) free(store *offheap.Store) {
+	if c.kind == bitmapContainer {
+		offheap.FreeSlice(store, c.bitmap)
+	} else {
+		offheap.FreeSlice(store, c.array)
+	}
+}
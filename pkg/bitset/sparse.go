@@ -0,0 +1,228 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package bitset
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A compressed bitset, in the style of roaring bitmaps.
+//
+// Values are partitioned by their high 16 bits. Each partition which contains
+// at least one value has a container, holding the low 16 bits of its values.
+// Containers with up to 4096 values store them in a sorted array, larger
+// containers use a bitmap.
+type SparseBitset struct {
+	store *offheap.Store
+	// Sorted by key, every container holds at least one value
+	containers offheap.RefSlice[container]
+}
+
+// Creates a new empty SparseBitset whose memory will be allocated in store.
+func NewSparse(store *offheap.Store) *SparseBitset {
+	return &SparseBitset{
+		store:      store,
+		containers: offheap.AllocSlice[container](store, 0, 0),
+	}
+}
+
+func splitValue(value uint32) (key uint16, low uint16) {
+	return uint16(value >> 16), uint16(value)
+}
+
+// Returns the index of the container for key, and true. If there is no such
+// container, the index where it would be inserted and false are returned.
+func (b *SparseBitset) findContainer(key uint16) (int, bool) {
+	return slices.BinarySearchFunc(b.containers.Value(), key, func(c container, key uint16) int {
+		return cmp.Compare(c.key, key)
+	})
+}
+
+// Adds value to the set.
+func (b *SparseBitset) Set(value uint32) {
+	key, low := splitValue(value)
+	idx, found := b.findContainer(key)
+	if !found {
+		b.containers = offheap.InsertAt(b.store, b.containers, idx, newArrayContainer(b.store, key))
+	}
+	b.containers.Value()[idx].add(b.store, low)
+}
+
+// Removes value from the set.
+func (b *SparseBitset) Clear(value uint32) {
+	key, low := splitValue(value)
+	idx, found := b.findContainer(key)
+	if !found {
+		return
+	}
+
+	c := &b.containers.Value()[idx]
+	if c.remove(b.store, low) && c.count == 0 {
+		b.removeContainer(idx)
+	}
+}
+
+// Returns true if value is in the set, false otherwise.
+func (b *SparseBitset) Test(value uint32) bool {
+	key, low := splitValue(value)
+	idx, found := b.findContainer(key)
+	return found && b.containers.Value()[idx].contains(low)
+}
+
+// Returns the number of values in the set.
+func (b *SparseBitset) Count() int {
+	count := 0
+	for _, c := range b.containers.Value() {
+		count += c.count
+	}
+	return count
+}
+
+// Returns the smallest value in the set which is >= from, and true. If there
+// is no such value false is returned.
+//
+// Together with Test this allows the set to be iterated without allocating
+//
+//	for v, ok := b.NextSet(0); ok; v, ok = b.NextSet(v + 1) {
+//		...
+//	}
+//
+// Care must be taken when the set contains math.MaxUint32, because v+1 will
+// wrap around to 0.
+func (b *SparseBitset) NextSet(from uint32) (uint32, bool) {
+	key, low := splitValue(from)
+	idx, found := b.findContainer(key)
+
+	containers := b.containers.Value()
+	if found {
+		if next, ok := containers[idx].next(low); ok {
+			return uint32(key)<<16 | uint32(next), true
+		}
+		idx++
+	}
+	if idx == len(containers) {
+		return 0, false
+	}
+
+	// Every container is non-empty, so this always finds a value
+	c := &containers[idx]
+	next, _ := c.next(0)
+	return uint32(c.key)<<16 | uint32(next), true
+}
+
+// Calls fun for each value in the set, in ascending order. If fun returns
+// false the iteration stops.
+//
+// The set must not be modified during the iteration.
+func (b *SparseBitset) Range(fun func(value uint32) bool) {
+	containers := b.containers.Value()
+	for i := range containers {
+		if !containers[i].rangeValues(fun) {
+			return
+		}
+	}
+}
+
+// Sets b to the intersection of b and other.
+func (b *SparseBitset) And(other *SparseBitset) {
+	var words, otherWords [bitmapWords]uint64
+
+	for idx := 0; idx < len(b.containers.Value()); {
+		c := &b.containers.Value()[idx]
+		otherIdx, found := other.findContainer(c.key)
+		if !found {
+			b.removeContainer(idx)
+			continue
+		}
+
+		c.toBitmap(&words)
+		other.containers.Value()[otherIdx].toBitmap(&otherWords)
+		for i := range words {
+			words[i] &= otherWords[i]
+		}
+		if b.replaceContainer(idx, &words) {
+			idx++
+		}
+	}
+}
+
+// Sets b to the union of b and other.
+func (b *SparseBitset) Or(other *SparseBitset) {
+	var words, otherWords [bitmapWords]uint64
+
+	otherContainers := other.containers.Value()
+	for otherIdx := range otherContainers {
+		otherContainer := &otherContainers[otherIdx]
+		idx, found := b.findContainer(otherContainer.key)
+		if !found {
+			b.containers = offheap.InsertAt(b.store, b.containers, idx, otherContainer.clone(b.store))
+			continue
+		}
+
+		b.containers.Value()[idx].toBitmap(&words)
+		otherContainer.toBitmap(&otherWords)
+		for i := range words {
+			words[i] |= otherWords[i]
+		}
+		b.replaceContainer(idx, &words)
+	}
+}
+
+// Sets b to the difference of b and other, i.e. the values in b which are not
+// in other.
+func (b *SparseBitset) AndNot(other *SparseBitset) {
+	var words, otherWords [bitmapWords]uint64
+
+	for idx := 0; idx < len(b.containers.Value()); {
+		c := &b.containers.Value()[idx]
+		otherIdx, found := other.findContainer(c.key)
+		if !found {
+			idx++
+			continue
+		}
+
+		c.toBitmap(&words)
+		other.containers.Value()[otherIdx].toBitmap(&otherWords)
+		for i := range words {
+			words[i] &^= otherWords[i]
+		}
+		if b.replaceContainer(idx, &words) {
+			idx++
+		}
+	}
+}
+
+// Frees the memory used by the set. After this call returns the set must
+// never be used again.
+func (b *SparseBitset) Free() {
+	containers := b.containers.Value()
+	for i := range containers {
+		containers[i].free(b.store)
+	}
+	offheap.FreeSlice(b.store, b.containers)
+	b.containers = offheap.RefSlice[container]{}
+}
+
+// Replaces the container at idx with a container built from words. If words
+// is empty the container is removed and false is returned.
+func (b *SparseBitset) replaceContainer(idx int, words *[bitmapWords]uint64) bool {
+	c := &b.containers.Value()[idx]
+	replacement, ok := containerFromBitmap(b.store, c.key, words)
+	if !ok {
+		b.removeContainer(idx)
+		return false
+	}
+	c.free(b.store)
+	*c = replacement
+	return true
+}
+
+func (b *SparseBitset) removeContainer(idx int) {
+	b.containers.Value()[idx].free(b.store)
+	b.containers = offheap.DeleteAt(b.store, b.containers, idx)
+}
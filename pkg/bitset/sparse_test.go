@@ -0,0 +1,246 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package bitset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Builds a SparseBitset and a map containing the same values. Values are
+// clustered into a small number of partitions, some of which are dense
+// enough to use bitmap containers.
+func randomSparse(r *rand.Rand, store *offheap.Store, count int) (*SparseBitset, map[uint32]bool) {
+	b := NewSparse(store)
+	expected := map[uint32]bool{}
+	for range count {
+		value := randomSparseValue(r)
+		b.Set(value)
+		expected[value] = true
+	}
+	return b, expected
+}
+
+func randomSparseValue(r *rand.Rand) uint32 {
+	key := uint32(r.Intn(8)) * 1000
+	// Low keys are densely populated, high keys sparsely
+	low := uint32(r.Intn(1 << 16))
+	if key >= 4000 {
+		low = uint32(r.Intn(1<<16)) &^ 0xFF
+	}
+	return key<<16 | low
+}
+
+func assertSparseContents(t *testing.T, expected map[uint32]bool, b *SparseBitset) {
+	t.Helper()
+
+	require.Equal(t, len(expected), b.Count())
+	for value := range expected {
+		require.True(t, b.Test(value))
+	}
+	require.Equal(t, sortedValues(expected), collectNextSet(b.NextSet))
+}
+
+// Show that an empty bitset behaves sensibly
+func TestSparseBitset_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewSparse(store)
+
+	assert.Equal(t, 0, b.Count())
+	assert.False(t, b.Test(0))
+	assert.False(t, b.Test(math.MaxUint32))
+	b.Clear(100)
+
+	_, ok := b.NextSet(0)
+	assert.False(t, ok)
+
+	b.Range(func(value uint32) bool {
+		t.Errorf("unexpected value %d", value)
+		return true
+	})
+}
+
+func TestSparseBitset_LargeValues(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewSparse(store)
+
+	b.Set(math.MaxUint32)
+	b.Set(0)
+	assert.True(t, b.Test(math.MaxUint32))
+	assert.True(t, b.Test(0))
+	assert.Equal(t, 2, b.Count())
+
+	assert.Equal(t, []uint32{0, math.MaxUint32}, collectNextSet(b.NextSet))
+}
+
+// Show that containers switch between array and bitmap representations as
+// values are added and removed
+func TestSparseBitset_ContainerConversion(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewSparse(store)
+
+	for value := range uint32(arrayMaxSize) {
+		b.Set(value * 2)
+	}
+	assert.Equal(t, arrayContainer, b.containers.Value()[0].kind)
+
+	b.Set(1)
+	assert.Equal(t, bitmapContainer, b.containers.Value()[0].kind)
+	assert.Equal(t, arrayMaxSize+1, b.Count())
+
+	b.Clear(0)
+	assert.Equal(t, arrayContainer, b.containers.Value()[0].kind)
+	assert.Equal(t, arrayMaxSize, b.Count())
+
+	assert.True(t, b.Test(1))
+	assert.False(t, b.Test(0))
+	for value := uint32(1); value < arrayMaxSize; value++ {
+		require.True(t, b.Test(value*2))
+	}
+
+	// Removing every value removes the container
+	b.Clear(1)
+	for value := uint32(1); value < arrayMaxSize; value++ {
+		b.Clear(value * 2)
+	}
+	assert.Equal(t, 0, len(b.containers.Value()))
+}
+
+// Perform a long sequence of random sets and clears, comparing the bitset
+// with a conventional map.
+func TestSparseBitset_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewSparse(store)
+	expected := map[uint32]bool{}
+
+	for i := range 100_000 {
+		value := randomSparseValue(r)
+		if r.Intn(3) == 0 {
+			b.Clear(value)
+			delete(expected, value)
+		} else {
+			b.Set(value)
+			expected[value] = true
+		}
+		if i%10_000 == 0 {
+			assertSparseContents(t, expected, b)
+		}
+	}
+	assertSparseContents(t, expected, b)
+
+	ranged := []uint32{}
+	b.Range(func(value uint32) bool {
+		ranged = append(ranged, value)
+		return true
+	})
+	assert.Equal(t, sortedValues(expected), ranged)
+}
+
+func TestSparseBitset_SetOperations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	r := rand.New(rand.NewSource(1))
+
+	for _, counts := range [][2]int{{100, 100}, {20_000, 100}, {100, 20_000}, {20_000, 20_000}} {
+		a, expectedA := randomSparse(r, store, counts[0])
+		b, expectedB := randomSparse(r, store, counts[1])
+
+		and := NewSparse(store)
+		and.Or(a)
+		and.And(b)
+
+		or := NewSparse(store)
+		or.Or(a)
+		or.Or(b)
+
+		andNot := NewSparse(store)
+		andNot.Or(a)
+		andNot.AndNot(b)
+
+		expectedAnd := map[uint32]bool{}
+		expectedOr := map[uint32]bool{}
+		expectedAndNot := map[uint32]bool{}
+		for value := range expectedA {
+			expectedOr[value] = true
+			if expectedB[value] {
+				expectedAnd[value] = true
+			} else {
+				expectedAndNot[value] = true
+			}
+		}
+		for value := range expectedB {
+			expectedOr[value] = true
+		}
+
+		assertSparseContents(t, expectedAnd, and)
+		assertSparseContents(t, expectedOr, or)
+		assertSparseContents(t, expectedAndNot, andNot)
+
+		// The operands are unchanged
+		assertSparseContents(t, expectedA, a)
+		assertSparseContents(t, expectedB, b)
+	}
+}
+
+// Show that freeing a bitset releases all of its containers
+func TestSparseBitset_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	r := rand.New(rand.NewSource(1))
+	b, _ := randomSparse(r, store, 20_000)
+
+	other, _ := randomSparse(r, store, 20_000)
+	b.And(other)
+	other.Free()
+
+	b.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Assert that iterating over a bitset does not allocate
+func TestSparseBitset_Iteration_NoAllocations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b, _ := randomSparse(r, store, 20_000)
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		sum := uint32(0)
+		for value, ok := b.NextSet(0); ok; value, ok = b.NextSet(value + 1) {
+			sum += value
+		}
+		b.Range(func(value uint32) bool {
+			sum += value
+			return true
+		})
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The btree package provides ordered maps whose nodes are allocated in an
+// offheap.Store. This allows for ordered iteration and range scans over very
+// large maps without impacting garbage collection.
+//
+// BTree accepts any cmp.Ordered key type which contains no pointers. Strings
+// contain pointers and can't be stored directly in a node, StringBTree stores
+// string keys as offheap.RefStrings instead.
+//
+// Neither type is safe for concurrent use.
+package btree
+
+import (
+	"cmp"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// An ordered map from K to V.
+//
+// Neither K nor V may contain pointers. In particular string keys are not
+// supported, use StringBTree instead. Creating a BTree with a key or value
+// type which contains pointers will panic.
+type BTree[K cmp.Ordered, V any] struct {
+	tree tree[K, K, V, orderedKeys[K]]
+}
+
+// Creates a new empty BTree whose nodes will be allocated in store.
+func New[K cmp.Ordered, V any](store *offheap.Store) *BTree[K, V] {
+	return &BTree[K, V]{
+		tree: newTree[K, K, V, orderedKeys[K]](store),
+	}
+}
+
+// Returns the value associated with key, and true. If key is not in the tree
+// the zero value of V and false are returned.
+func (b *BTree[K, V]) Get(key K) (V, bool) {
+	return b.tree.get(key)
+}
+
+// Associates value with key, replacing any existing value.
+func (b *BTree[K, V]) Put(key K, value V) {
+	b.tree.put(key, value)
+}
+
+// Removes key from the tree. Returns true if key was in the tree, false
+// otherwise.
+func (b *BTree[K, V]) Delete(key K) bool {
+	return b.tree.delete(key)
+}
+
+// Returns the number of keys in the tree.
+func (b *BTree[K, V]) Len() int {
+	return b.tree.length
+}
+
+// Returns the smallest key in the tree and its value. If the tree is empty
+// false is returned.
+func (b *BTree[K, V]) Min() (K, V, bool) {
+	return b.tree.min()
+}
+
+// Returns the largest key in the tree and its value. If the tree is empty
+// false is returned.
+func (b *BTree[K, V]) Max() (K, V, bool) {
+	return b.tree.max()
+}
+
+// Calls fun for each key/value in the tree in ascending order of key. If fun
+// returns false the iteration stops.
+//
+// The tree must not be modified during the iteration.
+func (b *BTree[K, V]) Ascend(fun func(key K, value V) bool) {
+	var zero K
+	b.tree.ascend(b.tree.root, zero, false, zero, false, fun)
+}
+
+// Calls fun for each key/value in the tree, where from <= key < to, in
+// ascending order of key. If fun returns false the iteration stops.
+//
+// The tree must not be modified during the iteration.
+func (b *BTree[K, V]) AscendRange(from, to K, fun func(key K, value V) bool) {
+	b.tree.ascend(b.tree.root, from, true, to, true, fun)
+}
+
+// Calls fun for each key/value in the tree in descending order of key. If fun
+// returns false the iteration stops.
+//
+// The tree must not be modified during the iteration.
+func (b *BTree[K, V]) Descend(fun func(key K, value V) bool) {
+	b.tree.descend(b.tree.root, fun)
+}
+
+// Frees all of the nodes in the tree. After this call returns the tree must
+// never be used again.
+func (b *BTree[K, V]) Free() {
+	b.tree.free()
+}
+
+// An ordered map from string to V. The string keys are copied into the
+// offheap.Store as RefStrings.
+//
+// V may not contain pointers. Creating a StringBTree with a value type which
+// contains pointers will panic.
+type StringBTree[V any] struct {
+	tree tree[string, offheap.RefString, V, stringKeys]
+}
+
+// Creates a new empty StringBTree whose nodes and keys will be allocated in
+// store.
+func NewStringKeyed[V any](store *offheap.Store) *StringBTree[V] {
+	return &StringBTree[V]{
+		tree: newTree[string, offheap.RefString, V, stringKeys](store),
+	}
+}
+
+// Returns the value associated with key, and true. If key is not in the tree
+// the zero value of V and false are returned.
+func (b *StringBTree[V]) Get(key string) (V, bool) {
+	return b.tree.get(key)
+}
+
+// Associates value with key, replacing any existing value. If key is not
+// already in the tree a copy of key is allocated.
+func (b *StringBTree[V]) Put(key string, value V) {
+	b.tree.put(key, value)
+}
+
+// Removes key from the tree, freeing its copy of the key. Returns true if key
+// was in the tree, false otherwise.
+func (b *StringBTree[V]) Delete(key string) bool {
+	return b.tree.delete(key)
+}
+
+// Returns the number of keys in the tree.
+func (b *StringBTree[V]) Len() int {
+	return b.tree.length
+}
+
+// Returns the smallest key in the tree and its value. If the tree is empty
+// false is returned.
+//
+// The key returned is backed by the tree's copy of the key, it must not be
+// used after that key is deleted.
+func (b *StringBTree[V]) Min() (string, V, bool) {
+	return b.tree.min()
+}
+
+// Returns the largest key in the tree and its value. If the tree is empty
+// false is returned.
+//
+// The key returned is backed by the tree's copy of the key, it must not be
+// used after that key is deleted.
+func (b *StringBTree[V]) Max() (string, V, bool) {
+	return b.tree.max()
+}
+
+// Calls fun for each key/value in the tree in ascending order of key. If fun
+// returns false the iteration stops.
+//
+// The keys passed to fun are backed by the tree's copy of the key, they must
+// not be used after that key is deleted. The tree must not be modified during
+// the iteration.
+func (b *StringBTree[V]) Ascend(fun func(key string, value V) bool) {
+	b.tree.ascend(b.tree.root, "", false, "", false, fun)
+}
+
+// Calls fun for each key/value in the tree, where from <= key < to, in
+// ascending order of key. If fun returns false the iteration stops.
+//
+// The keys passed to fun are backed by the tree's copy of the key, they must
+// not be used after that key is deleted. The tree must not be modified during
+// the iteration.
+func (b *StringBTree[V]) AscendRange(from, to string, fun func(key string, value V) bool) {
+	b.tree.ascend(b.tree.root, from, true, to, true, fun)
+}
+
+// Calls fun for each key/value in the tree, where key has the given prefix, in
+// ascending order of key. If fun returns false the iteration stops.
+//
+// The keys passed to fun are backed by the tree's copy of the key, they must
+// not be used after that key is deleted. The tree must not be modified during
+// the iteration.
+func (b *StringBTree[V]) AscendPrefix(prefix string, fun func(key string, value V) bool) {
+	if end, ok := prefixEnd(prefix); ok {
+		b.tree.ascend(b.tree.root, prefix, true, end, true, fun)
+		return
+	}
+	// Every key >= prefix has this prefix
+	b.tree.ascend(b.tree.root, prefix, true, "", false, fun)
+}
+
+// Calls fun for each key/value in the tree in descending order of key. If fun
+// returns false the iteration stops.
+//
+// The keys passed to fun are backed by the tree's copy of the key, they must
+// not be used after that key is deleted. The tree must not be modified during
+// the iteration.
+func (b *StringBTree[V]) Descend(fun func(key string, value V) bool) {
+	b.tree.descend(b.tree.root, fun)
+}
+
+// Frees all of the nodes, and keys, in the tree. After this call returns the
+// tree must never be used again.
+func (b *StringBTree[V]) Free() {
+	b.tree.free()
+}
+
+// Returns the smallest string which is greater than every string with the
+// given prefix. If there is no such string, i.e. the prefix is empty or made
+// up entirely of 0xFF bytes, false is returned.
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
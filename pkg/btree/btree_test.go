@@ -0,0 +1,434 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Show that an empty tree behaves sensibly
+func TestBTree_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int, int](store)
+
+	assert.Equal(t, 0, tree.Len())
+
+	_, ok := tree.Get(1)
+	assert.False(t, ok)
+
+	assert.False(t, tree.Delete(1))
+
+	_, _, ok = tree.Min()
+	assert.False(t, ok)
+	_, _, ok = tree.Max()
+	assert.False(t, ok)
+
+	tree.Ascend(func(key, value int) bool {
+		t.Errorf("unexpected key %d", key)
+		return true
+	})
+	tree.Descend(func(key, value int) bool {
+		t.Errorf("unexpected key %d", key)
+		return true
+	})
+}
+
+// Show that string keys are rejected, because they contain pointers
+func TestBTree_StringKeys_Panic(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() {
+		New[string, int](store)
+	})
+}
+
+func TestBTree_PutGet(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int, int](store)
+
+	for i := range 10_000 {
+		tree.Put(i, i*2)
+	}
+	assert.Equal(t, 10_000, tree.Len())
+
+	for i := range 10_000 {
+		value, ok := tree.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i*2, value)
+	}
+
+	// Replacing values does not change the length
+	for i := range 10_000 {
+		tree.Put(i, i*3)
+	}
+	assert.Equal(t, 10_000, tree.Len())
+
+	for i := range 10_000 {
+		value, ok := tree.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i*3, value)
+	}
+
+	_, ok := tree.Get(-1)
+	assert.False(t, ok)
+	_, ok = tree.Get(10_000)
+	assert.False(t, ok)
+
+	checkInvariants(t, &tree.tree)
+}
+
+// Perform a long sequence of random puts and deletes, comparing the tree with
+// a conventional map after every operation.
+func TestBTree_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int64, int64](store)
+	expected := map[int64]int64{}
+
+	for i := range 50_000 {
+		key := r.Int63n(5_000)
+		if r.Intn(3) == 0 {
+			_, inMap := expected[key]
+			delete(expected, key)
+			require.Equal(t, inMap, tree.Delete(key))
+		} else {
+			value := r.Int63()
+			expected[key] = value
+			tree.Put(key, value)
+		}
+		require.Equal(t, len(expected), tree.Len())
+
+		if i%5_000 == 0 {
+			checkInvariants(t, &tree.tree)
+		}
+	}
+
+	checkInvariants(t, &tree.tree)
+	assertContents(t, expected, tree)
+
+	// Delete everything
+	for key := range expected {
+		require.True(t, tree.Delete(key))
+	}
+	assert.Equal(t, 0, tree.Len())
+	checkInvariants(t, &tree.tree)
+}
+
+func TestBTree_MinMax(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int, int](store)
+
+	r := rand.New(rand.NewSource(1))
+	for _, key := range r.Perm(1000) {
+		tree.Put(key+10, key)
+	}
+
+	key, value, ok := tree.Min()
+	assert.True(t, ok)
+	assert.Equal(t, 10, key)
+	assert.Equal(t, 0, value)
+
+	key, value, ok = tree.Max()
+	assert.True(t, ok)
+	assert.Equal(t, 1009, key)
+	assert.Equal(t, 999, value)
+}
+
+func TestBTree_AscendDescend(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int, int](store)
+
+	r := rand.New(rand.NewSource(1))
+	for _, key := range r.Perm(1000) {
+		tree.Put(key, key)
+	}
+
+	ascending := []int{}
+	tree.Ascend(func(key, value int) bool {
+		ascending = append(ascending, key)
+		return true
+	})
+	for i := range ascending {
+		require.Equal(t, i, ascending[i])
+	}
+	assert.Equal(t, 1000, len(ascending))
+
+	descending := []int{}
+	tree.Descend(func(key, value int) bool {
+		descending = append(descending, key)
+		return true
+	})
+	slices.Reverse(descending)
+	assert.Equal(t, ascending, descending)
+
+	// Iteration stops when fun returns false
+	count := 0
+	tree.Ascend(func(key, value int) bool {
+		count++
+		return key < 99
+	})
+	assert.Equal(t, 100, count)
+
+	count = 0
+	tree.Descend(func(key, value int) bool {
+		count++
+		return key > 900
+	})
+	assert.Equal(t, 100, count)
+}
+
+func TestBTree_AscendRange(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int, int](store)
+
+	// Insert only even keys
+	for i := 0; i < 2000; i += 2 {
+		tree.Put(i, i)
+	}
+
+	for _, bounds := range [][2]int{
+		{0, 2000},
+		{-100, 5000},
+		{1, 2},
+		{1, 3},
+		{500, 501},
+		{501, 800},
+		{1500, 1000},
+		{1998, 1999},
+	} {
+		t.Run(fmt.Sprintf("from %d to %d", bounds[0], bounds[1]), func(t *testing.T) {
+			from, to := bounds[0], bounds[1]
+
+			expected := []int{}
+			for i := 0; i < 2000; i += 2 {
+				if i >= from && i < to {
+					expected = append(expected, i)
+				}
+			}
+
+			actual := []int{}
+			tree.AscendRange(from, to, func(key, value int) bool {
+				actual = append(actual, key)
+				return true
+			})
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+// Show that freeing a tree releases all of its node allocations
+func TestBTree_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int, int](store)
+
+	for i := range 10_000 {
+		tree.Put(i, i)
+	}
+	assert.NotEqual(t, 0, offheap.StatsForType[node[int, int]](store).Live)
+
+	tree.Free()
+	assert.Equal(t, 0, offheap.StatsForType[node[int, int]](store).Live)
+}
+
+func TestStringBTree_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := NewStringKeyed[int64](store)
+	expected := map[string]int64{}
+
+	for i := range 20_000 {
+		key := fmt.Sprintf("key-%d", r.Intn(2_000))
+		if r.Intn(3) == 0 {
+			_, inMap := expected[key]
+			delete(expected, key)
+			require.Equal(t, inMap, tree.Delete(key))
+		} else {
+			value := r.Int63()
+			expected[key] = value
+			tree.Put(key, value)
+		}
+		require.Equal(t, len(expected), tree.Len())
+
+		if i%2_000 == 0 {
+			checkInvariants(t, &tree.tree)
+		}
+	}
+
+	checkInvariants(t, &tree.tree)
+
+	keys := []string{}
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	actualKeys := []string{}
+	tree.Ascend(func(key string, value int64) bool {
+		actualKeys = append(actualKeys, key)
+		require.Equal(t, expected[key], value)
+		return true
+	})
+	assert.Equal(t, keys, actualKeys)
+
+	for key, expectedValue := range expected {
+		value, ok := tree.Get(key)
+		require.True(t, ok)
+		require.Equal(t, expectedValue, value)
+	}
+}
+
+// Show that the string keyed tree frees its copies of the keys
+func TestStringBTree_FreesKeys(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := NewStringKeyed[int](store)
+
+	for i := range 1000 {
+		tree.Put(fmt.Sprintf("%08d", i), i)
+	}
+	assert.Equal(t, 1000, offheap.StatsForString(store, 8).Live)
+
+	for i := range 500 {
+		require.True(t, tree.Delete(fmt.Sprintf("%08d", i)))
+	}
+	assert.Equal(t, 500, offheap.StatsForString(store, 8).Live)
+
+	tree.Free()
+	assert.Equal(t, 0, offheap.StatsForString(store, 8).Live)
+}
+
+func TestStringBTree_AscendPrefix(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := NewStringKeyed[int](store)
+
+	keys := []string{
+		"/api/users",
+		"/api/users/1",
+		"/api/users/2",
+		"/api/orders",
+		"/static/app.js",
+		"/",
+		"\xff",
+		"\xff\xff",
+	}
+	for i, key := range keys {
+		tree.Put(key, i)
+	}
+
+	collect := func(prefix string) []string {
+		found := []string{}
+		tree.AscendPrefix(prefix, func(key string, value int) bool {
+			found = append(found, key)
+			return true
+		})
+		return found
+	}
+
+	assert.Equal(t, []string{"/api/users", "/api/users/1", "/api/users/2"}, collect("/api/users"))
+	assert.Equal(t, []string{"/api/orders", "/api/users", "/api/users/1", "/api/users/2"}, collect("/api/"))
+	assert.Equal(t, []string{"\xff", "\xff\xff"}, collect("\xff"))
+	assert.Equal(t, []string{}, collect("/missing"))
+	assert.Equal(t, len(keys), len(collect("")))
+}
+
+func assertContents(t *testing.T, expected map[int64]int64, tree *BTree[int64, int64]) {
+	t.Helper()
+
+	for key, expectedValue := range expected {
+		value, ok := tree.Get(key)
+		require.True(t, ok)
+		require.Equal(t, expectedValue, value)
+	}
+
+	count := 0
+	tree.Ascend(func(key, value int64) bool {
+		count++
+		require.Equal(t, expected[key], value)
+		return true
+	})
+	assert.Equal(t, len(expected), count)
+}
+
+// Walks the entire tree checking that every node has a legal number of keys,
+// that keys are in strictly ascending order and that all leaves are at the
+// same depth.
+func checkInvariants[Q any, S any, V any, O keyOps[Q, S]](t *testing.T, tr *tree[Q, S, V, O]) {
+	t.Helper()
+
+	leafDepth := -1
+	count := 0
+	var prev *S
+
+	var walk func(ref offheap.RefObject[node[S, V]], depth int, isRoot bool)
+	walk = func(ref offheap.RefObject[node[S, V]], depth int, isRoot bool) {
+		n := ref.Value()
+		require.LessOrEqual(t, n.count, maxKeys)
+		if !isRoot {
+			require.GreaterOrEqual(t, n.count, minDegree-1)
+		}
+
+		if n.leaf {
+			if leafDepth == -1 {
+				leafDepth = depth
+			}
+			require.Equal(t, leafDepth, depth)
+		}
+
+		for i := 0; i < n.count; i++ {
+			if !n.leaf {
+				walk(n.children[i], depth+1, false)
+			}
+			if prev != nil {
+				require.Less(t, tr.ops.compare(tr.ops.fromStored(prev), &n.keys[i]), 0)
+			}
+			prev = &n.keys[i]
+			count++
+		}
+		if !n.leaf {
+			walk(n.children[n.count], depth+1, false)
+		}
+	}
+
+	walk(tr.root, 0, true)
+	require.Equal(t, tr.length, count)
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package btree
+
+import (
+	"cmp"
+	"strings"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The keys stored in tree nodes don't need to have the same type as the keys
+// used to query the tree. keyOps describes how query keys of type Q are
+// compared with, converted to and recovered from stored keys of type S.
+type keyOps[Q any, S any] interface {
+	// Compares the query key q with the stored key s, in the style of
+	// cmp.Compare
+	compare(q Q, s *S) int
+	// Converts a query key into a new stored key
+	toStored(store *offheap.Store, q Q) S
+	// Converts a stored key back into a query key
+	fromStored(s *S) Q
+	// Frees any allocations associated with the stored key
+	free(store *offheap.Store, s S)
+}
+
+// Ordered keys are stored directly in the tree nodes.
+type orderedKeys[K cmp.Ordered] struct{}
+
+func (orderedKeys[K]) compare(q K, s *K) int {
+	return cmp.Compare(q, *s)
+}
+
+func (orderedKeys[K]) toStored(_ *offheap.Store, q K) K {
+	return q
+}
+
+func (orderedKeys[K]) fromStored(s *K) K {
+	return *s
+}
+
+func (orderedKeys[K]) free(_ *offheap.Store, _ K) {}
+
+// String keys are stored in the tree nodes as RefStrings.
+type stringKeys struct{}
+
+func (stringKeys) compare(q string, s *offheap.RefString) int {
+	return strings.Compare(q, s.Value())
+}
+
+func (stringKeys) toStored(store *offheap.Store, q string) offheap.RefString {
+	return offheap.AllocStringFromString(store, q)
+}
+
+func (stringKeys) fromStored(s *offheap.RefString) string {
+	return s.Value()
+}
+
+func (stringKeys) free(store *offheap.Store, s offheap.RefString) {
+	offheap.FreeString(store, s)
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package btree
+
+import (
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The minimum degree of the tree. Every node, except the root, contains
+// between minDegree-1 and maxKeys keys.
+const minDegree = 16
+
+const maxKeys = 2*minDegree - 1
+
+const maxChildren = 2 * minDegree
+
+// A node in the tree. If the node is a leaf the children are unused.
+//
+// Keys and values are stored in separate arrays, this keeps the keys compact
+// which makes searching a node faster.
+type node[S any, V any] struct {
+	count    int
+	leaf     bool
+	keys     [maxKeys]S
+	values   [maxKeys]V
+	children [maxChildren]offheap.RefObject[node[S, V]]
+}
+
+// Inserts key/value at idx shifting all the following keys and values to
+// the right. The node must not be full.
+func (n *node[S, V]) insertAt(idx int, key S, value V) {
+	copy(n.keys[idx+1:n.count+1], n.keys[idx:n.count])
+	copy(n.values[idx+1:n.count+1], n.values[idx:n.count])
+	n.keys[idx] = key
+	n.values[idx] = value
+	n.count++
+}
+
+// Inserts child at idx shifting all the following children to the right. The
+// children must not be full.
+func (n *node[S, V]) insertChildAt(idx int, child offheap.RefObject[node[S, V]]) {
+	copy(n.children[idx+1:n.count+2], n.children[idx:n.count+1])
+	n.children[idx] = child
+}
+
+// Removes the key/value at idx shifting all the following keys and values to
+// the left.
+func (n *node[S, V]) removeAt(idx int) {
+	copy(n.keys[idx:], n.keys[idx+1:n.count])
+	copy(n.values[idx:], n.values[idx+1:n.count])
+	n.count--
+}
+
+// Removes the child at idx shifting all the following children to the left.
+// This must be called _before_ the matching key is removed with removeAt.
+func (n *node[S, V]) removeChildAt(idx int) {
+	copy(n.children[idx:], n.children[idx+1:n.count+1])
+}
@@ -0,0 +1,395 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package btree
+
+import (
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The generic B-tree implementation shared by BTree and StringBTree. Queries
+// are made with keys of type Q, which are stored in nodes as keys of type S.
+type tree[Q any, S any, V any, O keyOps[Q, S]] struct {
+	store  *offheap.Store
+	root   offheap.RefObject[node[S, V]]
+	length int
+	ops    O
+}
+
+func newTree[Q any, S any, V any, O keyOps[Q, S]](store *offheap.Store) tree[Q, S, V, O] {
+	t := tree[Q, S, V, O]{
+		store: store,
+	}
+	// Allocate the root eagerly, this will panic here if the node type
+	// contains pointers
+	t.root = t.allocNode(true)
+	return t
+}
+
+func (t *tree[Q, S, V, O]) allocNode(leaf bool) offheap.RefObject[node[S, V]] {
+	ref := offheap.AllocObject[node[S, V]](t.store)
+	n := ref.Value()
+	n.count = 0
+	n.leaf = leaf
+	return ref
+}
+
+// Returns the index of the first key in n which is >= q, and whether that key
+// is equal to q.
+func (t *tree[Q, S, V, O]) find(n *node[S, V], q Q) (int, bool) {
+	lo, hi := 0, n.count
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if t.ops.compare(q, &n.keys[mid]) > 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo < n.count && t.ops.compare(q, &n.keys[lo]) == 0
+}
+
+func (t *tree[Q, S, V, O]) get(q Q) (V, bool) {
+	n := t.root.Value()
+	for {
+		idx, found := t.find(n, q)
+		if found {
+			return n.values[idx], true
+		}
+		if n.leaf {
+			var zero V
+			return zero, false
+		}
+		n = n.children[idx].Value()
+	}
+}
+
+func (t *tree[Q, S, V, O]) put(q Q, value V) {
+	root := t.root.Value()
+	if root.count == maxKeys {
+		// The root is full, grow the tree by one level
+		newRootRef := t.allocNode(false)
+		newRoot := newRootRef.Value()
+		newRoot.children[0] = t.root
+		t.splitChild(newRoot, 0)
+		t.root = newRootRef
+	}
+
+	if t.insertNonFull(t.root, q, value) {
+		t.length++
+	}
+}
+
+// Inserts q/value into the subtree rooted at ref, which must not be full.
+// Full nodes are split on the way down, so we never need to walk back up the
+// tree. Returns true if a new key was inserted, false if an existing key had
+// its value replaced.
+func (t *tree[Q, S, V, O]) insertNonFull(ref offheap.RefObject[node[S, V]], q Q, value V) bool {
+	for {
+		n := ref.Value()
+		idx, found := t.find(n, q)
+		if found {
+			n.values[idx] = value
+			return false
+		}
+
+		if n.leaf {
+			n.insertAt(idx, t.ops.toStored(t.store, q), value)
+			return true
+		}
+
+		if n.children[idx].Value().count == maxKeys {
+			t.splitChild(n, idx)
+			// The median key of the child has moved up into n at
+			// idx, work out which side of it q belongs
+			c := t.ops.compare(q, &n.keys[idx])
+			if c == 0 {
+				n.values[idx] = value
+				return false
+			}
+			if c > 0 {
+				idx++
+			}
+		}
+		ref = n.children[idx]
+	}
+}
+
+// Splits the full child at idx into two nodes, the median key of the child is
+// moved up into parent.
+func (t *tree[Q, S, V, O]) splitChild(parent *node[S, V], idx int) {
+	left := parent.children[idx].Value()
+	rightRef := t.allocNode(left.leaf)
+	right := rightRef.Value()
+
+	// Move the top half of left into right
+	right.count = minDegree - 1
+	copy(right.keys[:], left.keys[minDegree:maxKeys])
+	copy(right.values[:], left.values[minDegree:maxKeys])
+	if !left.leaf {
+		copy(right.children[:], left.children[minDegree:maxChildren])
+	}
+	left.count = minDegree - 1
+
+	// Move the median into the parent
+	parent.insertChildAt(idx+1, rightRef)
+	parent.insertAt(idx, left.keys[minDegree-1], left.values[minDegree-1])
+}
+
+func (t *tree[Q, S, V, O]) delete(q Q) bool {
+	deleted := t.deleteFrom(t.root, q, true)
+	if deleted {
+		t.length--
+	}
+
+	// If the root has become empty, shrink the tree by one level
+	root := t.root.Value()
+	if root.count == 0 && !root.leaf {
+		oldRoot := t.root
+		t.root = root.children[0]
+		offheap.FreeObject(t.store, oldRoot)
+	}
+
+	return deleted
+}
+
+// Deletes q from the subtree rooted at ref. Any node we descend into is first
+// given at least minDegree keys, so a key can always be removed from it
+// without walking back up the tree.
+//
+// If freeKey is false the stored key is not freed, this is used when the key
+// has been moved into another node.
+func (t *tree[Q, S, V, O]) deleteFrom(ref offheap.RefObject[node[S, V]], q Q, freeKey bool) bool {
+	for {
+		n := ref.Value()
+		idx, found := t.find(n, q)
+
+		if found && n.leaf {
+			if freeKey {
+				t.ops.free(t.store, n.keys[idx])
+			}
+			n.removeAt(idx)
+			return true
+		}
+
+		if found {
+			left := n.children[idx].Value()
+			right := n.children[idx+1].Value()
+
+			switch {
+			case left.count >= minDegree:
+				// Replace the key with its predecessor, then
+				// delete the predecessor from the left subtree
+				predKey, predValue := t.maxEntry(left)
+				if freeKey {
+					t.ops.free(t.store, n.keys[idx])
+				}
+				n.keys[idx] = predKey
+				n.values[idx] = predValue
+				return t.deleteFrom(n.children[idx], t.ops.fromStored(&predKey), false)
+			case right.count >= minDegree:
+				// Replace the key with its successor, then
+				// delete the successor from the right subtree
+				succKey, succValue := t.minEntry(right)
+				if freeKey {
+					t.ops.free(t.store, n.keys[idx])
+				}
+				n.keys[idx] = succKey
+				n.values[idx] = succValue
+				return t.deleteFrom(n.children[idx+1], t.ops.fromStored(&succKey), false)
+			default:
+				// Both children are minimal, merge them with
+				// the key and delete from the merged child
+				t.merge(n, idx)
+				ref = n.children[idx]
+				continue
+			}
+		}
+
+		if n.leaf {
+			return false
+		}
+
+		// Make sure the child we descend into has enough keys
+		if n.children[idx].Value().count == minDegree-1 {
+			switch {
+			case idx > 0 && n.children[idx-1].Value().count >= minDegree:
+				t.borrowFromLeft(n, idx)
+			case idx < n.count && n.children[idx+1].Value().count >= minDegree:
+				t.borrowFromRight(n, idx)
+			case idx < n.count:
+				t.merge(n, idx)
+			default:
+				t.merge(n, idx-1)
+				idx--
+			}
+		}
+		ref = n.children[idx]
+	}
+}
+
+// Merges the child at idx+1, and the key at idx, into the child at idx. The
+// merged child node is freed.
+func (t *tree[Q, S, V, O]) merge(n *node[S, V], idx int) {
+	leftRef := n.children[idx]
+	rightRef := n.children[idx+1]
+	left := leftRef.Value()
+	right := rightRef.Value()
+
+	left.keys[left.count] = n.keys[idx]
+	left.values[left.count] = n.values[idx]
+	copy(left.keys[left.count+1:], right.keys[:right.count])
+	copy(left.values[left.count+1:], right.values[:right.count])
+	if !left.leaf {
+		copy(left.children[left.count+1:], right.children[:right.count+1])
+	}
+	left.count += right.count + 1
+
+	n.removeChildAt(idx + 1)
+	n.removeAt(idx)
+
+	offheap.FreeObject(t.store, rightRef)
+}
+
+// Moves a key from the child at idx-1, through n, into the child at idx.
+func (t *tree[Q, S, V, O]) borrowFromLeft(n *node[S, V], idx int) {
+	child := n.children[idx].Value()
+	sibling := n.children[idx-1].Value()
+
+	if !child.leaf {
+		child.insertChildAt(0, sibling.children[sibling.count])
+	}
+	child.insertAt(0, n.keys[idx-1], n.values[idx-1])
+
+	n.keys[idx-1] = sibling.keys[sibling.count-1]
+	n.values[idx-1] = sibling.values[sibling.count-1]
+	sibling.count--
+}
+
+// Moves a key from the child at idx+1, through n, into the child at idx.
+func (t *tree[Q, S, V, O]) borrowFromRight(n *node[S, V], idx int) {
+	child := n.children[idx].Value()
+	sibling := n.children[idx+1].Value()
+
+	child.keys[child.count] = n.keys[idx]
+	child.values[child.count] = n.values[idx]
+	if !child.leaf {
+		child.children[child.count+1] = sibling.children[0]
+	}
+	child.count++
+
+	n.keys[idx] = sibling.keys[0]
+	n.values[idx] = sibling.values[0]
+	if !sibling.leaf {
+		sibling.removeChildAt(0)
+	}
+	sibling.removeAt(0)
+}
+
+func (t *tree[Q, S, V, O]) minEntry(n *node[S, V]) (S, V) {
+	for !n.leaf {
+		n = n.children[0].Value()
+	}
+	return n.keys[0], n.values[0]
+}
+
+func (t *tree[Q, S, V, O]) maxEntry(n *node[S, V]) (S, V) {
+	for !n.leaf {
+		n = n.children[n.count].Value()
+	}
+	return n.keys[n.count-1], n.values[n.count-1]
+}
+
+func (t *tree[Q, S, V, O]) min() (Q, V, bool) {
+	if t.length == 0 {
+		var zeroQ Q
+		var zeroV V
+		return zeroQ, zeroV, false
+	}
+	key, value := t.minEntry(t.root.Value())
+	return t.ops.fromStored(&key), value, true
+}
+
+func (t *tree[Q, S, V, O]) max() (Q, V, bool) {
+	if t.length == 0 {
+		var zeroQ Q
+		var zeroV V
+		return zeroQ, zeroV, false
+	}
+	key, value := t.maxEntry(t.root.Value())
+	return t.ops.fromStored(&key), value, true
+}
+
+// Calls fun, in ascending order, for each key in [from, to). The bounds are
+// ignored if hasFrom or hasTo are false. Returns false if the iteration was
+// stopped early.
+func (t *tree[Q, S, V, O]) ascend(ref offheap.RefObject[node[S, V]], from Q, hasFrom bool, to Q, hasTo bool, fun func(key Q, value V) bool) bool {
+	n := ref.Value()
+
+	start := 0
+	if hasFrom {
+		start, _ = t.find(n, from)
+	}
+
+	for i := start; i < n.count; i++ {
+		if !n.leaf {
+			if !t.ascend(n.children[i], from, hasFrom, to, hasTo, fun) {
+				return false
+			}
+		}
+		if hasTo && t.ops.compare(to, &n.keys[i]) <= 0 {
+			return false
+		}
+		if !fun(t.ops.fromStored(&n.keys[i]), n.values[i]) {
+			return false
+		}
+	}
+
+	if !n.leaf {
+		return t.ascend(n.children[n.count], from, hasFrom, to, hasTo, fun)
+	}
+	return true
+}
+
+// Calls fun, in descending order, for each key in the tree. Returns false if
+// the iteration was stopped early.
+func (t *tree[Q, S, V, O]) descend(ref offheap.RefObject[node[S, V]], fun func(key Q, value V) bool) bool {
+	n := ref.Value()
+
+	for i := n.count - 1; i >= 0; i-- {
+		if !n.leaf {
+			if !t.descend(n.children[i+1], fun) {
+				return false
+			}
+		}
+		if !fun(t.ops.fromStored(&n.keys[i]), n.values[i]) {
+			return false
+		}
+	}
+
+	if !n.leaf {
+		return t.descend(n.children[0], fun)
+	}
+	return true
+}
+
+// Frees every node, and stored key, in the subtree rooted at ref.
+func (t *tree[Q, S, V, O]) freeNode(ref offheap.RefObject[node[S, V]]) {
+	n := ref.Value()
+	for i := 0; i < n.count; i++ {
+		t.ops.free(t.store, n.keys[i])
+	}
+	if !n.leaf {
+		for i := 0; i <= n.count; i++ {
+			t.freeNode(n.children[i])
+		}
+	}
+	offheap.FreeObject(t.store, ref)
+}
+
+func (t *tree[Q, S, V, O]) free() {
+	t.freeNode(t.root)
+	t.root = offheap.RefObject[node[S, V]]{}
+	t.length = 0
+}
@@ -0,0 +1,379 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The cache package provides an LRU cache whose entries, and hash index, are
+// allocated in an offheap.Store. This allows very large caches to be built
+// with no impact on the cost of garbage collection.
+//
+// The cache can be bounded by the number of entries, by the total size of its
+// entries or both. Entries can optionally expire after a time-to-live.
+//
+// Neither keys nor values may contain pointers. Values can refer to other
+// offheap allocations, such as RefString or RefSlice payloads, which can be
+// freed when they leave the cache using Config.OnRemove.
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/fmstephe/memorymanager/pkg/internal/keyhash"
+)
+
+const initialBuckets = 16
+
+// An entry in the cache. Each entry is a node in two structures. A singly
+// linked chain in the hash index bucket and the doubly linked LRU list.
+type entry[K comparable, V comparable] struct {
+	key     K
+	value   V
+	hash    uint64
+	expires int64 // UnixNano, 0 indicates no expiry
+	size    int
+	// Links in the LRU list
+	prev offheap.RefObject[entry[K, V]]
+	next offheap.RefObject[entry[K, V]]
+	// Link in the hash bucket chain
+	chain offheap.RefObject[entry[K, V]]
+}
+
+func sizeOfEntry[K comparable, V comparable]() int {
+	return int(unsafe.Sizeof(entry[K, V]{}))
+}
+
+// An LRU cache from K to V.
+//
+// Keys are hashed using their in-memory representation, so K must be built
+// from bools and integers, possibly in arrays and structs, and must not contain
+// padding. Creating a Cache with any other key type will panic. V may not
+// contain pointers, creating a Cache with a value type which contains pointers
+// will panic. Every type without pointers is comparable, and Set compares
+// values using ==, see Config.OnRemove.
+//
+// A Cache is safe for concurrent use.
+type Cache[K comparable, V comparable] struct {
+	// Immutable fields
+	maxEntries int
+	maxBytes   int
+	ttl        time.Duration
+	size       func(key K, value V) int
+	onRemove   func(key K, value V)
+	store      *offheap.Store
+	now        func() time.Time
+	//
+	lock    sync.Mutex
+	buckets offheap.RefSlice[offheap.RefObject[entry[K, V]]]
+	// The sentinel node of the LRU list, sentinel.next is the most
+	// recently used entry and sentinel.prev is the least recently used
+	sentinel offheap.RefObject[entry[K, V]]
+	stats    Stats
+}
+
+// Construct a new Cache with the provided config.
+func New[K comparable, V comparable](config Config[K, V]) *Cache[K, V] {
+	if err := keyhash.CheckKey[K](); err != nil {
+		panic(fmt.Errorf("cannot create cache %w", err))
+	}
+
+	store := config.getStore()
+
+	// Allocating the sentinel here will panic if K or V contain pointers
+	sentinel := offheap.AllocObject[entry[K, V]](store)
+	s := sentinel.Value()
+	s.next = sentinel
+	s.prev = sentinel
+
+	return &Cache[K, V]{
+		maxEntries: config.getMaxEntries(),
+		maxBytes:   config.getMaxBytes(),
+		ttl:        config.getTTL(),
+		size:       config.getSize(),
+		onRemove:   config.getOnRemove(),
+		store:      store,
+		now:        time.Now,
+		buckets:    allocBuckets[K, V](store, initialBuckets),
+		sentinel:   sentinel,
+	}
+}
+
+func allocBuckets[K comparable, V comparable](store *offheap.Store, size int) offheap.RefSlice[offheap.RefObject[entry[K, V]]] {
+	buckets := offheap.AllocSlice[offheap.RefObject[entry[K, V]]](store, size, size)
+	clear(buckets.Value())
+	return buckets
+}
+
+// Returns the value associated with key, and true. If key is not in the
+// cache, or its entry has expired, the zero value of V and false are
+// returned.
+//
+// A successful Get marks the entry as the most recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ref, e := c.find(keyhash.Hash(&key), key)
+	if e == nil {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+
+	if c.isExpired(e) {
+		c.remove(ref, e)
+		c.stats.Expirations++
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+
+	c.moveToFront(ref, e)
+	c.stats.Hits++
+	return e.value, true
+}
+
+// Associates value with key, using the default time-to-live from the Config.
+// Any existing value for key is replaced.
+//
+// If adding this entry exceeds the cache's limits, the least recently used
+// entries are evicted.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// Associates value with key, the entry will expire after ttl. If ttl <= 0 the
+// entry will not expire. Any existing value for key is replaced.
+//
+// If adding this entry exceeds the cache's limits, the least recently used
+// entries are evicted. If the entry is larger than MaxBytes on its own then it
+// is evicted immediately.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expires := int64(0)
+	if ttl > 0 {
+		expires = c.now().Add(ttl).UnixNano()
+	}
+	size := c.size(key, value)
+
+	hash := keyhash.Hash(&key)
+	ref, e := c.find(hash, key)
+
+	if c.maxBytes > 0 && size > c.maxBytes {
+		// This entry can never fit in the cache. Rather than evicting
+		// every other entry, we remove any existing entry for key and
+		// evict the new value immediately.
+		same := false
+		if e != nil {
+			same = e.value == value
+			c.remove(ref, e)
+		}
+		if !same {
+			// If the values are the same, the value has already
+			// been passed to OnRemove by remove
+			c.onRemove(key, value)
+		}
+		c.stats.Evictions++
+		return
+	}
+
+	if e != nil {
+		// Replace the existing entry's value. If the same value is
+		// being set again it hasn't left the cache.
+		if e.value != value {
+			c.onRemove(e.key, e.value)
+		}
+		c.stats.UsedBytes += size - e.size
+		e.value = value
+		e.expires = expires
+		e.size = size
+		c.moveToFront(ref, e)
+	} else {
+		ref = offheap.AllocObject[entry[K, V]](c.store)
+		e = ref.Value()
+		e.key = key
+		e.value = value
+		e.hash = hash
+		e.expires = expires
+		e.size = size
+		c.pushFront(ref, e)
+		c.insertIndex(ref, e)
+		c.stats.Entries++
+		c.stats.UsedBytes += size
+		c.maybeGrowIndex()
+	}
+
+	c.evict()
+}
+
+// Removes key from the cache. Returns true if key was in the cache, false
+// otherwise.
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ref, e := c.find(keyhash.Hash(&key), key)
+	if e == nil {
+		return false
+	}
+
+	c.remove(ref, e)
+	return true
+}
+
+// Returns the number of entries in the cache. This may include expired
+// entries which have not yet been removed.
+func (c *Cache[K, V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.stats.Entries
+}
+
+// Retrieves the stats for this cache.
+func (c *Cache[K, V]) GetStats() Stats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.stats
+}
+
+// Frees every entry in the cache, and the cache's index. OnRemove is not
+// called for the freed entries. After this call returns the cache must never
+// be used again.
+func (c *Cache[K, V]) Free() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sentinel := c.sentinel.Value()
+	for ref := sentinel.next; ref != c.sentinel; {
+		next := ref.Value().next
+		offheap.FreeObject(c.store, ref)
+		ref = next
+	}
+	offheap.FreeObject(c.store, c.sentinel)
+	offheap.FreeSlice(c.store, c.buckets)
+}
+
+func (c *Cache[K, V]) bucketIndex(hash uint64) int {
+	return int(hash & uint64(len(c.buckets.Value())-1))
+}
+
+func (c *Cache[K, V]) find(hash uint64, key K) (offheap.RefObject[entry[K, V]], *entry[K, V]) {
+	buckets := c.buckets.Value()
+	ref := buckets[c.bucketIndex(hash)]
+	for !ref.IsNil() {
+		e := ref.Value()
+		if e.hash == hash && e.key == key {
+			return ref, e
+		}
+		ref = e.chain
+	}
+	return ref, nil
+}
+
+func (c *Cache[K, V]) isExpired(e *entry[K, V]) bool {
+	return e.expires != 0 && c.now().UnixNano() >= e.expires
+}
+
+// Evicts the least recently used entries until the cache is within its limits.
+func (c *Cache[K, V]) evict() {
+	sentinel := c.sentinel.Value()
+	for c.stats.Entries > 0 && c.overLimit() {
+		ref := sentinel.prev
+		e := ref.Value()
+		if c.isExpired(e) {
+			c.stats.Expirations++
+		} else {
+			c.stats.Evictions++
+		}
+		c.remove(ref, e)
+	}
+}
+
+func (c *Cache[K, V]) overLimit() bool {
+	if c.maxEntries > 0 && c.stats.Entries > c.maxEntries {
+		return true
+	}
+	if c.maxBytes > 0 && c.stats.UsedBytes > c.maxBytes {
+		return true
+	}
+	return false
+}
+
+// Removes the entry from the index and the LRU list, and frees it.
+func (c *Cache[K, V]) remove(ref offheap.RefObject[entry[K, V]], e *entry[K, V]) {
+	c.removeIndex(ref, e)
+	c.unlink(e)
+	c.stats.Entries--
+	c.stats.UsedBytes -= e.size
+	c.onRemove(e.key, e.value)
+	offheap.FreeObject(c.store, ref)
+}
+
+func (c *Cache[K, V]) insertIndex(ref offheap.RefObject[entry[K, V]], e *entry[K, V]) {
+	buckets := c.buckets.Value()
+	idx := c.bucketIndex(e.hash)
+	e.chain = buckets[idx]
+	buckets[idx] = ref
+}
+
+func (c *Cache[K, V]) removeIndex(ref offheap.RefObject[entry[K, V]], e *entry[K, V]) {
+	buckets := c.buckets.Value()
+	idx := c.bucketIndex(e.hash)
+
+	if buckets[idx] == ref {
+		buckets[idx] = e.chain
+		return
+	}
+
+	prev := buckets[idx].Value()
+	for prev.chain != ref {
+		prev = prev.chain.Value()
+	}
+	prev.chain = e.chain
+}
+
+// Doubles the size of the index when the number of entries exceeds the
+// number of buckets.
+func (c *Cache[K, V]) maybeGrowIndex() {
+	oldBuckets := c.buckets
+	size := len(oldBuckets.Value())
+	if c.stats.Entries <= size {
+		return
+	}
+
+	c.buckets = allocBuckets[K, V](c.store, size*2)
+
+	// Every entry is in the LRU list, reinsert them all into the new index
+	sentinel := c.sentinel.Value()
+	for ref := sentinel.next; ref != c.sentinel; {
+		e := ref.Value()
+		c.insertIndex(ref, e)
+		ref = e.next
+	}
+
+	offheap.FreeSlice(c.store, oldBuckets)
+}
+
+func (c *Cache[K, V]) pushFront(ref offheap.RefObject[entry[K, V]], e *entry[K, V]) {
+	sentinel := c.sentinel.Value()
+	e.prev = c.sentinel
+	e.next = sentinel.next
+	sentinel.next.Value().prev = ref
+	sentinel.next = ref
+}
+
+func (c *Cache[K, V]) unlink(e *entry[K, V]) {
+	e.prev.Value().next = e.next
+	e.next.Value().prev = e.prev
+}
+
+func (c *Cache[K, V]) moveToFront(ref offheap.RefObject[entry[K, V]], e *entry[K, V]) {
+	c.unlink(e)
+	c.pushFront(ref, e)
+}
@@ -0,0 +1,390 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	a, b int64
+}
+
+// A controllable clock for testing expiry
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestCache_SetGet(t *testing.T) {
+	cache := New(Config[int64, testValue]{})
+
+	for i := range int64(10_000) {
+		cache.Set(i, testValue{a: i, b: -i})
+	}
+	assert.Equal(t, 10_000, cache.Len())
+
+	for i := range int64(10_000) {
+		value, ok := cache.Get(i)
+		require.True(t, ok)
+		require.Equal(t, testValue{a: i, b: -i}, value)
+	}
+
+	_, ok := cache.Get(-1)
+	assert.False(t, ok)
+
+	expectedStats := Stats{
+		Hits:      10_000,
+		Misses:    1,
+		Entries:   10_000,
+		UsedBytes: 10_000 * sizeOfEntry[int64, testValue](),
+	}
+	assert.Equal(t, expectedStats, cache.GetStats())
+}
+
+func TestCache_Replace(t *testing.T) {
+	removed := []int64{}
+	cache := New(Config[int64, int64]{
+		OnRemove: func(key, value int64) {
+			removed = append(removed, value)
+		},
+	})
+
+	cache.Set(1, 1)
+	cache.Set(1, 2)
+
+	value, ok := cache.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), value)
+	assert.Equal(t, 1, cache.Len())
+
+	// The replaced value was removed
+	assert.Equal(t, []int64{1}, removed)
+}
+
+func TestCache_Delete(t *testing.T) {
+	removed := []int64{}
+	cache := New(Config[int64, int64]{
+		OnRemove: func(key, value int64) {
+			removed = append(removed, key)
+		},
+	})
+
+	for i := range int64(100) {
+		cache.Set(i, i)
+	}
+
+	for i := int64(0); i < 100; i += 2 {
+		assert.True(t, cache.Delete(i))
+		assert.False(t, cache.Delete(i))
+	}
+	assert.Equal(t, 50, cache.Len())
+	assert.Equal(t, 50, len(removed))
+
+	for i := range int64(100) {
+		_, ok := cache.Get(i)
+		assert.Equal(t, i%2 == 1, ok)
+	}
+}
+
+// Show that the least recently used entries are evicted when MaxEntries is
+// exceeded
+func TestCache_EvictMaxEntries(t *testing.T) {
+	evicted := []int64{}
+	cache := New(Config[int64, int64]{
+		MaxEntries: 3,
+		OnRemove: func(key, value int64) {
+			evicted = append(evicted, key)
+		},
+	})
+
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	cache.Set(3, 3)
+
+	// Touch 1, making 2 the least recently used
+	_, ok := cache.Get(1)
+	assert.True(t, ok)
+
+	cache.Set(4, 4)
+	assert.Equal(t, []int64{2}, evicted)
+
+	cache.Set(5, 5)
+	assert.Equal(t, []int64{2, 3}, evicted)
+
+	for _, key := range []int64{1, 4, 5} {
+		_, ok := cache.Get(key)
+		assert.True(t, ok)
+	}
+	for _, key := range []int64{2, 3} {
+		_, ok := cache.Get(key)
+		assert.False(t, ok)
+	}
+
+	stats := cache.GetStats()
+	assert.Equal(t, 2, stats.Evictions)
+	assert.Equal(t, 3, stats.Entries)
+}
+
+// Show that the least recently used entries are evicted when MaxBytes is
+// exceeded
+func TestCache_EvictMaxBytes(t *testing.T) {
+	cache := New(Config[int64, int64]{
+		MaxBytes: 100,
+		Size: func(key, value int64) int {
+			return int(value)
+		},
+	})
+
+	cache.Set(1, 40)
+	cache.Set(2, 40)
+	assert.Equal(t, 80, cache.GetStats().UsedBytes)
+
+	// Pushes us over the limit, 1 is evicted
+	cache.Set(3, 40)
+	_, ok := cache.Get(1)
+	assert.False(t, ok)
+	assert.Equal(t, 80, cache.GetStats().UsedBytes)
+
+	// An entry which is too big on its own is evicted immediately
+	cache.Set(4, 101)
+	_, ok = cache.Get(4)
+	assert.False(t, ok)
+	assert.Equal(t, 80, cache.GetStats().UsedBytes)
+
+	// Replacing a value updates the used bytes
+	cache.Set(3, 10)
+	assert.Equal(t, 50, cache.GetStats().UsedBytes)
+}
+
+func TestCache_TTL(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	cache := New(Config[int64, int64]{
+		TTL: time.Minute,
+	})
+	cache.now = clock.Now
+
+	cache.Set(1, 1)
+	cache.SetWithTTL(2, 2, time.Hour)
+	cache.SetWithTTL(3, 3, 0)
+
+	clock.Advance(59 * time.Second)
+	for _, key := range []int64{1, 2, 3} {
+		_, ok := cache.Get(key)
+		assert.True(t, ok)
+	}
+
+	// Key 1 has expired
+	clock.Advance(time.Second)
+	_, ok := cache.Get(1)
+	assert.False(t, ok)
+
+	// Key 2 has expired
+	clock.Advance(time.Hour)
+	_, ok = cache.Get(2)
+	assert.False(t, ok)
+
+	// Key 3 never expires
+	_, ok = cache.Get(3)
+	assert.True(t, ok)
+
+	stats := cache.GetStats()
+	assert.Equal(t, 2, stats.Expirations)
+	assert.Equal(t, 2, stats.Misses)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+// Show that values can hold RefString payloads, which are freed when the
+// entry leaves the cache
+func TestCache_RefStringPayload(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	cache := New(Config[int64, offheap.RefString]{
+		MaxEntries: 10,
+		Store:      store,
+		OnRemove: func(key int64, value offheap.RefString) {
+			offheap.FreeString(store, value)
+		},
+	})
+
+	for i := range int64(100) {
+		cache.Set(i, offheap.AllocStringFromString(store, fmt.Sprintf("value-%04d", i)))
+	}
+
+	for i := int64(90); i < 100; i++ {
+		value, ok := cache.Get(i)
+		require.True(t, ok)
+		require.Equal(t, fmt.Sprintf("value-%04d", i), value.Value())
+	}
+
+	// Only the strings still in the cache are live
+	assert.Equal(t, 10, offheap.StatsForString(store, 10).Live)
+}
+
+// Show that setting a key to the value it already holds doesn't pass the value
+// to OnRemove, which would free a payload still held by the cache
+func TestCache_RefStringPayload_SetSameValue(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	removed := 0
+	cache := New(Config[int64, offheap.RefString]{
+		Store: store,
+		OnRemove: func(key int64, value offheap.RefString) {
+			removed++
+			offheap.FreeString(store, value)
+		},
+	})
+
+	value := offheap.AllocStringFromString(store, "value")
+	cache.Set(1, value)
+	cache.Set(1, value)
+	assert.Equal(t, 0, removed)
+
+	got, ok := cache.Get(1)
+	require.True(t, ok)
+	assert.Equal(t, "value", got.Value())
+
+	// Replacing the value with a different one does free the old value
+	cache.Set(1, offheap.AllocStringFromString(store, "other"))
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 1, offheap.StatsForString(store, 5).Live)
+}
+
+// Show that values are compared with ==, so setting a key to an equal value
+// whose padding bytes differ doesn't pass the value to OnRemove
+func TestCache_SetEqualValue_DifferentPadding(t *testing.T) {
+	type paddedValue struct {
+		a int64
+		b byte
+	}
+
+	removed := 0
+	cache := New(Config[int64, paddedValue]{
+		OnRemove: func(key int64, value paddedValue) {
+			removed++
+		},
+	})
+
+	value := paddedValue{a: 1, b: 2}
+	cache.Set(1, value)
+
+	// Write to the padding after b
+	padded := value
+	padding := unsafe.Slice((*byte)(unsafe.Pointer(&padded)), unsafe.Sizeof(padded))[unsafe.Offsetof(padded.b)+1:]
+	for i := range padding {
+		padding[i] = 0xFF
+	}
+	cache.Set(1, padded)
+	assert.Equal(t, 0, removed)
+
+	got, ok := cache.Get(1)
+	require.True(t, ok)
+	assert.Equal(t, value, got)
+}
+
+// Keys which are equal, but whose in-memory representation may differ, are
+// rejected
+func TestCache_NonCanonicalKey_Panic(t *testing.T) {
+	type paddedKey struct {
+		a int64
+		b byte
+	}
+
+	assert.Panics(t, func() {
+		New(Config[paddedKey, int64]{})
+	})
+	assert.Panics(t, func() {
+		New(Config[float64, int64]{})
+	})
+}
+
+// Perform a long sequence of random operations, comparing the cache with a
+// conventional map. With no limits configured nothing is ever evicted.
+func TestCache_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cache := New(Config[int64, int64]{})
+	expected := map[int64]int64{}
+
+	for range 100_000 {
+		key := r.Int63n(10_000)
+		switch r.Intn(3) {
+		case 0:
+			_, inMap := expected[key]
+			delete(expected, key)
+			require.Equal(t, inMap, cache.Delete(key))
+		case 1:
+			value := r.Int63()
+			expected[key] = value
+			cache.Set(key, value)
+		case 2:
+			expectedValue, inMap := expected[key]
+			value, ok := cache.Get(key)
+			require.Equal(t, inMap, ok)
+			require.Equal(t, expectedValue, value)
+		}
+		require.Equal(t, len(expected), cache.Len())
+	}
+}
+
+// Show that freeing the cache releases all of its allocations
+func TestCache_Free(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	cache := New(Config[int64, int64]{Store: store})
+	for i := range int64(1000) {
+		cache.Set(i, i)
+	}
+
+	cache.Free()
+
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+func TestCache_Pointers_Panic(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Config[int64, *int]{})
+	})
+}
+
+// Assert that getting an entry from the cache does not allocate
+func TestCache_Get_NoAllocations(t *testing.T) {
+	cache := New(Config[int64, int64]{})
+	for i := range int64(1000) {
+		cache.Set(i, i)
+	}
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		for i := range int64(1000) {
+			cache.Get(i)
+		}
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package cache
+
+import (
+	"time"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+type Config[K comparable, V comparable] struct {
+	// Defines the maximum number of entries in the cache. When this limit
+	// is exceeded the least recently used entries are evicted.
+	//
+	// <= 0 indicates no limit on the number of entries.
+	MaxEntries int
+
+	// Defines the maximum total size, as measured by Size, of all entries
+	// in the cache. When this limit is exceeded the least recently used
+	// entries are evicted.
+	//
+	// <= 0 indicates no limit on total bytes, this may risk memory
+	// exhaustion.
+	MaxBytes int
+
+	// Defines the default time-to-live for entries added via Set. Expired
+	// entries are never returned by Get.
+	//
+	// <= 0 indicates that entries don't expire.
+	TTL time.Duration
+
+	// Returns the size, in bytes, of an entry. This is used to enforce
+	// MaxBytes. If V contains References to other allocations, such as a
+	// RefString payload, their size can be included here.
+	//
+	// If nil then the size of each entry is the size of the cache's
+	// internal entry node.
+	Size func(key K, value V) int
+
+	// Called whenever a value leaves the cache, whether through eviction,
+	// expiry, Delete or being replaced by Set. If V contains References to
+	// other allocations, such as a RefString payload, they can be freed
+	// here.
+	//
+	// Setting a key to a value equal, using ==, to its current value
+	// doesn't call OnRemove, because the value hasn't left the cache. So
+	// setting a key to the RefString it already holds won't free it.
+	//
+	// This function is called while the cache's lock is held, it must not
+	// call any methods on the cache.
+	OnRemove func(key K, value V)
+
+	// Defines the offheap store to use for allocating cache entries.
+	//
+	// If nil then a new store will be created internally. Only needed if
+	// you want to share a single offheap store across multiple caches or
+	// with the payloads stored in the cache.
+	Store *offheap.Store
+}
+
+func (c *Config[K, V]) getMaxEntries() int {
+	return c.MaxEntries
+}
+
+func (c *Config[K, V]) getMaxBytes() int {
+	return c.MaxBytes
+}
+
+func (c *Config[K, V]) getTTL() time.Duration {
+	return c.TTL
+}
+
+func (c *Config[K, V]) getSize() func(key K, value V) int {
+	if c.Size == nil {
+		size := sizeOfEntry[K, V]()
+		c.Size = func(_ K, _ V) int {
+			return size
+		}
+	}
+	return c.Size
+}
+
+func (c *Config[K, V]) getOnRemove() func(key K, value V) {
+	if c.OnRemove == nil {
+		c.OnRemove = func(_ K, _ V) {}
+	}
+	return c.OnRemove
+}
+
+func (c *Config[K, V]) getStore() *offheap.Store {
+	if c.Store == nil {
+		c.Store = offheap.New()
+	}
+	return c.Store
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package cache
+
+// The statistics capturing the runtime behaviour of the cache.
+//
+// Hits indicates the number of calls to Get which found a live entry.
+//
+// Misses indicates the number of calls to Get which did not find a live
+// entry. This includes entries which were found but had expired.
+//
+// Evictions indicates the number of entries removed to keep the cache within
+// its MaxEntries or MaxBytes limits.
+//
+// Expirations indicates the number of entries removed because their
+// time-to-live had passed.
+//
+// Entries indicates the number of entries currently in the cache.
+//
+// UsedBytes indicates the total size of the entries currently in the cache.
+type Stats struct {
+	Hits        int
+	Misses      int
+	Evictions   int
+	Expirations int
+	Entries     int
+	UsedBytes   int
+}
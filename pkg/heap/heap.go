@@ -0,0 +1,172 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The heap package provides binary min-heaps whose elements are stored in an
+// offheap.Store. This allows priority queues, such as those used by timers and
+// schedulers, to hold millions of elements without impacting garbage
+// collection.
+//
+// The ordering of elements is defined by a user supplied compare function, in
+// the style of cmp.Compare. The element for which compare reports the smallest
+// value is at the top of the heap.
+//
+// Heap stores elements directly, and elements are addressed by their current
+// position in the heap. IndexedHeap additionally tracks the position of each
+// element, allowing an element's priority to be updated, or the element
+// removed, using a stable Handle.
+//
+// Neither type is safe for concurrent use.
+package heap
+
+import (
+	"fmt"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A binary min-heap of T, ordered by compare.
+//
+// T may not contain pointers. Creating a Heap with an element type which
+// contains pointers will panic.
+type Heap[T any] struct {
+	store   *offheap.Store
+	compare func(a, b T) int
+	items   offheap.RefSlice[T]
+}
+
+// Creates a new empty Heap whose elements will be stored in store. compare
+// must return a negative number when a < b, a positive number when a > b and
+// zero when a == b.
+func New[T any](store *offheap.Store, compare func(a, b T) int) *Heap[T] {
+	return &Heap[T]{
+		store:   store,
+		compare: compare,
+		items:   offheap.AllocSlice[T](store, 0, 0),
+	}
+}
+
+// Adds value to the heap.
+func (h *Heap[T]) Push(value T) {
+	h.items = offheap.Append(h.store, h.items, value)
+	h.up(h.Len() - 1)
+}
+
+// Removes and returns the smallest element in the heap. If the heap is empty
+// the zero value of T and false are returned.
+func (h *Heap[T]) Pop() (T, bool) {
+	if h.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.Remove(0), true
+}
+
+// Returns, without removing it, the smallest element in the heap. If the heap
+// is empty the zero value of T and false are returned.
+func (h *Heap[T]) Peek() (T, bool) {
+	if h.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return h.items.Value()[0], true
+}
+
+// Removes and returns the element at idx. Panics if idx is out of range.
+func (h *Heap[T]) Remove(idx int) T {
+	h.checkIndex(idx)
+
+	items := h.items.Value()
+	last := len(items) - 1
+	value := items[idx]
+
+	h.swap(idx, last)
+	h.items = offheap.Truncate(h.store, h.items, last)
+	if idx != last {
+		h.Fix(idx)
+	}
+	return value
+}
+
+// Re-establishes the heap ordering after the element at idx has been
+// modified, via the slice returned by Values. Panics if idx is out of range.
+func (h *Heap[T]) Fix(idx int) {
+	h.checkIndex(idx)
+
+	if !h.down(idx) {
+		h.up(idx)
+	}
+}
+
+// Returns the elements of the heap in heap order, the smallest element is
+// always at index 0. The slice is only valid until the next call to Push, Pop
+// or Remove.
+//
+// Elements may be modified in place, but Fix must be called with the index of
+// each modified element before any other method is called.
+func (h *Heap[T]) Values() []T {
+	return h.items.Value()
+}
+
+// Returns the number of elements in the heap.
+func (h *Heap[T]) Len() int {
+	return len(h.items.Value())
+}
+
+// Frees the memory used to store the heap's elements. After this call returns
+// the heap must never be used again.
+func (h *Heap[T]) Free() {
+	offheap.FreeSlice(h.store, h.items)
+	h.items = offheap.RefSlice[T]{}
+}
+
+func (h *Heap[T]) checkIndex(idx int) {
+	if idx < 0 || idx >= h.Len() {
+		panic(fmt.Errorf("index %d out of range for heap of length %d", idx, h.Len()))
+	}
+}
+
+func (h *Heap[T]) less(i, j int) bool {
+	items := h.items.Value()
+	return h.compare(items[i], items[j]) < 0
+}
+
+func (h *Heap[T]) swap(i, j int) {
+	items := h.items.Value()
+	items[i], items[j] = items[j], items[i]
+}
+
+// Moves the element at idx towards the top of the heap until it is no smaller
+// than its parent.
+func (h *Heap[T]) up(idx int) {
+	for idx > 0 {
+		parent := (idx - 1) / 2
+		if !h.less(idx, parent) {
+			return
+		}
+		h.swap(idx, parent)
+		idx = parent
+	}
+}
+
+// Moves the element at idx towards the bottom of the heap until it is no
+// larger than its children. Returns true if the element was moved.
+func (h *Heap[T]) down(idx int) bool {
+	start := idx
+	length := h.Len()
+	for {
+		smallest := 2*idx + 1
+		if smallest >= length {
+			break
+		}
+		if right := smallest + 1; right < length && h.less(right, smallest) {
+			smallest = right
+		}
+		if !h.less(smallest, idx) {
+			break
+		}
+		h.swap(idx, smallest)
+		idx = smallest
+	}
+	return idx > start
+}
@@ -0,0 +1,335 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package heap
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timer struct {
+	deadline int64
+	id       int64
+}
+
+func compareTimers(a, b timer) int {
+	return cmp.Compare(a.deadline, b.deadline)
+}
+
+// Show that an empty heap behaves sensibly
+func TestHeap_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := New(store, cmp.Compare[int])
+
+	assert.Equal(t, 0, h.Len())
+
+	_, ok := h.Pop()
+	assert.False(t, ok)
+
+	_, ok = h.Peek()
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { h.Remove(0) })
+	assert.Panics(t, func() { h.Fix(0) })
+}
+
+func TestHeap_Pointers_Panic(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() {
+		New(store, func(a, b *int) int { return 0 })
+	})
+}
+
+// Push random values and show that they are popped in sorted order
+func TestHeap_PushPop(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := New(store, cmp.Compare[int64])
+
+	r := rand.New(rand.NewSource(1))
+	expected := []int64{}
+	for range 10_000 {
+		value := r.Int63n(1000)
+		expected = append(expected, value)
+		h.Push(value)
+	}
+	assert.Equal(t, 10_000, h.Len())
+	slices.Sort(expected)
+
+	for _, expectedValue := range expected {
+		peeked, ok := h.Peek()
+		require.True(t, ok)
+		value, ok := h.Pop()
+		require.True(t, ok)
+		require.Equal(t, expectedValue, value)
+		require.Equal(t, peeked, value)
+	}
+	assert.Equal(t, 0, h.Len())
+}
+
+// Modify elements in place, using Fix to re-establish the ordering
+func TestHeap_Fix(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := New(store, compareTimers)
+
+	for i := range int64(100) {
+		h.Push(timer{deadline: i, id: i})
+	}
+
+	// Find timer 50 and move its deadline to the front
+	idx := slices.IndexFunc(h.Values(), func(tm timer) bool { return tm.id == 50 })
+	h.Values()[idx].deadline = -1
+	h.Fix(idx)
+
+	value, ok := h.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, timer{deadline: -1, id: 50}, value)
+
+	// Find timer 0 and move its deadline to the back
+	idx = slices.IndexFunc(h.Values(), func(tm timer) bool { return tm.id == 0 })
+	h.Values()[idx].deadline = 1000
+	h.Fix(idx)
+
+	ids := []int64{}
+	for h.Len() > 0 {
+		value, _ := h.Pop()
+		ids = append(ids, value.id)
+	}
+	assert.Equal(t, int64(1), ids[0])
+	assert.Equal(t, int64(0), ids[len(ids)-1])
+	assert.Equal(t, 99, len(ids))
+}
+
+// Remove random elements by index, and show the remaining elements are still
+// popped in sorted order
+func TestHeap_Remove(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := New(store, cmp.Compare[int])
+
+	r := rand.New(rand.NewSource(1))
+	for _, value := range r.Perm(1000) {
+		h.Push(value)
+	}
+
+	removed := map[int]bool{}
+	for range 500 {
+		value := h.Remove(r.Intn(h.Len()))
+		removed[value] = true
+	}
+	assert.Equal(t, 500, h.Len())
+
+	prev := -1
+	for h.Len() > 0 {
+		value, _ := h.Pop()
+		require.Less(t, prev, value)
+		require.False(t, removed[value])
+		prev = value
+	}
+}
+
+// Show that freeing a heap releases its allocation
+func TestHeap_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := New(store, cmp.Compare[int])
+	for i := range 1000 {
+		h.Push(i)
+	}
+
+	h.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Show that an empty indexed heap behaves sensibly
+func TestIndexedHeap_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := NewIndexed(store, cmp.Compare[int])
+
+	assert.Equal(t, 0, h.Len())
+
+	_, _, ok := h.Pop()
+	assert.False(t, ok)
+
+	_, _, ok = h.Peek()
+	assert.False(t, ok)
+
+	_, ok = h.Get(0)
+	assert.False(t, ok)
+
+	_, ok = h.Remove(0)
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { h.Update(0, 1) })
+}
+
+// Push random values and show that they are popped in sorted order, with the
+// handles they were pushed with
+func TestIndexedHeap_PushPop(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := NewIndexed(store, cmp.Compare[int])
+
+	r := rand.New(rand.NewSource(1))
+	handles := map[Handle]int{}
+	for _, value := range r.Perm(10_000) {
+		handle := h.Push(value)
+		handles[handle] = value
+	}
+	assert.Equal(t, 10_000, h.Len())
+
+	for expected := range 10_000 {
+		peekHandle, peeked, ok := h.Peek()
+		require.True(t, ok)
+		handle, value, ok := h.Pop()
+		require.True(t, ok)
+		require.Equal(t, expected, value)
+		require.Equal(t, handles[handle], value)
+		require.Equal(t, peekHandle, handle)
+		require.Equal(t, peeked, value)
+		require.False(t, h.Contains(handle))
+	}
+}
+
+// Demonstrate decrease-key and increase-key using handles
+func TestIndexedHeap_Update(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := NewIndexed(store, compareTimers)
+
+	handles := []Handle{}
+	for i := range int64(100) {
+		handles = append(handles, h.Push(timer{deadline: i, id: i}))
+	}
+
+	// Decrease the key of timer 50
+	h.Update(handles[50], timer{deadline: -1, id: 50})
+	value, ok := h.Get(handles[50])
+	assert.True(t, ok)
+	assert.Equal(t, timer{deadline: -1, id: 50}, value)
+
+	// Increase the key of timer 0
+	h.Update(handles[0], timer{deadline: 1000, id: 0})
+
+	handle, value, ok := h.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, handles[50], handle)
+	assert.Equal(t, int64(50), value.id)
+
+	ids := []int64{}
+	for h.Len() > 0 {
+		_, value, _ := h.Pop()
+		ids = append(ids, value.id)
+	}
+	assert.Equal(t, int64(1), ids[0])
+	assert.Equal(t, int64(0), ids[len(ids)-1])
+
+	// Updating a removed handle panics
+	assert.Panics(t, func() { h.Update(handles[0], timer{}) })
+}
+
+// Perform a long sequence of random operations, comparing the heap with a
+// conventional map from handle to value
+func TestIndexedHeap_Random(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := NewIndexed(store, cmp.Compare[int64])
+	r := rand.New(rand.NewSource(1))
+	expected := map[Handle]int64{}
+	live := []Handle{}
+
+	for range 50_000 {
+		switch op := r.Intn(4); {
+		case op == 0 || len(live) == 0:
+			value := r.Int63n(10_000)
+			handle := h.Push(value)
+			require.NotContains(t, expected, handle)
+			expected[handle] = value
+			live = append(live, handle)
+		case op == 1:
+			idx := r.Intn(len(live))
+			handle := live[idx]
+			value := r.Int63n(10_000)
+			h.Update(handle, value)
+			expected[handle] = value
+		case op == 2:
+			idx := r.Intn(len(live))
+			handle := live[idx]
+			value, ok := h.Remove(handle)
+			require.True(t, ok)
+			require.Equal(t, expected[handle], value)
+			delete(expected, handle)
+			live = slices.Delete(live, idx, idx+1)
+		case op == 3:
+			handle, value, ok := h.Pop()
+			require.True(t, ok)
+			require.Equal(t, expected[handle], value)
+			for _, other := range expected {
+				require.LessOrEqual(t, value, other)
+			}
+			delete(expected, handle)
+			live = slices.DeleteFunc(live, func(h Handle) bool { return h == handle })
+		}
+		require.Equal(t, len(expected), h.Len())
+	}
+
+	for handle, expectedValue := range expected {
+		value, ok := h.Get(handle)
+		require.True(t, ok)
+		require.Equal(t, expectedValue, value)
+	}
+}
+
+// Show that freeing an indexed heap releases its allocations
+func TestIndexedHeap_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	h := NewIndexed(store, cmp.Compare[int])
+	for i := range 1000 {
+		h.Push(i)
+	}
+	for range 500 {
+		h.Pop()
+	}
+
+	h.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
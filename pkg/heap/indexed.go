@@ -0,0 +1,234 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package heap
+
+import (
+	"fmt"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// Identifies an element in an IndexedHeap. A Handle remains valid until its
+// element is removed from the heap, after which it may be reused for a newly
+// pushed element.
+type Handle int
+
+// Marks a handle which is not currently in use
+const freePosition = -1
+
+type indexedItem[T any] struct {
+	value  T
+	handle Handle
+}
+
+// A binary min-heap of T, ordered by compare, which tracks the position of
+// every element. Each pushed element is given a Handle, which can be used to
+// get, update or remove the element regardless of where it has moved to in
+// the heap. This supports the decrease-key operation needed by algorithms
+// such as Dijkstra's shortest path, and cancellable timers.
+//
+// T may not contain pointers. Creating an IndexedHeap with an element type
+// which contains pointers will panic.
+type IndexedHeap[T any] struct {
+	store   *offheap.Store
+	compare func(a, b T) int
+	// The elements of the heap, in heap order
+	items offheap.RefSlice[indexedItem[T]]
+	// Maps each handle to its element's index in items
+	positions offheap.RefSlice[int]
+	// Handles which have been removed and can be reused
+	freeHandles offheap.RefSlice[Handle]
+}
+
+// Creates a new empty IndexedHeap whose elements will be stored in store.
+// compare must return a negative number when a < b, a positive number when a
+// > b and zero when a == b.
+func NewIndexed[T any](store *offheap.Store, compare func(a, b T) int) *IndexedHeap[T] {
+	return &IndexedHeap[T]{
+		store:       store,
+		compare:     compare,
+		items:       offheap.AllocSlice[indexedItem[T]](store, 0, 0),
+		positions:   offheap.AllocSlice[int](store, 0, 0),
+		freeHandles: offheap.AllocSlice[Handle](store, 0, 0),
+	}
+}
+
+// Adds value to the heap, returning the Handle which identifies it.
+func (h *IndexedHeap[T]) Push(value T) Handle {
+	handle := h.allocHandle()
+	idx := h.Len()
+
+	h.items = offheap.Append(h.store, h.items, indexedItem[T]{value: value, handle: handle})
+	h.positions.Value()[handle] = idx
+	h.up(idx)
+
+	return handle
+}
+
+// Removes and returns the smallest element in the heap, along with the Handle
+// which identified it. If the heap is empty the zero value of T and false are
+// returned.
+func (h *IndexedHeap[T]) Pop() (Handle, T, bool) {
+	if h.Len() == 0 {
+		var zero T
+		return freePosition, zero, false
+	}
+	handle := h.items.Value()[0].handle
+	return handle, h.removeAt(0), true
+}
+
+// Returns, without removing it, the smallest element in the heap, along with
+// the Handle which identifies it. If the heap is empty the zero value of T and
+// false are returned.
+func (h *IndexedHeap[T]) Peek() (Handle, T, bool) {
+	if h.Len() == 0 {
+		var zero T
+		return freePosition, zero, false
+	}
+	item := h.items.Value()[0]
+	return item.handle, item.value, true
+}
+
+// Returns the element identified by handle, and true. If handle does not
+// identify an element in the heap the zero value of T and false are returned.
+func (h *IndexedHeap[T]) Get(handle Handle) (T, bool) {
+	if !h.Contains(handle) {
+		var zero T
+		return zero, false
+	}
+	return h.items.Value()[h.positions.Value()[handle]].value, true
+}
+
+// Replaces the element identified by handle with value, and moves it to its
+// new position in the heap. value may be smaller (decrease-key) or larger
+// than the element it replaces. Panics if handle does not identify an element
+// in the heap.
+func (h *IndexedHeap[T]) Update(handle Handle, value T) {
+	if !h.Contains(handle) {
+		panic(fmt.Errorf("cannot update handle %d which is not in the heap", handle))
+	}
+
+	idx := h.positions.Value()[handle]
+	h.items.Value()[idx].value = value
+	h.fix(idx)
+}
+
+// Removes the element identified by handle, and returns it along with true.
+// If handle does not identify an element in the heap the zero value of T and
+// false are returned.
+func (h *IndexedHeap[T]) Remove(handle Handle) (T, bool) {
+	if !h.Contains(handle) {
+		var zero T
+		return zero, false
+	}
+	return h.removeAt(h.positions.Value()[handle]), true
+}
+
+// Returns true if handle identifies an element in the heap.
+func (h *IndexedHeap[T]) Contains(handle Handle) bool {
+	positions := h.positions.Value()
+	return handle >= 0 && int(handle) < len(positions) && positions[handle] != freePosition
+}
+
+// Returns the number of elements in the heap.
+func (h *IndexedHeap[T]) Len() int {
+	return len(h.items.Value())
+}
+
+// Frees the memory used to store the heap's elements. After this call returns
+// the heap must never be used again.
+func (h *IndexedHeap[T]) Free() {
+	offheap.FreeSlice(h.store, h.items)
+	offheap.FreeSlice(h.store, h.positions)
+	offheap.FreeSlice(h.store, h.freeHandles)
+	h.items = offheap.RefSlice[indexedItem[T]]{}
+	h.positions = offheap.RefSlice[int]{}
+	h.freeHandles = offheap.RefSlice[Handle]{}
+}
+
+// Returns a free handle, reusing a removed handle if one is available.
+func (h *IndexedHeap[T]) allocHandle() Handle {
+	freeHandles := h.freeHandles.Value()
+	if len(freeHandles) > 0 {
+		handle := freeHandles[len(freeHandles)-1]
+		h.freeHandles = offheap.Truncate(h.store, h.freeHandles, len(freeHandles)-1)
+		return handle
+	}
+
+	handle := Handle(len(h.positions.Value()))
+	h.positions = offheap.Append(h.store, h.positions, freePosition)
+	return handle
+}
+
+func (h *IndexedHeap[T]) removeAt(idx int) T {
+	items := h.items.Value()
+	last := len(items) - 1
+	item := items[idx]
+
+	h.swap(idx, last)
+	h.items = offheap.Truncate(h.store, h.items, last)
+	if idx != last {
+		h.fix(idx)
+	}
+
+	h.positions.Value()[item.handle] = freePosition
+	h.freeHandles = offheap.Append(h.store, h.freeHandles, item.handle)
+	return item.value
+}
+
+func (h *IndexedHeap[T]) fix(idx int) {
+	if !h.down(idx) {
+		h.up(idx)
+	}
+}
+
+func (h *IndexedHeap[T]) less(i, j int) bool {
+	items := h.items.Value()
+	return h.compare(items[i].value, items[j].value) < 0
+}
+
+// Swaps the elements at i and j, and updates their positions.
+func (h *IndexedHeap[T]) swap(i, j int) {
+	items := h.items.Value()
+	positions := h.positions.Value()
+	items[i], items[j] = items[j], items[i]
+	positions[items[i].handle] = i
+	positions[items[j].handle] = j
+}
+
+// Moves the element at idx towards the top of the heap until it is no smaller
+// than its parent.
+func (h *IndexedHeap[T]) up(idx int) {
+	for idx > 0 {
+		parent := (idx - 1) / 2
+		if !h.less(idx, parent) {
+			return
+		}
+		h.swap(idx, parent)
+		idx = parent
+	}
+}
+
+// Moves the element at idx towards the bottom of the heap until it is no
+// larger than its children. Returns true if the element was moved.
+func (h *IndexedHeap[T]) down(idx int) bool {
+	start := idx
+	length := h.Len()
+	for {
+		smallest := 2*idx + 1
+		if smallest >= length {
+			break
+		}
+		if right := smallest + 1; right < length && h.less(right, smallest) {
+			smallest = right
+		}
+		if !h.less(smallest, idx) {
+			break
+		}
+		h.swap(idx, smallest)
+		idx = smallest
+	}
+	return idx > start
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"strconv"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+type boolInterner struct {
+	interner internbase.InternerWithUint64Id[boolConverter]
+}
+
+func NewBoolInterner(config internbase.Config) Interner[bool] {
+	return &boolInterner{
+		interner: internbase.NewInternerWithUint64Id[boolConverter](config),
+	}
+}
+
+func (i *boolInterner) Get(value bool) string {
+	return i.interner.Get(newBoolConverter(value))
+}
+
+func (i *boolInterner) GetBytes(value bool) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *boolInterner) AppendTo(dst []byte, value bool) []byte {
+	return i.interner.AppendTo(dst, newBoolConverter(value))
+}
+
+func (i *boolInterner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *boolInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *boolInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithUint64Id = boolConverter{}
+
+// A converter for bool values. The identity is 1 for true and 0 for false.
+type boolConverter struct {
+	value bool
+}
+
+func newBoolConverter(value bool) boolConverter {
+	return boolConverter{
+		value: value,
+	}
+}
+
+func (c boolConverter) Identity() uint64 {
+	if c.value {
+		return 1
+	}
+	return 0
+}
+
+func (c boolConverter) String() string {
+	return strconv.FormatBool(c.value)
+}
+
+func (c boolConverter) AppendString(dst []byte) []byte {
+	return strconv.AppendBool(dst, c.value)
+}
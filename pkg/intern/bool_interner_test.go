@@ -0,0 +1,66 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"testing"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoolInterner_Interned(t *testing.T) {
+	for val, internedVal := range map[bool]string{true: "true", false: "false"} {
+		interner := NewBoolInterner(internbase.Config{MaxLen: 64, MaxBytes: 1024})
+
+		DoTestGenericInterner_Interned(t, interner, val, internedVal)
+	}
+}
+
+// strconv.FormatBool returns constant strings, so unlike the other interners
+// we can't show that strings which aren't interned are newly allocated. Only
+// the stats show they weren't interned.
+func TestBoolInterner_NotInternedMaxLen(t *testing.T) {
+	interner := NewBoolInterner(internbase.Config{MaxLen: 4, MaxBytes: 1024})
+
+	assert.Equal(t, "false", interner.Get(false))
+	assert.Equal(t, "false", interner.Get(false))
+	assert.Equal(t, "true", interner.Get(true))
+	assert.Equal(t, internbase.Stats{MaxLenExceeded: 2, Interned: 1}, interner.GetStats().Total)
+}
+
+func TestBoolInterner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewBoolInterner(internbase.Config{MaxLen: 64, MaxBytes: 4})
+
+	assert.Equal(t, "true", interner.Get(true))
+	assert.Equal(t, "false", interner.Get(false))
+	assert.Equal(t, "false", interner.Get(false))
+	assert.Equal(t, "true", interner.Get(true))
+	assert.Equal(t, internbase.Stats{UsedBytesExceeded: 2, Interned: 1, Returned: 1}, interner.GetStats().Total)
+}
+
+// Assert that getting a string, where the value has already been interned,
+// does not allocate
+func TestBoolInterner_NoAllocations(t *testing.T) {
+	interner := NewBoolInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]bool, 10_000)
+	for i := range vals {
+		vals[i] = i%2 == 0
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestBoolInterner_AppendTo(t *testing.T) {
+	interner := NewBoolInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]bool, 10_000)
+	for i := range vals {
+		vals[i] = i%2 == 0
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -22,10 +22,26 @@ func (i *bytesInterner) Get(bytes []byte) string {
 	return i.interner.Get(newBytesConverter(bytes))
 }
 
+func (i *bytesInterner) GetBytes(bytes []byte) []byte {
+	return stringBytes(i.Get(bytes))
+}
+
+func (i *bytesInterner) AppendTo(dst []byte, bytes []byte) []byte {
+	return i.interner.AppendTo(dst, newBytesConverter(bytes))
+}
+
 func (i *bytesInterner) GetStats() internbase.StatsSummary {
 	return i.interner.GetStats()
 }
 
+func (i *bytesInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *bytesInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
 var _ internbase.ConverterWithBytesId = bytesConverter{}
 
 type bytesConverter struct {
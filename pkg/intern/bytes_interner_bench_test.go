@@ -75,3 +75,14 @@ func BenchmarkBytesInterner_AllInterned10K(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkBytesInterner_ParallelInterned10K(b *testing.B) {
+	interner := NewBytesInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	bytes := make([][]byte, 10_000)
+	for i := range bytes {
+		bytes[i] = []byte(strconv.Itoa(i))
+	}
+
+	benchmarkParallelInterned(b, interner, bytes)
+}
@@ -236,3 +236,14 @@ func TestBytesInterner_NoAllocations(t *testing.T) {
 
 	DoTestGenericInterner_NoAllocations(t, interner, byteVals)
 }
+
+func TestBytesInterner_AppendTo(t *testing.T) {
+	interner := NewBytesInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([][]byte, 10_000)
+	for i := range vals {
+		vals[i] = []byte(strconv.Itoa(i))
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -26,9 +26,19 @@
 // stored in an *offheap.Store. This means that there is no garbage collection
 // cost associated with keeping large numbers of interned strings.
 //
+// Interned strings can also be written directly into a caller's buffer
+//
+//	buf = someTypeInterner.AppendTo(buf, someTypeValue)
+//
+// AppendTo doesn't allocate, whether or not the value has already been
+// interned, which makes it suitable for building log lines or serialised
+// messages. GetBytes returns the interned string as a []byte which must not be
+// modified.
+//
 // This package contains a number of pre-made interners for the types int64,
-// float64, time.Time, []byte and string. But this package also includes the
-// tools to build custom interners for other types.
+// int32, uint64, bool, float64, time.Time, time.Duration, netip.Addr,
+// netip.AddrPort, 16 byte UUIDs, []byte and string. But this package also
+// includes the tools to build custom interners for other types.
 //
 // Because the interned strings are manually managed, and we don't have a
 // mechanism for knowing when to free interned string values, interned strings
@@ -38,6 +48,31 @@
 // number of bytes which can be used to intern strings. When this limit is
 // reached no new strings will be interned.
 //
+// Alternatively an interner can be configured with an eviction policy, such
+// as internbase.NewClockPolicy or internbase.NewTinyLFUPolicy. When the byte
+// limit is reached the policy chooses interned strings to evict, making room
+// for new strings. Because callers may still be using an evicted string it is
+// not freed immediately. The caller must periodically call AdvanceEpoch,
+// indicating that strings returned before the previous call to AdvanceEpoch
+// are no longer in use, after which evicted strings can be safely freed e.g.
+//
+//	interner := intern.NewStringInterner(internbase.Config{
+//		MaxBytes: 1 << 20,
+//		Eviction: internbase.NewClockPolicy,
+//	})
+//
+//	for batch := range batches {
+//		process(interner, batch)
+//		interner.AdvanceEpoch()
+//	}
+//
+// Strings which need to outlive this grace period must be copied.
+//
+// Interners are safe for concurrent use. Getting an already interned string
+// only takes a shared read lock, so concurrent lookups don't block each other.
+// With an eviction policy these lookups are buffered, and applied to the policy
+// in batches while holding each shard's write lock.
+//
 // It is expected that strings which are a good target for interning should
 // appear for interning frequently and there should be a finite number of these
 // common string values. In the case where this pattern holds true a well
@@ -46,7 +81,30 @@
 // have a stable set of common string values, then this interning approach will
 // be less effective.
 //
+// Whether an interner is effective can be monitored through GetStats. The
+// counters in its Stats accumulate until ResetStats is called, while
+// internbase.StatsWindow reports the hit rate over a rolling window, and
+// internbase.PublishExpvar publishes both through expvar e.g.
+//
+//	window := internbase.NewStatsWindow(interner, 60)
+//	internbase.PublishExpvar("currency_interner", window)
+//
+//	for range time.Tick(time.Second) {
+//		window.Sample()
+//	}
+//
+// The Usage in GetStats reports the entries and bytes in each shard, which
+// shows whether strings are spread evenly across the shards.
+//
 // It should be reasonably easy to create new interners using the types found
 // in the internbase package. Just following the implementation of the
 // interners found in this package.
+//
+// Where compact identifiers are more useful than deduplicated strings,
+// internbase.SymbolTable assigns a dense uint32 id to each distinct string,
+// and can look up the string for any id, e.g.
+//
+//	symbols := internbase.NewSymbolTable(internbase.Config{MaxBytes: 1 << 20})
+//	id, ok := symbols.Intern("GBP")
+//	str := symbols.Lookup(id)
 package intern
@@ -0,0 +1,180 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"time"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+type durationInterner struct {
+	interner internbase.InternerWithUint64Id[durationConverter]
+}
+
+// Interns time.Duration values formatted using time.Duration.String().
+func NewDurationInterner(config internbase.Config) Interner[time.Duration] {
+	return &durationInterner{
+		interner: internbase.NewInternerWithUint64Id[durationConverter](config),
+	}
+}
+
+func (i *durationInterner) Get(value time.Duration) string {
+	return i.interner.Get(newDurationConverter(value))
+}
+
+func (i *durationInterner) GetBytes(value time.Duration) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *durationInterner) AppendTo(dst []byte, value time.Duration) []byte {
+	return i.interner.AppendTo(dst, newDurationConverter(value))
+}
+
+func (i *durationInterner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *durationInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *durationInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithUint64Id = durationConverter{}
+
+// A converter for time.Duration values. Here the identity is the number of
+// nanoseconds in the duration.
+type durationConverter struct {
+	value time.Duration
+}
+
+func newDurationConverter(value time.Duration) durationConverter {
+	return durationConverter{
+		value: value,
+	}
+}
+
+func (c durationConverter) Identity() uint64 {
+	return uint64(c.value)
+}
+
+func (c durationConverter) String() string {
+	return c.value.String()
+}
+
+func (c durationConverter) AppendString(dst []byte) []byte {
+	return appendDuration(dst, c.value)
+}
+
+// Appends d to dst, formatted exactly as by time.Duration.String(). The time
+// package doesn't offer a way to append a duration without allocating, so
+// this follows its implementation.
+func appendDuration(dst []byte, d time.Duration) []byte {
+	// Largest duration is 2562047h47m16.854775807s, written right to left
+	buf := [32]byte{}
+	w := len(buf)
+
+	u := uint64(d)
+	neg := d < 0
+	if neg {
+		u = -u
+	}
+
+	if u < uint64(time.Second) {
+		// Special case: if duration is smaller than a second, use
+		// smaller units, like 1.2ms
+		prec := 0
+		w--
+		buf[w] = 's'
+		w--
+		switch {
+		case u == 0:
+			buf[w] = '0'
+			return append(dst, buf[w:]...)
+		case u < uint64(time.Microsecond):
+			buf[w] = 'n'
+		case u < uint64(time.Millisecond):
+			prec = 3
+			// U+00B5 'µ' micro sign == 0xC2 0xB5
+			w--
+			copy(buf[w:], "µ")
+		default:
+			prec = 6
+			buf[w] = 'm'
+		}
+		w, u = formatDurationFrac(buf[:w], u, prec)
+		w = formatDurationInt(buf[:w], u)
+	} else {
+		w--
+		buf[w] = 's'
+		w, u = formatDurationFrac(buf[:w], u, 9)
+		// u is now integer seconds
+		w = formatDurationInt(buf[:w], u%60)
+		u /= 60
+		// u is now integer minutes
+		if u > 0 {
+			w--
+			buf[w] = 'm'
+			w = formatDurationInt(buf[:w], u%60)
+			u /= 60
+			// u is now integer hours
+			if u > 0 {
+				w--
+				buf[w] = 'h'
+				w = formatDurationInt(buf[:w], u)
+			}
+		}
+	}
+
+	if neg {
+		w--
+		buf[w] = '-'
+	}
+
+	return append(dst, buf[w:]...)
+}
+
+// Formats the fraction of v/10**prec (e.g., ".12345") into the tail of buf,
+// omitting trailing zeros. It omits the decimal point too when the fraction
+// is 0. It returns the index where the output bytes begin and the value
+// v/10**prec.
+func formatDurationFrac(buf []byte, v uint64, prec int) (int, uint64) {
+	w := len(buf)
+	print := false
+	for range prec {
+		digit := v % 10
+		print = print || digit != 0
+		if print {
+			w--
+			buf[w] = byte(digit) + '0'
+		}
+		v /= 10
+	}
+	if print {
+		w--
+		buf[w] = '.'
+	}
+	return w, v
+}
+
+// Formats v into the tail of buf. It returns the index where the output
+// begins.
+func formatDurationInt(buf []byte, v uint64) int {
+	w := len(buf)
+	if v == 0 {
+		w--
+		buf[w] = '0'
+		return w
+	}
+	for v > 0 {
+		w--
+		buf[w] = byte(v%10) + '0'
+		v /= 10
+	}
+	return w
+}
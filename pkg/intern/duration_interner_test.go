@@ -0,0 +1,85 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationInterner_Interned(t *testing.T) {
+	interner := NewDurationInterner(internbase.Config{MaxLen: 64, MaxBytes: 1024})
+	val := 1500 * time.Millisecond
+	internedVal := val.String()
+
+	DoTestGenericInterner_Interned(t, interner, val, internedVal)
+}
+
+func TestDurationInterner_NotInternedMaxLen(t *testing.T) {
+	interner := NewDurationInterner(internbase.Config{MaxLen: 3, MaxBytes: 1024})
+	val := 1500 * time.Millisecond
+	internedVal := val.String()
+
+	DoTestGenericInterner_NotInternedMaxLen(t, interner, val, internedVal)
+}
+
+func TestDurationInterner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewDurationInterner(internbase.Config{MaxLen: 64, MaxBytes: 3})
+	val := 1500 * time.Millisecond
+	internedVal := val.String()
+
+	DoTestGenericInterner_NotInternedMaxBytes(t, interner, val, internedVal)
+}
+
+// Assert that getting a string, where the value has already been interned,
+// does not allocate
+func TestDurationInterner_NoAllocations(t *testing.T) {
+	interner := NewDurationInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]time.Duration, 10_000)
+	for i := range vals {
+		vals[i] = time.Duration(i) * time.Millisecond
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestDurationInterner_AppendTo(t *testing.T) {
+	interner := NewDurationInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]time.Duration, 10_000)
+	for i := range vals {
+		vals[i] = time.Duration(i-5_000) * 123_457 * time.Nanosecond
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
+
+// Demonstrate that appendDuration formats durations exactly as
+// time.Duration.String() does
+func TestAppendDuration(t *testing.T) {
+	vals := []time.Duration{
+		0, 1, -1, 999, 1000, 999_999, 1_000_000, time.Second, -time.Second,
+		90 * time.Minute, 1500 * time.Millisecond, math.MaxInt64, math.MinInt64,
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for range 10_000 {
+		// Spread values across every magnitude, both positive and negative
+		val := time.Duration(r.Int63() >> r.Intn(63))
+		if r.Intn(2) == 0 {
+			val = -val
+		}
+		vals = append(vals, val)
+	}
+
+	for _, val := range vals {
+		assert.Equal(t, val.String(), string(appendDuration(nil, val)))
+	}
+}
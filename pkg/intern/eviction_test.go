@@ -0,0 +1,181 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"strconv"
+	"testing"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+	"github.com/stretchr/testify/assert"
+)
+
+func liveAllocations(store *offheap.Store) int {
+	live := 0
+	for _, stats := range store.Stats() {
+		live += stats.Live
+	}
+	return live
+}
+
+// Demonstrate that when the byte limit is reached old strings are evicted to
+// make room for new ones, and that evicted strings are only freed after their
+// grace period has ended
+func TestEviction_Clock_EvictAndFree(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	// Room for 10 four byte strings
+	interner := NewInt64Interner(internbase.Config{MaxBytes: 40, Shards: 1, Eviction: internbase.NewClockPolicy, Store: store}, 10)
+	// The shard's index is also allocated in the store
+	index := liveAllocations(store)
+
+	first := []string{}
+	for i := range int64(10) {
+		first = append(first, interner.Get(1000+i))
+	}
+	assert.Equal(t, internbase.Stats{Interned: 10}, interner.GetStats().Total)
+	assert.Equal(t, 40, interner.GetStats().UsedBytes)
+	assert.Equal(t, []internbase.ShardUsage{{Entries: 10, Bytes: 40}}, interner.GetStats().Usage)
+
+	// Interning 10 new strings evicts the first 10
+	for i := range int64(10) {
+		assert.Equal(t, strconv.FormatInt(2000+i, 10), interner.Get(2000+i))
+	}
+	stats := interner.GetStats()
+	assert.Equal(t, internbase.Stats{Interned: 20, Evicted: 10}, stats.Total)
+	assert.Equal(t, 40, stats.UsedBytes)
+	assert.Equal(t, 40, stats.RetiredBytes)
+	// Evicted strings are no longer counted as the shard's entries
+	assert.Equal(t, []internbase.ShardUsage{{Entries: 10, Bytes: 40}}, stats.Usage)
+	assert.Equal(t, index+20, liveAllocations(store))
+
+	// The evicted strings are still valid during their grace period
+	interner.AdvanceEpoch()
+	for i, str := range first {
+		assert.Equal(t, strconv.Itoa(1000+i), str)
+	}
+	assert.Equal(t, 40, interner.GetStats().RetiredBytes)
+	assert.Equal(t, index+20, liveAllocations(store))
+
+	// After the grace period the evicted strings are freed
+	interner.AdvanceEpoch()
+	assert.Equal(t, 0, interner.GetStats().RetiredBytes)
+	assert.Equal(t, index+10, liveAllocations(store))
+}
+
+// Demonstrate that recently accessed strings are given a second chance, and
+// are not evicted
+func TestEviction_Clock_SecondChance(t *testing.T) {
+	interner := NewStringInterner(internbase.Config{MaxBytes: 3, Shards: 1, Eviction: internbase.NewClockPolicy})
+
+	a := interner.Get("a")
+	interner.Get("b")
+	interner.Get("c")
+
+	// Access "a" so it is not the next string evicted
+	interner.Get("a")
+
+	interner.Get("d")
+	assert.Equal(t, internbase.Stats{Interned: 4, Returned: 1, Evicted: 1}, interner.GetStats().Total)
+
+	// "a" is still interned
+	a2 := interner.Get("a")
+	assert.Same(t, unsafe.StringData(a), unsafe.StringData(a2))
+	assert.Equal(t, internbase.Stats{Interned: 4, Returned: 2, Evicted: 1}, interner.GetStats().Total)
+
+	// "b" was evicted, so it is interned again
+	interner.Get("b")
+	assert.Equal(t, internbase.Stats{Interned: 5, Returned: 2, Evicted: 2}, interner.GetStats().Total)
+}
+
+// Demonstrate that the TinyLFU policy will not evict frequently requested
+// strings to make room for infrequently requested strings
+func TestEviction_TinyLFU_Admission(t *testing.T) {
+	interner := NewStringInterner(internbase.Config{MaxBytes: 4, Shards: 1, Eviction: internbase.NewTinyLFUPolicy})
+
+	popular := []string{"a", "b", "c", "d"}
+	for range 5 {
+		for _, str := range popular {
+			interner.Get(str)
+		}
+	}
+	assert.Equal(t, internbase.Stats{Interned: 4, Returned: 16}, interner.GetStats().Total)
+
+	// A string requested once is not interned
+	interner.Get("e")
+	assert.Equal(t, internbase.Stats{Interned: 4, Returned: 16, UsedBytesExceeded: 1}, interner.GetStats().Total)
+
+	// Once it has been requested more often than the popular strings it
+	// is interned
+	for range 10 {
+		interner.Get("e")
+	}
+	stats := interner.GetStats().Total
+	assert.Equal(t, 5, stats.Interned)
+	assert.Equal(t, 1, stats.Evicted)
+}
+
+// Demonstrate that if AdvanceEpoch is never called eviction stops once the
+// retired strings reach the byte limit
+func TestEviction_RetiredBytesLimit(t *testing.T) {
+	interner := NewInt64Interner(internbase.Config{MaxBytes: 40, Shards: 1, Eviction: internbase.NewClockPolicy}, 10)
+
+	for i := range int64(30) {
+		assert.Equal(t, strconv.FormatInt(1000+i, 10), interner.Get(1000+i))
+	}
+
+	stats := interner.GetStats()
+	assert.Equal(t, internbase.Stats{Interned: 20, Evicted: 10, UsedBytesExceeded: 10}, stats.Total)
+	assert.Equal(t, 40, stats.UsedBytes)
+	assert.Equal(t, 40, stats.RetiredBytes)
+
+	// Once the retired strings are freed eviction resumes
+	interner.AdvanceEpoch()
+	interner.AdvanceEpoch()
+	interner.Get(5000)
+	stats = interner.GetStats()
+	assert.Equal(t, internbase.Stats{Interned: 21, Evicted: 11, UsedBytesExceeded: 10}, stats.Total)
+	assert.Equal(t, 4, stats.RetiredBytes)
+}
+
+// Demonstrate that strings which are longer than the byte limit are never
+// interned, and don't cause any evictions
+func TestEviction_StringTooLarge(t *testing.T) {
+	interner := NewStringInterner(internbase.Config{MaxBytes: 4, Shards: 1, Eviction: internbase.NewClockPolicy})
+
+	interner.Get("abcd")
+	interner.Get("abcde")
+	assert.Equal(t, internbase.Stats{Interned: 1, UsedBytesExceeded: 1}, interner.GetStats().Total)
+}
+
+// Demonstrate that without an eviction policy AdvanceEpoch has no effect
+func TestEviction_NoPolicy(t *testing.T) {
+	interner := NewStringInterner(internbase.Config{MaxBytes: 3, Shards: 1})
+
+	interner.Get("abc")
+	interner.AdvanceEpoch()
+	interner.AdvanceEpoch()
+	interner.Get("def")
+	assert.Equal(t, internbase.Stats{Interned: 1, UsedBytesExceeded: 1}, interner.GetStats().Total)
+}
+
+// Assert that getting interned strings with an eviction policy does not
+// allocate
+func TestEviction_NoAllocations(t *testing.T) {
+	for _, policy := range []func(*offheap.Store) internbase.EvictionPolicy{internbase.NewClockPolicy, internbase.NewTinyLFUPolicy} {
+		interner := NewInt64Interner(internbase.Config{Eviction: policy}, 10)
+
+		ints := make([]int64, 10_000)
+		for i := range ints {
+			ints[i] = int64(i)
+		}
+
+		DoTestGenericInterner_NoAllocations(t, interner, ints)
+	}
+}
@@ -31,10 +31,26 @@ func (i *float64Interner) Get(value float64) string {
 	return i.interner.Get(newFloat64Converter(value, i.fmt, i.prec, i.bitSize))
 }
 
+func (i *float64Interner) GetBytes(value float64) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *float64Interner) AppendTo(dst []byte, value float64) []byte {
+	return i.interner.AppendTo(dst, newFloat64Converter(value, i.fmt, i.prec, i.bitSize))
+}
+
 func (i *float64Interner) GetStats() internbase.StatsSummary {
 	return i.interner.GetStats()
 }
 
+func (i *float64Interner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *float64Interner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
 var _ internbase.ConverterWithUint64Id = float64Converter{}
 
 // A flexible converter for float64 values. Here the identity is generated by a
@@ -63,3 +79,7 @@ func (c float64Converter) Identity() uint64 {
 func (c float64Converter) String() string {
 	return strconv.FormatFloat(c.value, c.fmt, c.prec, c.bitSize)
 }
+
+func (c float64Converter) AppendString(dst []byte) []byte {
+	return strconv.AppendFloat(dst, c.value, c.fmt, c.prec, c.bitSize)
+}
@@ -144,3 +144,14 @@ func TestFloat64Interner_NoAllocations(t *testing.T) {
 	// allocate
 	assert.Equal(t, 0.0, avgAllocs)
 }
+
+func TestFloat64Interner_AppendTo(t *testing.T) {
+	interner := NewFloat64Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 'f', -1, 64)
+
+	vals := make([]float64, 10_000)
+	for i := range vals {
+		vals[i] = float64(i) / 7
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
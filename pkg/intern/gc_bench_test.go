@@ -0,0 +1,66 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+const gcBenchmarkStrings = 1_000_000
+
+// Measures the cost of a full garbage collection while a large number of
+// strings are interned. The interner's index should have very little impact
+// on the garbage collector.
+func BenchmarkInt64Interner_GC(b *testing.B) {
+	interner := NewInt64Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 10)
+	for i := range int64(gcBenchmarkStrings) {
+		interner.Get(i)
+	}
+
+	benchmarkGC(b)
+	runtime.KeepAlive(interner)
+}
+
+// Measures the cost of a full garbage collection while a large number of
+// strings are interned. The interner's index should have very little impact
+// on the garbage collector.
+func BenchmarkStringInterner_GC(b *testing.B) {
+	interner := NewStringInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+	for i := range gcBenchmarkStrings {
+		interner.Get(strconv.Itoa(i))
+	}
+
+	benchmarkGC(b)
+	runtime.KeepAlive(interner)
+}
+
+// Reports the time taken to run a full garbage collection, along with the
+// size of the Go heap and the total stop-the-world pause time.
+func benchmarkGC(b *testing.B) {
+	runtime.GC()
+
+	before := runtime.MemStats{}
+	runtime.ReadMemStats(&before)
+
+	b.ResetTimer()
+	start := time.Now()
+	for range b.N {
+		runtime.GC()
+	}
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	after := runtime.MemStats{}
+	runtime.ReadMemStats(&after)
+
+	b.ReportMetric(float64(elapsed.Nanoseconds())/float64(b.N), "ns/gc")
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "pause-ns/gc")
+	b.ReportMetric(float64(after.HeapAlloc), "heap-bytes")
+}
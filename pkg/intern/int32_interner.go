@@ -0,0 +1,74 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"strconv"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+type int32Interner struct {
+	interner internbase.InternerWithUint64Id[int32Converter]
+	base     int
+}
+
+func NewInt32Interner(config internbase.Config, base int) Interner[int32] {
+	return &int32Interner{
+		interner: internbase.NewInternerWithUint64Id[int32Converter](config),
+		base:     base,
+	}
+}
+
+func (i *int32Interner) Get(value int32) string {
+	return i.interner.Get(newInt32Converter(value, i.base))
+}
+
+func (i *int32Interner) GetBytes(value int32) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *int32Interner) AppendTo(dst []byte, value int32) []byte {
+	return i.interner.AppendTo(dst, newInt32Converter(value, i.base))
+}
+
+func (i *int32Interner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *int32Interner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *int32Interner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithUint64Id = int32Converter{}
+
+// A converter for int32 values. Here the identity is just the value itself.
+type int32Converter struct {
+	value int32
+	base  int
+}
+
+func newInt32Converter(value int32, base int) int32Converter {
+	return int32Converter{
+		value: value,
+		base:  base,
+	}
+}
+
+func (c int32Converter) Identity() uint64 {
+	return uint64(c.value)
+}
+
+func (c int32Converter) String() string {
+	return strconv.FormatInt(int64(c.value), c.base)
+}
+
+func (c int32Converter) AppendString(dst []byte) []byte {
+	return strconv.AppendInt(dst, int64(c.value), c.base)
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+func TestInt32Interner_Interned(t *testing.T) {
+	interner := NewInt32Interner(internbase.Config{MaxLen: 64, MaxBytes: 1024}, 10)
+	val := int32(-1234)
+	internedVal := strconv.FormatInt(int64(val), 10)
+
+	DoTestGenericInterner_Interned(t, interner, val, internedVal)
+}
+
+func TestInt32Interner_NotInternedMaxLen(t *testing.T) {
+	interner := NewInt32Interner(internbase.Config{MaxLen: 3, MaxBytes: 1024}, 10)
+	val := int32(-1234)
+	internedVal := strconv.FormatInt(int64(val), 10)
+
+	DoTestGenericInterner_NotInternedMaxLen(t, interner, val, internedVal)
+}
+
+func TestInt32Interner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewInt32Interner(internbase.Config{MaxLen: 64, MaxBytes: 3}, 10)
+	val := int32(-1234)
+	internedVal := strconv.FormatInt(int64(val), 10)
+
+	DoTestGenericInterner_NotInternedMaxBytes(t, interner, val, internedVal)
+}
+
+// Assert that getting a string, where the value has already been interned,
+// does not allocate
+func TestInt32Interner_NoAllocations(t *testing.T) {
+	interner := NewInt32Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 10)
+
+	vals := make([]int32, 10_000)
+	for i := range vals {
+		vals[i] = int32(i)
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestInt32Interner_AppendTo(t *testing.T) {
+	interner := NewInt32Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 16)
+
+	vals := make([]int32, 10_000)
+	for i := range vals {
+		vals[i] = int32(i) - 5_000
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -26,10 +26,26 @@ func (i *int64Interner) Get(value int64) string {
 	return i.interner.Get(newInt64Converter(value, i.base))
 }
 
+func (i *int64Interner) GetBytes(value int64) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *int64Interner) AppendTo(dst []byte, value int64) []byte {
+	return i.interner.AppendTo(dst, newInt64Converter(value, i.base))
+}
+
 func (i *int64Interner) GetStats() internbase.StatsSummary {
 	return i.interner.GetStats()
 }
 
+func (i *int64Interner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *int64Interner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
 var _ internbase.ConverterWithUint64Id = int64Converter{}
 
 // A converter for int64 values. Here the identity is just the value itself.
@@ -52,3 +68,7 @@ func (c int64Converter) Identity() uint64 {
 func (c int64Converter) String() string {
 	return strconv.FormatInt(c.value, c.base)
 }
+
+func (c int64Converter) AppendString(dst []byte) []byte {
+	return strconv.AppendInt(dst, c.value, c.base)
+}
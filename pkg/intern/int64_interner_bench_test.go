@@ -74,3 +74,14 @@ func BenchmarkInt64Interner_AllInterned10K(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkInt64Interner_ParallelInterned10K(b *testing.B) {
+	interner := NewInt64Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 10)
+
+	ints := make([]int64, 10_000)
+	for i := range ints {
+		ints[i] = int64(i)
+	}
+
+	benchmarkParallelInterned(b, interner, ints)
+}
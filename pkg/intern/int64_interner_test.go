@@ -132,3 +132,14 @@ func TestInt64Interner_NoAllocations(t *testing.T) {
 
 	DoTestGenericInterner_NoAllocations(t, interner, ints)
 }
+
+func TestInt64Interner_AppendTo(t *testing.T) {
+	interner := NewInt64Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 10)
+
+	vals := make([]int64, 10_000)
+	for i := range vals {
+		vals[i] = int64(i) - 5_000
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
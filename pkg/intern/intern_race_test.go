@@ -4,50 +4,105 @@
 
 package intern
 
-/*
 import (
+	"fmt"
 	"math/rand"
+	"runtime"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
 	"github.com/fmstephe/memorymanager/testpkg/testutil"
+	"github.com/stretchr/testify/assert"
 )
 
+const (
+	raceGoroutines = 16
+	raceIterations = 20_000
+)
+
+// Demonstrate that many goroutines can concurrently intern, and retrieve
+// interned, values and always get the correct string back. This test is most
+// useful when run with -race.
 func TestInterner_Race(t *testing.T) {
-	interner := New(-1, -1)
+	bytes, ints, floats := buildTestData(1000, 100)
+
+	t.Run("Unlimited", func(t *testing.T) {
+		config := internbase.Config{MaxLen: 0, MaxBytes: 0}
+		runRace(t, config, bytes, ints, floats)
+	})
 
-	bytes, ints, floats := buildTestData(100_000, 1000)
-	const goroutines = 100
-	const iterations = 100_000_000
+	t.Run("Clock Eviction", func(t *testing.T) {
+		config := internbase.Config{MaxLen: 0, MaxBytes: 10_000, Eviction: internbase.NewClockPolicy}
+		runRace(t, config, bytes, ints, floats)
+	})
+}
+
+func runRace(t *testing.T, config internbase.Config, bytes [][]byte, ints []int64, floats []float64) {
+	bytesInterner := NewBytesInterner(config)
+	intInterner := NewInt64Interner(config, 10)
+	floatInterner := NewFloat64Interner(config, 'f', -1, 64)
+
+	wg := sync.WaitGroup{}
+	for range raceGoroutines {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			internBytes(t, bytesInterner, bytes, raceIterations)
+		}()
+		go func() {
+			defer wg.Done()
+			internInts(t, intInterner, ints, raceIterations)
+		}()
+		go func() {
+			defer wg.Done()
+			internFloats(t, floatInterner, floats, raceIterations)
+		}()
+	}
+	wg.Wait()
 
-	for range goroutines {
-		go internStrings(interner, bytes, iterations)
-		go internInts(interner, ints, iterations)
-		go internFloats(interner, floats, iterations)
+	// Every call was either returned, interned or rejected
+	for _, stats := range []internbase.StatsSummary{bytesInterner.GetStats(), intInterner.GetStats(), floatInterner.GetStats()} {
+		total := stats.Total
+		assert.Equal(t, raceGoroutines*raceIterations, total.Returned+total.Interned+total.MaxLenExceeded+total.UsedBytesExceeded)
 	}
 }
 
-func internStrings(interner *StringInterner, bytes [][]byte, iterations int) {
+func internBytes(t *testing.T, interner Interner[[]byte], bytes [][]byte, iterations int) {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	for i := 0; i < iterations; i++ {
-		interner.GetFromBytes(bytes[r.Intn(len(bytes))])
+	for range iterations {
+		val := bytes[r.Intn(len(bytes))]
+		if str := interner.Get(val); str != string(val) {
+			assert.Equal(t, string(val), str)
+			return
+		}
 	}
 }
 
-func internInts(interner *StringInterner, ints []int64, iterations int) {
+func internInts(t *testing.T, interner Interner[int64], ints []int64, iterations int) {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	for i := 0; i < iterations; i++ {
-		interner.GetFromInt64(ints[r.Intn(len(ints))])
+	for range iterations {
+		val := ints[r.Intn(len(ints))]
+		if str := interner.Get(val); str != strconv.FormatInt(val, 10) {
+			assert.Equal(t, strconv.FormatInt(val, 10), str)
+			return
+		}
 	}
 }
 
-func internFloats(interner *StringInterner, ints []float64, iterations int) {
+func internFloats(t *testing.T, interner Interner[float64], floats []float64, iterations int) {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	for i := 0; i < iterations; i++ {
-		interner.GetFromFloat64(ints[r.Intn(len(ints))])
+	for range iterations {
+		val := floats[r.Intn(len(floats))]
+		if str := interner.Get(val); str != strconv.FormatFloat(val, 'f', -1, 64) {
+			assert.Equal(t, strconv.FormatFloat(val, 'f', -1, 64), str)
+			return
+		}
 	}
 }
 
@@ -72,4 +127,27 @@ func buildTestData(size, bytesLimit int) ([][]byte, []int64, []float64) {
 
 	return bytes, ints, floats
 }
-*/
+
+// Benchmark getting already interned values from many goroutines at once,
+// with increasing GOMAXPROCS. When the values are all interned lookups don't
+// block each other, so throughput should scale with GOMAXPROCS.
+func benchmarkParallelInterned[T any](b *testing.B, interner Interner[T], vals []T) {
+	for _, val := range vals {
+		interner.Get(val)
+	}
+
+	for _, procs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("GOMAXPROCS=%d", procs), func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(time.Now().UnixNano()))
+				for pb.Next() {
+					interner.Get(vals[r.Intn(len(vals))])
+				}
+			})
+		})
+	}
+}
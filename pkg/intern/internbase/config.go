@@ -19,7 +19,8 @@ type Config struct {
 	MaxLen int
 
 	// Defines the maximum total number of bytes which can be interned.
-	// Once this limit is reached no new strings will be interned.
+	// Once this limit is reached no new strings will be interned, unless
+	// an Eviction policy is configured.
 	//
 	// <= 0 indicates no limit on total bytes, this may risk memory
 	// exhaustion.
@@ -36,6 +37,19 @@ type Config struct {
 	// shards automatically.
 	Shards int
 
+	// Creates the EvictionPolicy used by each shard, which may allocate
+	// in the interner's store. When MaxBytes is reached the policy
+	// chooses interned strings to evict, making room for new strings.
+	// NewClockPolicy and NewTinyLFUPolicy can be used here.
+	//
+	// Evicted strings are not freed immediately, because they may still
+	// be in use by callers. They are freed once their grace period has
+	// ended, see AdvanceEpoch. Evicted strings waiting to be freed may use
+	// up to MaxBytes in addition to the interned strings.
+	//
+	// nil indicates that strings are never evicted.
+	Eviction func(store *offheap.Store) EvictionPolicy
+
 	// Defines the offheap store to use for allocating interned strings.
 	//
 	// If nil then a new store will be created internally. Only needed if
@@ -59,6 +73,13 @@ func (c *Config) getShards() int {
 	return nextPowerOfTwo(c.Shards)
 }
 
+func (c *Config) getEviction(store *offheap.Store) EvictionPolicy {
+	if c.Eviction == nil {
+		return nil
+	}
+	return c.Eviction(store)
+}
+
 func (c *Config) getStore() *offheap.Store {
 	if c.Store == nil {
 		c.Store = offheap.New()
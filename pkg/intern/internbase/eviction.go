@@ -0,0 +1,241 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// An EvictionPolicy chooses which interned strings to evict when the MaxBytes
+// limit is reached. Each shard of an interner has its own EvictionPolicy, and
+// the policy's methods are always called while the shard is write locked, so
+// implementations don't need to be safe for concurrent use.
+//
+// Requests for strings which are already interned are served while holding
+// the shard's read lock. These requests are buffered and passed to Access, in
+// order, before the policy is next used. Under heavy contention a small number
+// of these requests may never be passed to Access.
+//
+// Interned strings are identified by a uint64 key, which is the identity of
+// the converter for InternerWithUint64Id and the hash of the identity for
+// InternerWithBytesId.
+type EvictionPolicy interface {
+	// Called every time a string is requested, whether or not key is
+	// currently interned.
+	Access(key uint64)
+
+	// Called when key has been interned.
+	Add(key uint64)
+
+	// Returns an interned key to evict in order to make room for the
+	// candidate key. If no key should be evicted, because there are no
+	// interned keys or the candidate is not worth interning, then false
+	// is returned.
+	Victim(candidate uint64) (uint64, bool)
+
+	// Called when key has been evicted.
+	Remove(key uint64)
+}
+
+// A CLOCK, or second-chance, eviction policy. Interned keys are arranged in a
+// ring, and each key is marked as referenced when it is accessed. When a
+// victim is needed the clock hand sweeps the ring, evicting the first key
+// which hasn't been referenced since the hand last passed it.
+//
+// The ring, the index from key to ring slot and the list of free slots are all
+// allocated in an offheap.Store, so a policy tracking a very large number of
+// interned strings adds nothing to the cost of garbage collection.
+type clockPolicy struct {
+	store *offheap.Store
+	slots offheap.RefSlice[clockSlot]
+	index table[int]
+	free  offheap.RefSlice[int]
+	hand  int
+}
+
+type clockSlot struct {
+	key        uint64
+	used       bool
+	referenced bool
+}
+
+// Returns a new CLOCK EvictionPolicy, allocating in store. Suitable for use
+// as Config.Eviction.
+func NewClockPolicy(store *offheap.Store) EvictionPolicy {
+	return newClockPolicy(store)
+}
+
+func newClockPolicy(store *offheap.Store) *clockPolicy {
+	return &clockPolicy{
+		store: store,
+		slots: offheap.AllocSlice[clockSlot](store, 0, initialTableSize),
+		index: newTable[int](store),
+		free:  offheap.AllocSlice[int](store, 0, initialTableSize),
+	}
+}
+
+func (p *clockPolicy) Access(key uint64) {
+	if idx, ok := p.index.get(key); ok {
+		p.slots.Value()[idx].referenced = true
+	}
+}
+
+func (p *clockPolicy) Add(key uint64) {
+	slot := clockSlot{key: key, used: true}
+
+	if free := p.free.Value(); len(free) > 0 {
+		idx := free[len(free)-1]
+		p.free = offheap.Truncate(p.store, p.free, len(free)-1)
+		p.slots.Value()[idx] = slot
+		p.index.put(key, idx)
+		return
+	}
+
+	p.slots = offheap.Append(p.store, p.slots, slot)
+	p.index.put(key, len(p.slots.Value())-1)
+}
+
+func (p *clockPolicy) Victim(_ uint64) (uint64, bool) {
+	if p.index.len() == 0 {
+		return 0, false
+	}
+
+	slots := p.slots.Value()
+	for {
+		if p.hand >= len(slots) {
+			p.hand = 0
+		}
+		slot := &slots[p.hand]
+		p.hand++
+
+		if !slot.used {
+			continue
+		}
+		if slot.referenced {
+			// Give this key a second chance
+			slot.referenced = false
+			continue
+		}
+		return slot.key, true
+	}
+}
+
+func (p *clockPolicy) Remove(key uint64) {
+	idx, ok := p.index.get(key)
+	if !ok {
+		return
+	}
+
+	p.slots.Value()[idx] = clockSlot{}
+	p.free = offheap.Append(p.store, p.free, idx)
+	p.index.remove(key)
+}
+
+// The number of counters in each row of the TinyLFU frequency sketch
+const tinyLFUWidth = 1 << 12
+
+// The number of rows in the TinyLFU frequency sketch
+const tinyLFUDepth = 4
+
+// Counters saturate at this value
+const tinyLFUMaxCount = 15
+
+// A TinyLFU admission policy in front of a CLOCK eviction policy. The
+// frequency of every requested key is estimated using a small count-min
+// sketch. When the CLOCK policy chooses a victim, the candidate is only
+// interned if it has been requested more frequently than the victim.
+//
+// The counters are periodically halved so that the estimated frequencies
+// favour recent requests.
+type tinyLFUPolicy struct {
+	clock     *clockPolicy
+	counters  [tinyLFUDepth][tinyLFUWidth]uint8
+	additions int
+}
+
+// Returns a new TinyLFU EvictionPolicy, allocating in store. Suitable for use
+// as Config.Eviction.
+func NewTinyLFUPolicy(store *offheap.Store) EvictionPolicy {
+	return &tinyLFUPolicy{
+		clock: newClockPolicy(store),
+	}
+}
+
+func (p *tinyLFUPolicy) Access(key uint64) {
+	p.increment(key)
+	p.clock.Access(key)
+}
+
+func (p *tinyLFUPolicy) Add(key uint64) {
+	p.clock.Add(key)
+}
+
+func (p *tinyLFUPolicy) Victim(candidate uint64) (uint64, bool) {
+	victim, ok := p.clock.Victim(candidate)
+	if !ok {
+		return 0, false
+	}
+
+	if p.estimate(candidate) <= p.estimate(victim) {
+		// The candidate isn't requested often enough to replace
+		// the victim
+		return 0, false
+	}
+	return victim, true
+}
+
+func (p *tinyLFUPolicy) Remove(key uint64) {
+	p.clock.Remove(key)
+}
+
+func (p *tinyLFUPolicy) increment(key uint64) {
+	hash := mix64(key)
+	for row := range p.counters {
+		idx := tinyLFUIndex(hash, row)
+		if p.counters[row][idx] < tinyLFUMaxCount {
+			p.counters[row][idx]++
+		}
+	}
+
+	p.additions++
+	if p.additions >= 10*tinyLFUWidth {
+		p.age()
+	}
+}
+
+func (p *tinyLFUPolicy) estimate(key uint64) uint8 {
+	hash := mix64(key)
+	estimate := uint8(tinyLFUMaxCount)
+	for row := range p.counters {
+		estimate = min(estimate, p.counters[row][tinyLFUIndex(hash, row)])
+	}
+	return estimate
+}
+
+// Halves every counter, so that old requests count for less than new ones
+func (p *tinyLFUPolicy) age() {
+	for row := range p.counters {
+		for idx := range p.counters[row] {
+			p.counters[row][idx] /= 2
+		}
+	}
+	p.additions /= 2
+}
+
+func tinyLFUIndex(hash uint64, row int) int {
+	// Each row uses a different 16 bit slice of the hash
+	return int(hash>>(row*16)) & (tinyLFUWidth - 1)
+}
+
+// The splitmix64 finaliser. Keys, such as int64 identities, are often not
+// well distributed so we mix them before using them to index the sketch.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Demonstrate that the CLOCK policy evicts every unreferenced key, and gives
+// referenced keys a second chance, while reusing the slots of removed keys
+func TestClockPolicy_Victims(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	policy := newClockPolicy(store)
+	for key := range uint64(1_000) {
+		policy.Add(key)
+	}
+	// Keys divisible by 10 are referenced
+	for key := uint64(0); key < 1_000; key += 10 {
+		policy.Access(key)
+	}
+
+	// Every unreferenced key is evicted before any referenced key
+	for range 900 {
+		victim, ok := policy.Victim(0)
+		require.True(t, ok)
+		require.NotZero(t, victim%10)
+		policy.Remove(victim)
+	}
+
+	// Removed slots are reused, so the ring doesn't grow
+	capacity := cap(policy.slots.Value())
+	for key := uint64(1_000); key < 1_900; key++ {
+		policy.Add(key)
+	}
+	assert.Equal(t, capacity, cap(policy.slots.Value()))
+
+	// Every key is still tracked and evicted exactly once
+	evicted := map[uint64]bool{}
+	for range 1_000 {
+		victim, ok := policy.Victim(0)
+		require.True(t, ok)
+		require.False(t, evicted[victim])
+		evicted[victim] = true
+		policy.Remove(victim)
+	}
+	_, ok := policy.Victim(0)
+	assert.False(t, ok)
+}
+
+// Demonstrate that the CLOCK policy's ring and index are allocated in the store
+func TestClockPolicy_AllocatesInStore(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	policy := newClockPolicy(store)
+	for key := range uint64(10_000) {
+		policy.Add(key)
+	}
+
+	assert.Equal(t, 1, offheap.StatsForSlice[clockSlot](store, cap(policy.slots.Value())).Live)
+	assert.Equal(t, 1, offheap.StatsForSlice[tableSlot[int]](store, cap(policy.index.slots.Value())).Live)
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"expvar"
+)
+
+// The stats published by PublishExpvar.
+//
+// HitRate is the hit rate over the life of the interner, or since the last
+// call to ResetStats, while Window and WindowHitRate cover only the window.
+type ExpvarStats struct {
+	StatsSummary
+	HitRate       float64
+	Window        Stats
+	WindowHitRate float64
+	ShardSkew     float64
+}
+
+// Returns the stats published by PublishExpvar, for window's interner.
+func NewExpvarStats(window *StatsWindow) ExpvarStats {
+	summary := window.source.GetStats()
+	delta := window.Delta()
+
+	return ExpvarStats{
+		StatsSummary:  summary,
+		HitRate:       summary.Total.HitRate(),
+		Window:        delta,
+		WindowHitRate: delta.HitRate(),
+		ShardSkew:     summary.ShardSkew(),
+	}
+}
+
+// Publishes the stats of window's interner as an expvar variable called
+// name. The stats are read each time the variable is read, e.g. when
+// /debug/vars is served, as a JSON encoded ExpvarStats.
+//
+// The window is not sampled by reading the variable, the caller must still
+// call Sample periodically.
+//
+// Like expvar.Publish this panics if name is already published.
+func PublishExpvar(name string, window *StatsWindow) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return NewExpvarStats(window)
+	}))
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The method, common to all converters except ConverterWithBytesId, which
+// generates a converter's string.
+type stringGenerator interface {
+	String() string
+}
+
+// Calls a converter's AppendString method, if it has one. Converters may
+// optionally implement AppendString(dst []byte) []byte, appending the same
+// string returned by String() to dst. This allows AppendTo to intern values
+// without allocating.
+//
+// The method is found once, when an interner is constructed. Finding it by
+// converting every converter to an interface would allocate a copy of the
+// converter each time.
+type stringAppender[C stringGenerator] func(converter C, dst []byte) []byte
+
+// Returns the stringAppender for C, or nil if C has no AppendString method.
+func newStringAppender[C stringGenerator]() stringAppender[C] {
+	method, ok := reflect.TypeFor[C]().MethodByName("AppendString")
+	if !ok || !method.Func.IsValid() {
+		return nil
+	}
+	appender, ok := method.Func.Interface().(func(C, []byte) []byte)
+	if !ok {
+		// AppendString has the wrong signature
+		return nil
+	}
+	return appender
+}
+
+// Appends the string of converter to dst.
+func (a stringAppender[C]) appendString(dst []byte, converter C) []byte {
+	if a != nil {
+		return a(converter, dst)
+	}
+	return append(dst, converter.String()...)
+}
+
+// A shard for interners whose converters are identified by a fixed size
+// comparable identity, I, which is too large to be used directly as a key.
+// Identities are hashed, and the identity is stored alongside each interned
+// string so colliding identities can be told apart. The converter's String()
+// method is only called when the identity isn't already interned.
+type fixedIdShard[I comparable, C stringGenerator] struct {
+	controller *internController
+	store      *offheap.Store
+	appender   stringAppender[C]
+	//
+	lock     sync.RWMutex
+	interned probingMap[I]
+	evictor  shardEvictor
+	stats    Stats
+	usage    ShardUsage
+	// Count strings returned, and their hash collisions, while holding the
+	// read lock
+	sharedReturned      atomic.Int64
+	sharedHashCollision atomic.Int64
+}
+
+func newFixedIdShard[I comparable, C stringGenerator](controller *internController, store *offheap.Store, policy EvictionPolicy) fixedIdShard[I, C] {
+	return fixedIdShard[I, C]{
+		controller: controller,
+		store:      store,
+		appender:   newStringAppender[C](),
+		//
+		interned: newProbingMap[I](store),
+		evictor:  newShardEvictor(policy),
+	}
+}
+
+func (i *fixedIdShard[I, C]) get(hash uint64, identity I, converter C) string {
+	if interned, ok := i.getShared(hash, identity); ok {
+		return interned
+	}
+	return i.getExclusive(hash, identity, converter)
+}
+
+// Looks up an already interned string while holding only the read lock, so
+// that concurrent lookups don't block each other. Returns false if the string
+// isn't interned.
+func (i *fixedIdShard[I, C]) getShared(hash uint64, identity I) (string, bool) {
+	i.lock.RLock()
+
+	key, refString, found, collisions := i.find(hash, identity)
+	if !found {
+		i.lock.RUnlock()
+		return "", false
+	}
+	i.sharedHashCollision.Add(int64(collisions))
+	i.sharedReturned.Add(1)
+	full := i.evictor.accessShared(key)
+	str := refString.Value()
+
+	i.lock.RUnlock()
+
+	if full {
+		i.drainAccesses()
+	}
+	return str, true
+}
+
+// Applies the accesses recorded by getShared to the eviction policy.
+func (i *fixedIdShard[I, C]) drainAccesses() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.evictor.drain()
+}
+
+func (i *fixedIdShard[I, C]) getExclusive(hash uint64, identity I, converter C) string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	key, refString, found := i.lookup(hash, identity)
+	if found {
+		return refString.Value()
+	}
+
+	str := converter.String()
+	if refString, ok := i.intern(key, hash, identity, str); ok {
+		return refString.Value()
+	}
+	return str
+}
+
+func (i *fixedIdShard[I, C]) appendTo(dst []byte, hash uint64, identity I, converter C) []byte {
+	if interned, ok := i.getShared(hash, identity); ok {
+		return append(dst, interned...)
+	}
+	return i.appendExclusive(dst, hash, identity, converter)
+}
+
+func (i *fixedIdShard[I, C]) appendExclusive(dst []byte, hash uint64, identity I, converter C) []byte {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	key, refString, found := i.lookup(hash, identity)
+	if found {
+		return append(dst, refString.Value()...)
+	}
+
+	start := len(dst)
+	dst = i.appender.appendString(dst, converter)
+	// intern copies str, so it can safely refer to dst
+	str := unsafe.String(unsafe.SliceData(dst[start:]), len(dst)-start)
+	i.intern(key, hash, identity, str)
+	return dst
+}
+
+// Looks up the string for identity. If it isn't found the key where it should
+// be inserted is returned. Must be called while holding the lock.
+func (i *fixedIdShard[I, C]) lookup(hash uint64, identity I) (key uint64, refString offheap.RefString, found bool) {
+	key, refString, found, collisions := i.find(hash, identity)
+	i.stats.HashCollision += collisions
+	i.evictor.access(key)
+
+	if found {
+		i.stats.Returned++
+	}
+	return key, refString, found
+}
+
+// Interns a copy of str at key, if possible. Must be called while holding the
+// lock.
+func (i *fixedIdShard[I, C]) intern(key, hash uint64, identity I, str string) (offheap.RefString, bool) {
+	if !i.controller.canInternMaxLen(str) {
+		i.stats.MaxLenExceeded++
+		return offheap.RefString{}, false
+	}
+
+	if !i.evictor.makeRoom(i.controller, &i.interned, key, str, &i.stats, &i.usage) {
+		i.stats.UsedBytesExceeded++
+		return offheap.RefString{}, false
+	}
+
+	// Evicting strings may have changed where str should be inserted
+	key, _, _, _ = i.find(hash, identity)
+
+	// intern string and then return interned version
+	refString := offheap.AllocStringFromString(i.store, str)
+	i.interned.insert(key, hash, identity, refString)
+	i.evictor.added(key)
+
+	i.stats.Interned++
+	i.usage.Entries++
+	i.usage.Bytes += len(str)
+	return refString, true
+}
+
+func (i *fixedIdShard[I, C]) find(hash uint64, identity I) (key uint64, refString offheap.RefString, found bool, collisions int) {
+	return i.interned.find(hash, func(probed I, _ offheap.RefString) bool {
+		return probed == identity
+	})
+}
+
+func (i *fixedIdShard[I, C]) collect() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.evictor.collect(i.controller, i.store)
+}
+
+func (i *fixedIdShard[I, C]) getStats() Stats {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	stats := i.stats
+	stats.Returned += int(i.sharedReturned.Load())
+	stats.HashCollision += int(i.sharedHashCollision.Load())
+	return stats
+}
+
+func (i *fixedIdShard[I, C]) getUsage() ShardUsage {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.usage
+}
+
+func (i *fixedIdShard[I, C]) resetStats() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.stats = Stats{}
+	i.sharedReturned.Store(0)
+	i.sharedHashCollision.Store(0)
+}
+
+// Records a string which couldn't be interned because its identity was too
+// long.
+func (i *fixedIdShard[I, C]) maxLenExceeded() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.stats.MaxLenExceeded++
+}
@@ -11,7 +11,9 @@ type internController struct {
 	maxLen   int   // set to <= 0 for unlimited string length
 	maxBytes int64 // set to <= 0 for unlimited bytes
 	// Mutable fields
-	usedBytes atomic.Int64
+	usedBytes    atomic.Int64
+	retiredBytes atomic.Int64
+	epoch        atomic.Uint64
 }
 
 func newController(maxLen, maxBytes int) *internController {
@@ -26,6 +28,18 @@ func (c *internController) getUsedBytes() int {
 	return int(c.usedBytes.Load())
 }
 
+func (c *internController) getRetiredBytes() int {
+	return int(c.retiredBytes.Load())
+}
+
+func (c *internController) getEpoch() uint64 {
+	return c.epoch.Load()
+}
+
+func (c *internController) advanceEpoch() {
+	c.epoch.Add(1)
+}
+
 func (c *internController) canInternMaxLen(str string) bool {
 	if c.maxLen <= 0 {
 		// No length limit
@@ -60,3 +74,35 @@ func (c *internController) canInternUsedBytes(str string) bool {
 	// str can be interned, and the additional bytes have been accounted for
 	return true
 }
+
+// Returns true if str could be interned if enough interned strings were
+// evicted.
+func (c *internController) canEverInternUsedBytes(str string) bool {
+	return c.maxBytes <= 0 || int64(len(str)) <= c.maxBytes
+}
+
+// Moves size bytes from the interned strings to the retired strings. Returns
+// false, and moves nothing, if this would cause the retired strings to exceed
+// maxBytes.
+func (c *internController) retire(size int) bool {
+	for {
+		retiredBytes := c.retiredBytes.Load()
+		nextRetiredBytes := retiredBytes + int64(size)
+
+		if (c.maxBytes > 0) && (nextRetiredBytes > c.maxBytes) {
+			return false
+		}
+
+		if c.retiredBytes.CompareAndSwap(retiredBytes, nextRetiredBytes) {
+			break
+		}
+	}
+
+	c.usedBytes.Add(-int64(size))
+	return true
+}
+
+// Records that size bytes of retired strings have been freed.
+func (c *internController) releaseRetired(size int) {
+	c.retiredBytes.Add(-int64(size))
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The interned strings of a single shard, identified by uint64 keys.
+type internedStrings interface {
+	// Returns the string with key.
+	value(key uint64) offheap.RefString
+	// Removes the string with key.
+	remove(key uint64)
+}
+
+// Interned strings keyed directly by a converter's uint64 identity. Each
+// identity identifies exactly one string, so there are no collisions.
+//
+// The index is an offheap table, so interning a very large number of strings
+// adds almost nothing to the work done by the garbage collector.
+type identityMap struct {
+	table table[offheap.RefString]
+}
+
+func newIdentityMap(store *offheap.Store) identityMap {
+	return identityMap{
+		table: newTable[offheap.RefString](store),
+	}
+}
+
+func (m *identityMap) get(key uint64) (offheap.RefString, bool) {
+	return m.table.get(key)
+}
+
+func (m *identityMap) insert(key uint64, refString offheap.RefString) {
+	m.table.put(key, refString)
+}
+
+func (m *identityMap) value(key uint64) offheap.RefString {
+	refString, _ := m.table.get(key)
+	return refString
+}
+
+func (m *identityMap) remove(key uint64) {
+	m.table.remove(key)
+}
+
+func (m *identityMap) len() int {
+	return m.table.len()
+}
+
+// Interned strings keyed by a hash of their identity. Different identities
+// may have the same hash, so collisions are resolved by linear probing over
+// the keys i.e. if key hash is occupied by a different string we try hash+1,
+// then hash+2 and so on until we find the string or an unoccupied key.
+//
+// Each string is stored with an identity of type I, which is compared when
+// searching. Where the string is its own identity, I is struct{} and the
+// strings themselves are compared.
+//
+// Removing a string from the middle of a probe sequence would hide the
+// strings further along the sequence. So when a string is removed its key is
+// kept as a tombstone, a nil RefString, which is skipped over by lookups but
+// can be reused by inserts. Tombstones at the end of a probe sequence are
+// deleted.
+type probingMap[I comparable] struct {
+	table table[probedString[I]]
+}
+
+func newProbingMap[I comparable](store *offheap.Store) probingMap[I] {
+	return probingMap[I]{
+		table: newTable[probedString[I]](store),
+	}
+}
+
+// An interned string, along with its hash and identity. The hash allows us to
+// distinguish strings with colliding hashes from strings which have been
+// probed past their own hash.
+type probedString[I comparable] struct {
+	hash      uint64
+	identity  I
+	refString offheap.RefString
+}
+
+// Searches for the string whose hash is hash, using matches to compare each
+// interned string with the same hash.
+//
+// If the string is found its key and interned value are returned, with found
+// set to true. Otherwise the key where the string should be inserted is
+// returned.
+//
+// The number of different strings found with exactly the same hash is also
+// returned.
+func (m *probingMap[I]) find(hash uint64, matches func(identity I, refString offheap.RefString) bool) (key uint64, refString offheap.RefString, found bool, collisions int) {
+	insertKey := hash
+	foundTombstone := false
+
+	for key = hash; ; key++ {
+		probed, ok := m.table.get(key)
+		if !ok {
+			if foundTombstone {
+				return insertKey, offheap.RefString{}, false, collisions
+			}
+			return key, offheap.RefString{}, false, collisions
+		}
+
+		if probed.refString.IsNil() {
+			if !foundTombstone {
+				insertKey = key
+				foundTombstone = true
+			}
+			continue
+		}
+
+		if probed.hash != hash {
+			// This string was probed here from a different hash
+			continue
+		}
+
+		if matches(probed.identity, probed.refString) {
+			return key, probed.refString, true, collisions
+		}
+		collisions++
+	}
+}
+
+// Searches for str, whose hash is hash. Used when strings are their own
+// identity.
+func findString(m *probingMap[struct{}], hash uint64, str string) (key uint64, refString offheap.RefString, found bool, collisions int) {
+	return m.find(hash, func(_ struct{}, refString offheap.RefString) bool {
+		return refString.Value() == str
+	})
+}
+
+func (m *probingMap[I]) insert(key, hash uint64, identity I, refString offheap.RefString) {
+	m.table.put(key, probedString[I]{
+		hash:      hash,
+		identity:  identity,
+		refString: refString,
+	})
+}
+
+func (m *probingMap[I]) value(key uint64) offheap.RefString {
+	probed, _ := m.table.get(key)
+	return probed.refString
+}
+
+func (m *probingMap[I]) remove(key uint64) {
+	if m.table.contains(key + 1) {
+		// key is in the middle of a probe sequence
+		m.table.put(key, probedString[I]{})
+		return
+	}
+
+	// key is at the end of a probe sequence, delete it and any
+	// tombstones immediately before it
+	m.table.remove(key)
+	for prev := key - 1; ; prev-- {
+		probed, ok := m.table.get(prev)
+		if !ok || !probed.refString.IsNil() {
+			break
+		}
+		m.table.remove(prev)
+	}
+}
+
+func (m *probingMap[I]) len() int {
+	return m.table.len()
+}
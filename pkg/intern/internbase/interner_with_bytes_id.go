@@ -6,6 +6,7 @@ package internbase
 
 import (
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	xxhash "github.com/cespare/xxhash/v2"
@@ -44,7 +45,7 @@ func NewInternerWithBytesId[C ConverterWithBytesId](config Config) InternerWithB
 
 	shards := make([]internerWithBytesIdShard, nextPowerOfTwo(shardCount))
 	for i := range shards {
-		shards[i] = newInternerWithBytesIdShard(controller, store)
+		shards[i] = newInternerWithBytesIdShard(controller, store, config.getEviction(store))
 	}
 
 	return InternerWithBytesId[C]{
@@ -67,13 +68,55 @@ func (i *InternerWithBytesId[C]) Get(converter C) string {
 	return i.shards[idx].get(hash, bytes)
 }
 
+// Appends the string representation of converter to dst, interning it if it
+// is not already interned.
+//
+// If dst has enough capacity no heap allocation is made whether or not the
+// string was already interned.
+func (i *InternerWithBytesId[C]) AppendTo(dst []byte, converter C) []byte {
+	bytes := converter.Identity()
+	hash := xxhash.Sum64(bytes)
+	idx := i.getIndex(hash)
+	return i.shards[idx].appendTo(dst, hash, bytes)
+}
+
 // Retrieves the summarised stats for interned strings
 func (i *InternerWithBytesId[C]) GetStats() StatsSummary {
 	intShards := make([]Stats, 0, len(i.shards))
+	usage := make([]ShardUsage, 0, len(i.shards))
 	for idx := range i.shards {
 		intShards = append(intShards, i.shards[idx].getStats())
+		usage = append(usage, i.shards[idx].getUsage())
+	}
+	summary := MakeSummary(intShards, i.controller.getUsedBytes())
+	summary.RetiredBytes = i.controller.getRetiredBytes()
+	summary.Usage = usage
+	return summary
+}
+
+// Resets every counter in Stats to zero. The ShardUsage in subsequent
+// summaries is unaffected.
+func (i *InternerWithBytesId[C]) ResetStats() {
+	for idx := range i.shards {
+		i.shards[idx].resetStats()
+	}
+}
+
+// Advances the interner's epoch, and frees any evicted strings whose grace
+// period has ended.
+//
+// Calling AdvanceEpoch indicates that strings returned by Get before the
+// previous call to AdvanceEpoch are no longer in use. An evicted string is
+// freed once it can no longer be in use. If strings returned by Get are
+// retained longer than this they must be copied.
+//
+// If the interner is not configured with an eviction policy then strings are
+// never evicted, and this method has no effect.
+func (i *InternerWithBytesId[C]) AdvanceEpoch() {
+	i.controller.advanceEpoch()
+	for idx := range i.shards {
+		i.shards[idx].collect()
 	}
-	return MakeSummary(intShards, i.controller.getUsedBytes())
 }
 
 func (i *InternerWithBytesId[C]) getIndex(hash uint64) uint64 {
@@ -84,71 +127,192 @@ type internerWithBytesIdShard struct {
 	controller *internController
 	store      *offheap.Store
 	//
-	lock     sync.Mutex
-	interned map[uint64]offheap.RefString
+	lock     sync.RWMutex
+	interned probingMap[struct{}]
+	evictor  shardEvictor
 	stats    Stats
+	usage    ShardUsage
+	// Count strings returned, and their hash collisions, while holding the
+	// read lock
+	sharedReturned      atomic.Int64
+	sharedHashCollision atomic.Int64
 }
 
-func newInternerWithBytesIdShard(controller *internController, store *offheap.Store) internerWithBytesIdShard {
+func newInternerWithBytesIdShard(controller *internController, store *offheap.Store, policy EvictionPolicy) internerWithBytesIdShard {
 	return internerWithBytesIdShard{
 		controller: controller,
 		store:      store,
 		//
-		interned: make(map[uint64]offheap.RefString),
+		interned: newProbingMap[struct{}](store),
+		evictor:  newShardEvictor(policy),
 	}
 }
 
 func (i *internerWithBytesIdShard) get(hash uint64, bytes []byte) string {
+	if len(bytes) == 0 {
+		// We hardcode the empty string case here
+		i.sharedReturned.Add(1)
+		return ""
+	}
+
+	unsafeStr := unsafe.String(&bytes[0], len(bytes))
+
+	if interned, ok := i.getShared(hash, unsafeStr); ok {
+		return interned
+	}
+	return i.getExclusive(hash, bytes, unsafeStr)
+}
+
+// Looks up an already interned string while holding only the read lock, so
+// that concurrent lookups don't block each other. Returns false if the string
+// isn't interned.
+func (i *internerWithBytesIdShard) getShared(hash uint64, unsafeStr string) (string, bool) {
+	i.lock.RLock()
+
+	key, refString, found, collisions := findString(&i.interned, hash, unsafeStr)
+	if !found {
+		i.lock.RUnlock()
+		return "", false
+	}
+	i.sharedHashCollision.Add(int64(collisions))
+	i.sharedReturned.Add(1)
+	full := i.evictor.accessShared(key)
+	str := refString.Value()
+
+	i.lock.RUnlock()
+
+	if full {
+		i.drainAccesses()
+	}
+	return str, true
+}
+
+// Applies the accesses recorded by getShared to the eviction policy.
+func (i *internerWithBytesIdShard) drainAccesses() {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
+	i.evictor.drain()
+}
+
+func (i *internerWithBytesIdShard) getExclusive(hash uint64, bytes []byte, unsafeStr string) string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	key, refString, found := i.lookup(hash, unsafeStr)
+	if found {
+		// Return the interned version of the string
+		return refString.Value()
+	}
+
+	if refString, ok := i.intern(key, hash, bytes, unsafeStr); ok {
+		return refString.Value()
+	}
+	// Can't intern this string. Return string copy
+	return string(bytes)
+}
+
+func (i *internerWithBytesIdShard) appendTo(dst []byte, hash uint64, bytes []byte) []byte {
 	if len(bytes) == 0 {
 		// We hardcode the empty string case here
-		i.stats.Returned++
-		return ""
+		i.sharedReturned.Add(1)
+		return dst
 	}
 
 	unsafeStr := unsafe.String(&bytes[0], len(bytes))
-	if refString, ok := i.interned[hash]; ok {
-		internedStr := refString.Value()
-		// Because two different strings _might_ have the same hash we
-		// test that the interned string and the submitted string are
-		// equal.
-		if internedStr == unsafeStr {
-			// Return the interned version of the string
-			i.stats.Returned++
-			return internedStr
-		}
-		// Hash collision, can't intern this string.  Return string
-		// copy
-		i.stats.HashCollision++
-		return string(bytes)
+
+	if interned, ok := i.getShared(hash, unsafeStr); ok {
+		return append(dst, interned...)
+	}
+	return i.appendExclusive(dst, hash, bytes, unsafeStr)
+}
+
+func (i *internerWithBytesIdShard) appendExclusive(dst []byte, hash uint64, bytes []byte, unsafeStr string) []byte {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	key, refString, found := i.lookup(hash, unsafeStr)
+	if found {
+		return append(dst, refString.Value()...)
+	}
+
+	i.intern(key, hash, bytes, unsafeStr)
+	return append(dst, bytes...)
+}
+
+// Looks up str. If it isn't found the key where it should be inserted is
+// returned. Must be called while holding the lock.
+func (i *internerWithBytesIdShard) lookup(hash uint64, str string) (key uint64, refString offheap.RefString, found bool) {
+	// Because two different strings _might_ have the same hash find
+	// compares the interned strings with the submitted string
+	key, refString, found, collisions := findString(&i.interned, hash, str)
+	i.stats.HashCollision += collisions
+	i.evictor.access(key)
+
+	if found {
+		i.stats.Returned++
 	}
+	return key, refString, found
+}
 
+// Interns a copy of bytes at key, if possible. Must be called while holding
+// the lock.
+func (i *internerWithBytesIdShard) intern(key, hash uint64, bytes []byte, unsafeStr string) (offheap.RefString, bool) {
 	if !i.controller.canInternMaxLen(unsafeStr) {
-		// Too long, can't intern this string. Return string copy
+		// Too long, can't intern this string
 		i.stats.MaxLenExceeded++
-		return string(bytes)
+		return offheap.RefString{}, false
 	}
 
-	if !i.controller.canInternUsedBytes(unsafeStr) {
-		// Too many bytes interned, can't intern this string. Return
-		// string copy
+	if !i.evictor.makeRoom(i.controller, &i.interned, key, unsafeStr, &i.stats, &i.usage) {
+		// Too many bytes interned, can't intern this string
 		i.stats.UsedBytesExceeded++
-		return string(bytes)
+		return offheap.RefString{}, false
 	}
 
+	// Evicting strings may have changed where str should be inserted
+	key, _, _, _ = findString(&i.interned, hash, unsafeStr)
+
 	// intern string and then return interned version
 	refString := offheap.AllocStringFromBytes(i.store, bytes)
-	i.interned[hash] = refString
+	i.interned.insert(key, hash, struct{}{}, refString)
+	i.evictor.added(key)
 
 	i.stats.Interned++
-	return refString.Value()
+	i.usage.Entries++
+	i.usage.Bytes += len(unsafeStr)
+	return refString, true
+}
+
+func (i *internerWithBytesIdShard) collect() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.evictor.collect(i.controller, i.store)
 }
 
 func (i *internerWithBytesIdShard) getStats() Stats {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	return i.stats
+	stats := i.stats
+	stats.Returned += int(i.sharedReturned.Load())
+	stats.HashCollision += int(i.sharedHashCollision.Load())
+	return stats
+}
+
+func (i *internerWithBytesIdShard) getUsage() ShardUsage {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.usage
+}
+
+func (i *internerWithBytesIdShard) resetStats() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.stats = Stats{}
+	i.sharedReturned.Store(0)
+	i.sharedHashCollision.Store(0)
 }
@@ -0,0 +1,158 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+// We don't know of any xxhash collisions between manageable sized strings, so
+// these tests call the shard directly with a made up hash.
+const collidingHash = 12345
+
+// Demonstrate that different strings with the same hash are all interned
+func TestBytesIdShard_HashCollision(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	shard := newInternerWithBytesIdShard(newController(0, 0), store, nil)
+	strs := []string{"first", "second", "third"}
+
+	interned := []string{}
+	for _, str := range strs {
+		internedStr := shard.get(collidingHash, []byte(str))
+		assert.Equal(t, str, internedStr)
+		interned = append(interned, internedStr)
+	}
+	// "second" collided with "first", "third" collided with both
+	assert.Equal(t, Stats{Interned: 3, HashCollision: 3}, shard.getStats())
+
+	for i, str := range strs {
+		internedStr := shard.get(collidingHash, []byte(str))
+		assert.Same(t, unsafe.StringData(interned[i]), unsafe.StringData(internedStr))
+	}
+	assert.Equal(t, Stats{Interned: 3, Returned: 3, HashCollision: 6}, shard.getStats())
+}
+
+// Demonstrate that strings probed past their hash, because of a collision, are
+// not counted as collisions with strings whose hash is at the probed key
+func TestBytesIdShard_ProbedPastNeighbour(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	shard := newInternerWithBytesIdShard(newController(0, 0), store, nil)
+
+	shard.get(collidingHash, []byte("first"))
+	shard.get(collidingHash, []byte("second"))
+	// This string's hash is occupied by "second"
+	neighbour := "neighbour"
+	shard.get(collidingHash+1, []byte(neighbour))
+	assert.Equal(t, Stats{Interned: 3, HashCollision: 1}, shard.getStats())
+
+	internedStr := shard.get(collidingHash+1, []byte(neighbour))
+	assert.Equal(t, neighbour, internedStr)
+	assert.Equal(t, Stats{Interned: 3, Returned: 1, HashCollision: 1}, shard.getStats())
+}
+
+// Demonstrate that evicting a string from the middle of a probe sequence does
+// not hide the strings after it
+func TestBytesIdShard_EvictCollidingString(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	shard := newInternerWithBytesIdShard(newController(0, 9), store, NewClockPolicy(store))
+
+	shard.get(collidingHash, []byte("aaa"))
+	bbb := shard.get(collidingHash, []byte("bbb"))
+	ccc := shard.get(collidingHash, []byte("ccc"))
+	// Access the later strings so "aaa" is evicted first
+	shard.get(collidingHash, []byte("bbb"))
+	shard.get(collidingHash, []byte("ccc"))
+
+	shard.get(collidingHash+100, []byte("ddd"))
+	assert.Equal(t, 1, shard.getStats().Evicted)
+
+	// The strings after the evicted string are still found
+	assert.Same(t, unsafe.StringData(bbb), unsafe.StringData(shard.get(collidingHash, []byte("bbb"))))
+	assert.Same(t, unsafe.StringData(ccc), unsafe.StringData(shard.get(collidingHash, []byte("ccc"))))
+
+	// The evicted string's key is reused when it is interned again
+	tombstone, ok := shard.interned.table.get(collidingHash)
+	assert.True(t, ok)
+	assert.True(t, tombstone.refString.IsNil())
+}
+
+// Demonstrate that, with eviction enabled, interned strings are returned while
+// holding only the read lock, and that these lookups still protect strings
+// from eviction
+func TestBytesIdShard_EvictionSharedLookup(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	shard := newInternerWithBytesIdShard(newController(0, 6), store, NewClockPolicy(store))
+
+	aaa := shard.get(collidingHash, []byte("aaa"))
+	shard.get(collidingHash+100, []byte("bbb"))
+
+	// Taking the write lock here would deadlock
+	shard.lock.RLock()
+	assert.Same(t, unsafe.StringData(aaa), unsafe.StringData(shard.get(collidingHash, []byte("aaa"))))
+	shard.lock.RUnlock()
+
+	// The lookup of "aaa" gave it a second chance, so "bbb" is evicted
+	shard.get(collidingHash+200, []byte("ccc"))
+	assert.Equal(t, 1, shard.getStats().Evicted)
+	_, _, found, _ := findString(&shard.interned, collidingHash+100, "bbb")
+	assert.False(t, found)
+	assert.Same(t, unsafe.StringData(aaa), unsafe.StringData(shard.get(collidingHash, []byte("aaa"))))
+
+	// Buffered lookups are applied to the policy whenever the buffer fills
+	for range 10 * accessBufferSize {
+		shard.get(collidingHash, []byte("aaa"))
+	}
+	assert.Less(t, shard.evictor.accesses.next.Load(), int64(accessBufferSize))
+}
+
+func TestProbingMap_RemoveTombstones(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	m := newProbingMap[struct{}](store)
+	for i, str := range []string{"a", "b", "c"} {
+		key, _, found, _ := findString(&m, collidingHash, str)
+		assert.False(t, found)
+		assert.Equal(t, uint64(collidingHash+i), key)
+		m.insert(key, collidingHash, struct{}{}, offheap.AllocStringFromString(store, str))
+	}
+
+	// Removing from the middle leaves tombstones
+	m.remove(collidingHash)
+	m.remove(collidingHash + 1)
+	assert.Equal(t, 3, m.len())
+
+	key, refString, found, _ := findString(&m, collidingHash, "c")
+	assert.True(t, found)
+	assert.Equal(t, uint64(collidingHash+2), key)
+	assert.Equal(t, "c", refString.Value())
+
+	// A new string is inserted at the first tombstone
+	key, _, found, _ = findString(&m, collidingHash, "d")
+	assert.False(t, found)
+	assert.Equal(t, uint64(collidingHash), key)
+
+	// Removing the end of the sequence removes the tombstones before it
+	m.remove(collidingHash + 2)
+	assert.Equal(t, 0, m.len())
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"sync"
+
+	xxhash "github.com/cespare/xxhash/v2"
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The maximum length, in bytes, of the identity of a ConverterWithCompositeId.
+const MaxCompositeIdLen = 64
+
+// An ConverterWithCompositeId converts types to strings which are able to be
+// canonically identified by a short sequence of bytes, built from one or more
+// values.
+//
+// A good example of this is a host and port pair, which might be identified by
+// the bytes of the host followed by the two bytes of the port, but whose
+// string is generated by fmt.Sprintf("%s:%d", host, port).
+//
+// Identity appends the identity to buf and returns the result, e.g. using
+// binary.BigEndian.AppendUint16 and append. buf has a capacity of
+// MaxCompositeIdLen. Unlike ConverterWithBytesId the identity doesn't need to
+// be equal to the string, so String() is only called if the identity has not
+// already been interned.
+//
+// Converters may also implement AppendString(dst []byte) []byte, appending the
+// same string returned by String() to dst. AppendTo uses it, when available,
+// to intern values without allocating.
+type ConverterWithCompositeId interface {
+	Identity(buf []byte) []byte
+	String() string
+}
+
+// A fixed size copy of an identity. Unused bytes are always zero, so
+// identities can be compared with ==.
+type compositeId struct {
+	len   uint8
+	bytes [MaxCompositeIdLen]byte
+}
+
+// Buffers passed to ConverterWithCompositeId.Identity. Passing a buffer to a
+// converter always moves it to the heap, so buffers are pooled to avoid an
+// allocation for every call to Get.
+var compositeIdBuffers = sync.Pool{
+	New: func() any {
+		return new([MaxCompositeIdLen]byte)
+	},
+}
+
+// A InternerWithCompositeId is the type which manages the interning of strings.
+type InternerWithCompositeId[C ConverterWithCompositeId] struct {
+	indexMask  uint64
+	controller *internController
+	store      *offheap.Store
+	appender   stringAppender[C]
+	shards     []fixedIdShard[compositeId, C]
+}
+
+// Construct a new InternerWithCompositeId with the provided config.
+func NewInternerWithCompositeId[C ConverterWithCompositeId](config Config) InternerWithCompositeId[C] {
+	controller := newController(config.getMaxLen(), config.getMaxBytes())
+	store := config.getStore()
+	shardCount := config.getShards()
+
+	shards := make([]fixedIdShard[compositeId, C], shardCount)
+	for i := range shards {
+		shards[i] = newFixedIdShard[compositeId, C](controller, store, config.getEviction(store))
+	}
+
+	return InternerWithCompositeId[C]{
+		indexMask:  uint64(shardCount - 1),
+		controller: controller,
+		store:      store,
+		appender:   newStringAppender[C](),
+		shards:     shards,
+	}
+}
+
+// Returns the string representation of converter.
+//
+// The string value may be retrieved from an interning cache or stored in the
+// cache.  Regardless of whether the string is or was interned, the correct
+// string value is returned.
+//
+// If converter's identity is longer than MaxCompositeIdLen the string is
+// generated but not interned, and is counted as MaxLenExceeded.
+func (i *InternerWithCompositeId[C]) Get(converter C) string {
+	identity, hash, ok := compositeIdentity(converter)
+	idx := i.getIndex(hash)
+	if !ok {
+		i.shards[idx].maxLenExceeded()
+		return converter.String()
+	}
+	return i.shards[idx].get(hash, identity, converter)
+}
+
+// Appends the string representation of converter to dst.
+//
+// If the string is not yet interned it is appended to dst directly and then
+// interned, so if dst has enough capacity no heap allocation is made whether
+// or not the string was already interned.
+func (i *InternerWithCompositeId[C]) AppendTo(dst []byte, converter C) []byte {
+	identity, hash, ok := compositeIdentity(converter)
+	idx := i.getIndex(hash)
+	if !ok {
+		i.shards[idx].maxLenExceeded()
+		return i.appender.appendString(dst, converter)
+	}
+	return i.shards[idx].appendTo(dst, hash, identity, converter)
+}
+
+// Retrieves the summarised stats for interned strings
+func (i *InternerWithCompositeId[C]) GetStats() StatsSummary {
+	shards := make([]Stats, 0, len(i.shards))
+	usage := make([]ShardUsage, 0, len(i.shards))
+	for idx := range i.shards {
+		shards = append(shards, i.shards[idx].getStats())
+		usage = append(usage, i.shards[idx].getUsage())
+	}
+	summary := MakeSummary(shards, i.controller.getUsedBytes())
+	summary.RetiredBytes = i.controller.getRetiredBytes()
+	summary.Usage = usage
+	return summary
+}
+
+// Resets every counter in Stats to zero. The ShardUsage in subsequent
+// summaries is unaffected.
+func (i *InternerWithCompositeId[C]) ResetStats() {
+	for idx := range i.shards {
+		i.shards[idx].resetStats()
+	}
+}
+
+// Advances the interner's epoch, and frees any evicted strings whose grace
+// period has ended.
+//
+// Calling AdvanceEpoch indicates that strings returned by Get before the
+// previous call to AdvanceEpoch are no longer in use. An evicted string is
+// freed once it can no longer be in use. If strings returned by Get are
+// retained longer than this they must be copied.
+//
+// If the interner is not configured with an eviction policy then strings are
+// never evicted, and this method has no effect.
+func (i *InternerWithCompositeId[C]) AdvanceEpoch() {
+	i.controller.advanceEpoch()
+	for idx := range i.shards {
+		i.shards[idx].collect()
+	}
+}
+
+func (i *InternerWithCompositeId[C]) getIndex(hash uint64) uint64 {
+	return i.indexMask & hash
+}
+
+// Builds the identity of converter, along with its hash. Returns false if the
+// identity is longer than MaxCompositeIdLen.
+func compositeIdentity[C ConverterWithCompositeId](converter C) (compositeId, uint64, bool) {
+	buf := compositeIdBuffers.Get().(*[MaxCompositeIdLen]byte)
+	bytes := converter.Identity(buf[:0])
+	if len(bytes) > MaxCompositeIdLen {
+		compositeIdBuffers.Put(buf)
+		return compositeId{}, xxhash.Sum64(bytes), false
+	}
+
+	identity := compositeId{}
+	identity.len = uint8(copy(identity.bytes[:], bytes))
+	compositeIdBuffers.Put(buf)
+
+	return identity, xxhash.Sum64(identity.bytes[:identity.len]), true
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Counts the number of times String() is called across all hostPortConverters
+var hostPortStringCalls int
+
+type hostPortConverter struct {
+	host string
+	port uint16
+}
+
+func (c hostPortConverter) Identity(buf []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, c.port)
+	return append(buf, c.host...)
+}
+
+func (c hostPortConverter) String() string {
+	hostPortStringCalls++
+	return fmt.Sprintf("%s:%d", c.host, c.port)
+}
+
+func (c hostPortConverter) AppendString(dst []byte) []byte {
+	return fmt.Appendf(dst, "%s:%d", c.host, c.port)
+}
+
+// Demonstrate that strings are only generated when the identity has not been
+// interned
+func TestCompositeIdInterner_StringOnlyOnMiss(t *testing.T) {
+	interner := NewInternerWithCompositeId[hostPortConverter](Config{})
+	hostPortStringCalls = 0
+
+	first := interner.Get(hostPortConverter{host: "localhost", port: 8080})
+	assert.Equal(t, "localhost:8080", first)
+	assert.Equal(t, 1, hostPortStringCalls)
+
+	second := interner.Get(hostPortConverter{host: "localhost", port: 8080})
+	assert.Same(t, unsafe.StringData(first), unsafe.StringData(second))
+	assert.Equal(t, 1, hostPortStringCalls)
+
+	// A different port is a different identity
+	assert.Equal(t, "localhost:8081", interner.Get(hostPortConverter{host: "localhost", port: 8081}))
+	assert.Equal(t, 2, hostPortStringCalls)
+
+	assert.Equal(t, Stats{Interned: 2, Returned: 1}, interner.GetStats().Total)
+}
+
+// Demonstrate that identities longer than MaxCompositeIdLen are not interned
+func TestCompositeIdInterner_IdentityTooLong(t *testing.T) {
+	interner := NewInternerWithCompositeId[hostPortConverter](Config{})
+
+	host := strings.Repeat("a", MaxCompositeIdLen)
+	converter := hostPortConverter{host: host, port: 80}
+
+	first := interner.Get(converter)
+	assert.Equal(t, host+":80", first)
+	second := interner.Get(converter)
+	assert.Equal(t, host+":80", second)
+	assert.NotSame(t, unsafe.StringData(first), unsafe.StringData(second))
+
+	assert.Equal(t, Stats{MaxLenExceeded: 2}, interner.GetStats().Total)
+}
+
+// Demonstrate that an identity which fills the buffer exactly is interned
+func TestCompositeIdInterner_IdentityMaxLen(t *testing.T) {
+	interner := NewInternerWithCompositeId[hostPortConverter](Config{})
+
+	host := strings.Repeat("a", MaxCompositeIdLen-2)
+	converter := hostPortConverter{host: host, port: 80}
+
+	first := interner.Get(converter)
+	second := interner.Get(converter)
+	assert.Same(t, unsafe.StringData(first), unsafe.StringData(second))
+
+	assert.Equal(t, Stats{Interned: 1, Returned: 1}, interner.GetStats().Total)
+}
+
+// A hostPortConverter which doesn't implement AppendString
+type plainHostPortConverter struct {
+	host string
+	port uint16
+}
+
+func (c plainHostPortConverter) Identity(buf []byte) []byte {
+	return hostPortConverter(c).Identity(buf)
+}
+
+func (c plainHostPortConverter) String() string {
+	return hostPortConverter(c).String()
+}
+
+// Demonstrate that AppendTo uses AppendString when the converter implements
+// it, and String() otherwise
+func TestCompositeIdInterner_AppendTo(t *testing.T) {
+	interner := NewInternerWithCompositeId[hostPortConverter](Config{})
+	hostPortStringCalls = 0
+
+	dst := interner.AppendTo([]byte("addr="), hostPortConverter{host: "localhost", port: 8080})
+	assert.Equal(t, "addr=localhost:8080", string(dst))
+	assert.Equal(t, 0, hostPortStringCalls)
+	assert.Equal(t, "localhost:8080", interner.Get(hostPortConverter{host: "localhost", port: 8080}))
+	assert.Equal(t, 0, hostPortStringCalls)
+
+	plainInterner := NewInternerWithCompositeId[plainHostPortConverter](Config{})
+	hostPortStringCalls = 0
+
+	dst = plainInterner.AppendTo([]byte("addr="), plainHostPortConverter{host: "localhost", port: 8080})
+	assert.Equal(t, "addr=localhost:8080", string(dst))
+	assert.Equal(t, 1, hostPortStringCalls)
+	dst = plainInterner.AppendTo(nil, plainHostPortConverter{host: "localhost", port: 8080})
+	assert.Equal(t, "localhost:8080", string(dst))
+	assert.Equal(t, 1, hostPortStringCalls)
+
+	assert.Equal(t, Stats{Interned: 1, Returned: 1}, plainInterner.GetStats().Total)
+}
@@ -6,6 +6,8 @@ package internbase
 
 import (
 	"sync"
+	"sync/atomic"
+	"unsafe"
 
 	"github.com/fmstephe/memorymanager/offheap"
 )
@@ -15,6 +17,10 @@ import (
 //
 // A good example of this is an actual uint64 value. Another example would be a
 // time.Time value which is identified by its UnixNanos() value.
+//
+// Converters may also implement AppendString(dst []byte) []byte, appending the
+// same string returned by String() to dst. AppendTo uses it, when available,
+// to intern values without allocating.
 type ConverterWithUint64Id interface {
 	Identity() uint64
 	String() string
@@ -36,7 +42,7 @@ func NewInternerWithUint64Id[C ConverterWithUint64Id](config Config) InternerWit
 
 	shards := make([]internerWithUint64IdShard[C], shardCount)
 	for i := range shards {
-		shards[i] = newInternerWithUint64IdShard[C](controller, store)
+		shards[i] = newInternerWithUint64IdShard[C](controller, store, config.getEviction(store))
 	}
 
 	return InternerWithUint64Id[C]{
@@ -57,13 +63,53 @@ func (i *InternerWithUint64Id[C]) Get(converter C) string {
 	return i.shards[idx].get(converter)
 }
 
+// Appends the string representation of converter to dst.
+//
+// If the string is not yet interned it is appended to dst directly and then
+// interned, so if dst has enough capacity no heap allocation is made whether
+// or not the string was already interned.
+func (i *InternerWithUint64Id[C]) AppendTo(dst []byte, converter C) []byte {
+	idx := i.getIndex(converter.Identity())
+	return i.shards[idx].appendTo(dst, converter)
+}
+
 // Retrieves the summarised stats for interned int strings
 func (i *InternerWithUint64Id[C]) GetStats() StatsSummary {
 	intShards := make([]Stats, 0, len(i.shards))
+	usage := make([]ShardUsage, 0, len(i.shards))
 	for idx := range i.shards {
 		intShards = append(intShards, i.shards[idx].getStats())
+		usage = append(usage, i.shards[idx].getUsage())
+	}
+	summary := MakeSummary(intShards, i.controller.getUsedBytes())
+	summary.RetiredBytes = i.controller.getRetiredBytes()
+	summary.Usage = usage
+	return summary
+}
+
+// Resets every counter in Stats to zero. The ShardUsage in subsequent
+// summaries is unaffected.
+func (i *InternerWithUint64Id[C]) ResetStats() {
+	for idx := range i.shards {
+		i.shards[idx].resetStats()
+	}
+}
+
+// Advances the interner's epoch, and frees any evicted strings whose grace
+// period has ended.
+//
+// Calling AdvanceEpoch indicates that strings returned by Get before the
+// previous call to AdvanceEpoch are no longer in use. An evicted string is
+// freed once it can no longer be in use. If strings returned by Get are
+// retained longer than this they must be copied.
+//
+// If the interner is not configured with an eviction policy then strings are
+// never evicted, and this method has no effect.
+func (i *InternerWithUint64Id[C]) AdvanceEpoch() {
+	i.controller.advanceEpoch()
+	for idx := range i.shards {
+		i.shards[idx].collect()
 	}
-	return MakeSummary(intShards, i.controller.getUsedBytes())
 }
 
 func (i *InternerWithUint64Id[C]) getIndex(hash uint64) uint64 {
@@ -73,56 +119,169 @@ func (i *InternerWithUint64Id[C]) getIndex(hash uint64) uint64 {
 type internerWithUint64IdShard[C ConverterWithUint64Id] struct {
 	controller *internController
 	store      *offheap.Store
+	appender   stringAppender[C]
 	//
-	lock     sync.Mutex
-	interned map[uint64]offheap.RefString
+	lock     sync.RWMutex
+	interned identityMap
+	evictor  shardEvictor
 	stats    Stats
+	usage    ShardUsage
+	// Counts strings returned while holding the read lock
+	sharedReturned atomic.Int64
 }
 
-func newInternerWithUint64IdShard[C ConverterWithUint64Id](controller *internController, store *offheap.Store) internerWithUint64IdShard[C] {
+func newInternerWithUint64IdShard[C ConverterWithUint64Id](controller *internController, store *offheap.Store, policy EvictionPolicy) internerWithUint64IdShard[C] {
 	return internerWithUint64IdShard[C]{
 		controller: controller,
 		store:      store,
+		appender:   newStringAppender[C](),
 		//
-		interned: make(map[uint64]offheap.RefString),
+		interned: newIdentityMap(store),
+		evictor:  newShardEvictor(policy),
 	}
 }
 
 func (i *internerWithUint64IdShard[C]) get(converter C) string {
+	identity := converter.Identity()
+
+	if interned, ok := i.getShared(identity); ok {
+		return interned
+	}
+	return i.getExclusive(identity, converter)
+}
+
+// Looks up an already interned string while holding only the read lock, so
+// that concurrent lookups don't block each other. Returns false if the string
+// isn't interned.
+func (i *internerWithUint64IdShard[C]) getShared(identity uint64) (string, bool) {
+	i.lock.RLock()
+
+	refString, ok := i.interned.get(identity)
+	if !ok {
+		i.lock.RUnlock()
+		return "", false
+	}
+	i.sharedReturned.Add(1)
+	full := i.evictor.accessShared(identity)
+	str := refString.Value()
+
+	i.lock.RUnlock()
+
+	if full {
+		i.drainAccesses()
+	}
+	return str, true
+}
+
+// Applies the accesses recorded by getShared to the eviction policy.
+func (i *internerWithUint64IdShard[C]) drainAccesses() {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	identity := converter.Identity()
+	i.evictor.drain()
+}
 
-	if refString, ok := i.interned[identity]; ok {
-		i.stats.Returned++
+func (i *internerWithUint64IdShard[C]) getExclusive(identity uint64, converter C) string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if refString, ok := i.lookup(identity); ok {
 		return refString.Value()
 	}
 
 	str := converter.String()
+	if refString, ok := i.intern(identity, str); ok {
+		return refString.Value()
+	}
+	return str
+}
+
+func (i *internerWithUint64IdShard[C]) appendTo(dst []byte, converter C) []byte {
+	identity := converter.Identity()
 
+	if interned, ok := i.getShared(identity); ok {
+		return append(dst, interned...)
+	}
+	return i.appendExclusive(dst, identity, converter)
+}
+
+func (i *internerWithUint64IdShard[C]) appendExclusive(dst []byte, identity uint64, converter C) []byte {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if refString, ok := i.lookup(identity); ok {
+		return append(dst, refString.Value()...)
+	}
+
+	start := len(dst)
+	dst = i.appender.appendString(dst, converter)
+	// intern copies str, so it can safely refer to dst
+	str := unsafe.String(unsafe.SliceData(dst[start:]), len(dst)-start)
+	i.intern(identity, str)
+	return dst
+}
+
+// Looks up the string for identity. Must be called while holding the lock.
+func (i *internerWithUint64IdShard[C]) lookup(identity uint64) (offheap.RefString, bool) {
+	i.evictor.access(identity)
+
+	refString, ok := i.interned.get(identity)
+	if ok {
+		i.stats.Returned++
+	}
+	return refString, ok
+}
+
+// Interns a copy of str, if possible. Must be called while holding the lock.
+func (i *internerWithUint64IdShard[C]) intern(identity uint64, str string) (offheap.RefString, bool) {
 	if !i.controller.canInternMaxLen(str) {
 		i.stats.MaxLenExceeded++
-		return str
+		return offheap.RefString{}, false
 	}
 
-	if !i.controller.canInternUsedBytes(str) {
+	if !i.evictor.makeRoom(i.controller, &i.interned, identity, str, &i.stats, &i.usage) {
 		i.stats.UsedBytesExceeded++
-		return str
+		return offheap.RefString{}, false
 	}
 
 	// intern int-string and then return interned version
 	refString := offheap.AllocStringFromString(i.store, str)
-	i.interned[identity] = refString
+	i.interned.insert(identity, refString)
+	i.evictor.added(identity)
 
-	interned := refString.Value()
 	i.stats.Interned++
-	return interned
+	i.usage.Entries++
+	i.usage.Bytes += len(str)
+	return refString, true
+}
+
+func (i *internerWithUint64IdShard[C]) collect() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.evictor.collect(i.controller, i.store)
 }
 
 func (i *internerWithUint64IdShard[C]) getStats() Stats {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	return i.stats
+	stats := i.stats
+	stats.Returned += int(i.sharedReturned.Load())
+	return stats
+}
+
+func (i *internerWithUint64IdShard[C]) getUsage() ShardUsage {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.usage
+}
+
+func (i *internerWithUint64IdShard[C]) resetStats() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.stats = Stats{}
+	i.sharedReturned.Store(0)
 }
@@ -0,0 +1,126 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"encoding/binary"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// An ConverterWithUint128Id converts types to strings which are able to be
+// canonically identified by a 128 bit value.
+//
+// Good examples of this are IPv6 addresses and UUIDs. These are too large to
+// be identified by a uint64, but can be identified without generating and
+// hashing their string representation.
+//
+// Converters may also implement AppendString(dst []byte) []byte, appending the
+// same string returned by String() to dst. AppendTo uses it, when available,
+// to intern values without allocating.
+type ConverterWithUint128Id interface {
+	Identity() [16]byte
+	String() string
+}
+
+// A InternerWithUint128Id is the type which manages the interning of strings.
+type InternerWithUint128Id[C ConverterWithUint128Id] struct {
+	indexMask  uint64
+	controller *internController
+	store      *offheap.Store
+	shards     []fixedIdShard[[16]byte, C]
+}
+
+// Construct a new InternerWithUint128Id with the provided config.
+func NewInternerWithUint128Id[C ConverterWithUint128Id](config Config) InternerWithUint128Id[C] {
+	controller := newController(config.getMaxLen(), config.getMaxBytes())
+	store := config.getStore()
+	shardCount := config.getShards()
+
+	shards := make([]fixedIdShard[[16]byte, C], shardCount)
+	for i := range shards {
+		shards[i] = newFixedIdShard[[16]byte, C](controller, store, config.getEviction(store))
+	}
+
+	return InternerWithUint128Id[C]{
+		indexMask:  uint64(shardCount - 1),
+		controller: controller,
+		store:      store,
+		shards:     shards,
+	}
+}
+
+// Returns the string representation of converter.
+//
+// The string value may be retrieved from an interning cache or stored in the
+// cache.  Regardless of whether the string is or was interned, the correct
+// string value is returned.
+func (i *InternerWithUint128Id[C]) Get(converter C) string {
+	identity := converter.Identity()
+	hash := hash128(identity)
+	idx := i.getIndex(hash)
+	return i.shards[idx].get(hash, identity, converter)
+}
+
+// Appends the string representation of converter to dst.
+//
+// If the string is not yet interned it is appended to dst directly and then
+// interned, so if dst has enough capacity no heap allocation is made whether
+// or not the string was already interned.
+func (i *InternerWithUint128Id[C]) AppendTo(dst []byte, converter C) []byte {
+	identity := converter.Identity()
+	hash := hash128(identity)
+	idx := i.getIndex(hash)
+	return i.shards[idx].appendTo(dst, hash, identity, converter)
+}
+
+// Retrieves the summarised stats for interned strings
+func (i *InternerWithUint128Id[C]) GetStats() StatsSummary {
+	shards := make([]Stats, 0, len(i.shards))
+	usage := make([]ShardUsage, 0, len(i.shards))
+	for idx := range i.shards {
+		shards = append(shards, i.shards[idx].getStats())
+		usage = append(usage, i.shards[idx].getUsage())
+	}
+	summary := MakeSummary(shards, i.controller.getUsedBytes())
+	summary.RetiredBytes = i.controller.getRetiredBytes()
+	summary.Usage = usage
+	return summary
+}
+
+// Resets every counter in Stats to zero. The ShardUsage in subsequent
+// summaries is unaffected.
+func (i *InternerWithUint128Id[C]) ResetStats() {
+	for idx := range i.shards {
+		i.shards[idx].resetStats()
+	}
+}
+
+// Advances the interner's epoch, and frees any evicted strings whose grace
+// period has ended.
+//
+// Calling AdvanceEpoch indicates that strings returned by Get before the
+// previous call to AdvanceEpoch are no longer in use. An evicted string is
+// freed once it can no longer be in use. If strings returned by Get are
+// retained longer than this they must be copied.
+//
+// If the interner is not configured with an eviction policy then strings are
+// never evicted, and this method has no effect.
+func (i *InternerWithUint128Id[C]) AdvanceEpoch() {
+	i.controller.advanceEpoch()
+	for idx := range i.shards {
+		i.shards[idx].collect()
+	}
+}
+
+func (i *InternerWithUint128Id[C]) getIndex(hash uint64) uint64 {
+	return i.indexMask & hash
+}
+
+func hash128(identity [16]byte) uint64 {
+	hi := binary.LittleEndian.Uint64(identity[:8])
+	lo := binary.LittleEndian.Uint64(identity[8:])
+	return mix64(hi ^ mix64(lo))
+}
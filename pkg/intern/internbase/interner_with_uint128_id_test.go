@@ -0,0 +1,55 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+type testUint128Converter struct {
+	identity [16]byte
+}
+
+func (c testUint128Converter) Identity() [16]byte {
+	return c.identity
+}
+
+func (c testUint128Converter) String() string {
+	return fmt.Sprintf("%x", c.identity)
+}
+
+func (c testUint128Converter) AppendString(dst []byte) []byte {
+	return fmt.Appendf(dst, "%x", c.identity)
+}
+
+// Demonstrate that different identities with the same hash are all interned
+func TestUint128IdShard_HashCollision(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	shard := newFixedIdShard[[16]byte, testUint128Converter](newController(0, 0), store, nil)
+
+	converters := []testUint128Converter{{identity: [16]byte{1}}, {identity: [16]byte{2}}, {identity: [16]byte{3}}}
+
+	interned := []string{}
+	for _, converter := range converters {
+		internedStr := shard.get(collidingHash, converter.identity, converter)
+		assert.Equal(t, converter.String(), internedStr)
+		interned = append(interned, internedStr)
+	}
+	assert.Equal(t, Stats{Interned: 3, HashCollision: 3}, shard.getStats())
+
+	for i, converter := range converters {
+		internedStr := shard.get(collidingHash, converter.identity, converter)
+		assert.Same(t, unsafe.StringData(interned[i]), unsafe.StringData(internedStr))
+	}
+	assert.Equal(t, Stats{Interned: 3, Returned: 3, HashCollision: 6}, shard.getStats())
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"sync/atomic"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// Strings returned by an interner may still be in use by callers long after
+// they are evicted, so evicted strings can't be freed immediately. Instead
+// they are retired, tagged with the epoch they were evicted in. The caller
+// advances the epoch, via AdvanceEpoch, to indicate that strings returned
+// before the previous call to AdvanceEpoch are no longer in use.
+//
+// A string retired in epoch E may have been returned to a caller during epoch
+// E, but never later. So once the epoch has reached E+2 every caller which
+// could be using the string has finished with it, and it can be freed.
+const gracePeriodEpochs = 2
+
+type retiredString struct {
+	ref   offheap.RefString
+	epoch uint64
+}
+
+// The number of accesses a shard buffers, while holding the read lock, before
+// they are applied to its EvictionPolicy
+const accessBufferSize = 64
+
+// Records accesses to interned strings made while holding a shard's read lock.
+// The EvictionPolicy isn't safe for concurrent use, so the accesses are
+// applied to it later, in the order they were recorded, while holding the
+// write lock.
+//
+// If the buffer fills up while the reader which filled it is waiting for the
+// write lock then further accesses are dropped. Eviction policies only
+// approximate recency and frequency, so losing a few accesses under heavy
+// contention is harmless.
+type accessBuffer struct {
+	keys [accessBufferSize]atomic.Uint64
+	next atomic.Int64
+}
+
+// Manages the eviction of strings from a single shard. Apart from
+// accessShared, all methods must be called while the shard is write locked.
+type shardEvictor struct {
+	// nil if eviction is disabled
+	policy   EvictionPolicy
+	accesses *accessBuffer
+	retired  []retiredString
+}
+
+func newShardEvictor(policy EvictionPolicy) shardEvictor {
+	if policy == nil {
+		return shardEvictor{}
+	}
+	return shardEvictor{
+		policy:   policy,
+		accesses: &accessBuffer{},
+	}
+}
+
+// Records an access to key while holding the read lock. Returns true if the
+// buffer has just been filled, in which case the caller must call drain while
+// holding the write lock.
+func (e *shardEvictor) accessShared(key uint64) bool {
+	if e.policy == nil {
+		return false
+	}
+
+	idx := e.accesses.next.Add(1) - 1
+	if idx >= accessBufferSize {
+		// The buffer is full, and about to be drained
+		return false
+	}
+	e.accesses.keys[idx].Store(key)
+	return idx == accessBufferSize-1
+}
+
+// Applies the accesses recorded while holding the read lock to the
+// EvictionPolicy.
+func (e *shardEvictor) drain() {
+	if e.policy == nil {
+		return
+	}
+
+	count := min(e.accesses.next.Load(), accessBufferSize)
+	for idx := range count {
+		e.policy.Access(e.accesses.keys[idx].Load())
+	}
+	e.accesses.next.Store(0)
+}
+
+func (e *shardEvictor) access(key uint64) {
+	if e.policy != nil {
+		e.drain()
+		e.policy.Access(key)
+	}
+}
+
+func (e *shardEvictor) added(key uint64) {
+	if e.policy != nil {
+		e.policy.Add(key)
+	}
+}
+
+// Makes room for str to be interned, evicting interned strings from this
+// shard if necessary. Returns true if str can be interned, in which case its
+// bytes have been accounted for in the controller.
+func (e *shardEvictor) makeRoom(controller *internController, interned internedStrings, key uint64, str string, stats *Stats, usage *ShardUsage) bool {
+	if e.policy == nil || !controller.canEverInternUsedBytes(str) {
+		return controller.canInternUsedBytes(str)
+	}
+	e.drain()
+
+	for !controller.canInternUsedBytes(str) {
+		victim, ok := e.policy.Victim(key)
+		if !ok {
+			return false
+		}
+
+		refString := interned.value(victim)
+		size := len(refString.Value())
+		if !controller.retire(size) {
+			// Too many evicted strings are waiting to be freed
+			return false
+		}
+
+		interned.remove(victim)
+		e.policy.Remove(victim)
+		e.retired = append(e.retired, retiredString{
+			ref:   refString,
+			epoch: controller.getEpoch(),
+		})
+		stats.Evicted++
+		usage.Entries--
+		usage.Bytes -= size
+	}
+
+	return true
+}
+
+// Frees every retired string whose grace period has ended.
+func (e *shardEvictor) collect(controller *internController, store *offheap.Store) {
+	epoch := controller.getEpoch()
+
+	kept := e.retired[:0]
+	for _, retired := range e.retired {
+		if retired.epoch+gracePeriodEpochs > epoch {
+			kept = append(kept, retired)
+			continue
+		}
+
+		size := len(retired.ref.Value())
+		offheap.FreeString(store, retired.ref)
+		controller.releaseRetired(size)
+	}
+	clear(e.retired[len(kept):])
+	e.retired = kept
+}
@@ -8,13 +8,51 @@ package internbase
 //
 // UsedBytes stat is global across all converters.
 //
+// RetiredBytes is the number of bytes used by evicted strings which have not
+// yet been freed. Like UsedBytes it is global across all converters.
+//
 // Total is sum across all shards of the fields in Stats.
 //
 // Shards holds the individual shard Stats.
+//
+// Usage holds the strings currently interned in each shard, in the same order
+// as Shards.
 type StatsSummary struct {
-	UsedBytes int
-	Total     Stats
-	Shards    []Stats
+	UsedBytes    int
+	RetiredBytes int
+	Total        Stats
+	Shards       []Stats
+	Usage        []ShardUsage
+}
+
+// Returns the number of entries in the fullest shard divided by the mean
+// number of entries per shard. A well distributed interner has a skew close to
+// 1, and a skew of len(Usage) means every entry is in a single shard. Returns
+// 0 if nothing is interned.
+func (s StatsSummary) ShardSkew() float64 {
+	total := 0
+	fullest := 0
+	for _, usage := range s.Usage {
+		total += usage.Entries
+		fullest = max(fullest, usage.Entries)
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(fullest) * float64(len(s.Usage)) / float64(total)
+}
+
+// The strings currently interned in a single shard.
+//
+// Entries is the number of interned strings.
+//
+// Bytes is the total length of the interned strings. Evicted strings which
+// have not yet been freed are not included.
+//
+// Unlike Stats these are not counters, and are not reset by ResetStats.
+type ShardUsage struct {
+	Entries int
+	Bytes   int
 }
 
 // The statistics capturing the runtime behaviour of the interner.
@@ -30,14 +68,58 @@ type StatsSummary struct {
 // UsedBytesExceeded indicates the number of strings not interned because the
 // global usedBytes limit was exceeded.
 //
-// HashCollision indicates the number of strings not interned because of a hash
-// collision.
+// HashCollision indicates the number of times a string was compared with a
+// different interned string with the same hash. Colliding strings are still
+// interned, so this is only a diagnostic.
+//
+// Evicted indicates the number of interned strings which have been evicted to
+// make room for new strings.
 type Stats struct {
 	Returned          int
 	Interned          int
 	MaxLenExceeded    int
 	UsedBytesExceeded int
 	HashCollision     int
+	Evicted           int
+}
+
+// Returns the number of strings requested, whether or not they were interned.
+func (s Stats) Lookups() int {
+	return s.Returned + s.Interned + s.MaxLenExceeded + s.UsedBytesExceeded
+}
+
+// Returns the fraction of lookups which returned a previously interned
+// string. Returns 0 if there have been no lookups.
+func (s Stats) HitRate() float64 {
+	lookups := s.Lookups()
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.Returned) / float64(lookups)
+}
+
+// Returns the difference between each of the counters in s and in earlier.
+func (s Stats) sub(earlier Stats) Stats {
+	return Stats{
+		Returned:          s.Returned - earlier.Returned,
+		Interned:          s.Interned - earlier.Interned,
+		MaxLenExceeded:    s.MaxLenExceeded - earlier.MaxLenExceeded,
+		UsedBytesExceeded: s.UsedBytesExceeded - earlier.UsedBytesExceeded,
+		HashCollision:     s.HashCollision - earlier.HashCollision,
+		Evicted:           s.Evicted - earlier.Evicted,
+	}
+}
+
+// Returns true if any counter in s is smaller than in earlier, which can only
+// happen if the counters were reset in between.
+func (s Stats) reset(earlier Stats) bool {
+	diff := s.sub(earlier)
+	return diff.Returned < 0 ||
+		diff.Interned < 0 ||
+		diff.MaxLenExceeded < 0 ||
+		diff.UsedBytesExceeded < 0 ||
+		diff.HashCollision < 0 ||
+		diff.Evicted < 0
 }
 
 func MakeSummary(shards []Stats, usedBytes int) StatsSummary {
@@ -49,6 +131,7 @@ func MakeSummary(shards []Stats, usedBytes int) StatsSummary {
 		total.MaxLenExceeded += shards[i].MaxLenExceeded
 		total.UsedBytesExceeded += shards[i].UsedBytesExceeded
 		total.HashCollision += shards[i].HashCollision
+		total.Evicted += shards[i].Evicted
 	}
 
 	return StatsSummary{
@@ -0,0 +1,107 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Anything which reports interner stats. Every interner, and SymbolTable,
+// is a StatsSource.
+type StatsSource interface {
+	GetStats() StatsSummary
+}
+
+// A StatsWindow tracks the activity of an interner over a rolling window.
+//
+// The counters in Stats accumulate for the life of an interner, so they can't
+// show whether an interner is effective now. Instead a StatsWindow keeps the
+// Total stats from its most recent calls to Sample, and reports how much each
+// counter has grown across them. The caller decides how long the window is by
+// how often Sample is called e.g.
+//
+//	window := internbase.NewStatsWindow(interner, 60)
+//
+//	for range time.Tick(time.Second) {
+//		window.Sample()
+//	}
+//
+// reports the hit rate over the last minute.
+//
+// If the interner's counters are reset by ResetStats, the samples taken
+// before the reset are discarded when the next sample is taken.
+//
+// A StatsWindow is safe for concurrent use.
+type StatsWindow struct {
+	source StatsSource
+	//
+	lock sync.Mutex
+	// A ring of the most recent samples, samples[next] is the oldest
+	// once the ring is full
+	samples []Stats
+	next    int
+	count   int
+}
+
+// Construct a new StatsWindow over source, spanning the last intervals
+// intervals between calls to Sample.
+func NewStatsWindow(source StatsSource, intervals int) *StatsWindow {
+	if intervals < 1 {
+		panic(fmt.Errorf("stats window must span at least 1 interval, got %d", intervals))
+	}
+
+	return &StatsWindow{
+		source:  source,
+		samples: make([]Stats, intervals+1),
+	}
+}
+
+// Records the current stats of the interner, discarding the oldest sample if
+// the window is full.
+func (w *StatsWindow) Sample() {
+	total := w.source.GetStats().Total
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.count > 0 && total.reset(w.newest()) {
+		w.count = 0
+	}
+
+	w.samples[w.next] = total
+	w.next = (w.next + 1) % len(w.samples)
+	w.count = min(w.count+1, len(w.samples))
+}
+
+// Returns how much each counter has grown between the oldest and newest
+// samples in the window. Until Sample has been called twice every counter is
+// zero.
+func (w *StatsWindow) Delta() Stats {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.count < 2 {
+		return Stats{}
+	}
+	return w.newest().sub(w.oldest())
+}
+
+// Returns the fraction of lookups within the window which returned a
+// previously interned string. Returns 0 if there were no lookups within the
+// window.
+func (w *StatsWindow) HitRate() float64 {
+	return w.Delta().HitRate()
+}
+
+// Must be called while holding the lock, and with at least one sample.
+func (w *StatsWindow) newest() Stats {
+	return w.samples[(w.next+len(w.samples)-1)%len(w.samples)]
+}
+
+// Must be called while holding the lock, and with at least one sample.
+func (w *StatsWindow) oldest() Stats {
+	return w.samples[(w.next+len(w.samples)-w.count)%len(w.samples)]
+}
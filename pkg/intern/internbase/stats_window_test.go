@@ -0,0 +1,135 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Interns each of strs in table
+func internAll(table *SymbolTable, strs ...string) {
+	for _, str := range strs {
+		table.Intern(str)
+	}
+}
+
+func TestStatsWindow_Delta(t *testing.T) {
+	table := NewSymbolTable(Config{})
+	window := NewStatsWindow(table, 2)
+
+	// Activity before the first sample is outside the window
+	internAll(table, "a", "b")
+	window.Sample()
+	assert.Equal(t, Stats{}, window.Delta())
+	assert.Equal(t, 0.0, window.HitRate())
+
+	internAll(table, "a", "b", "c", "d")
+	window.Sample()
+	assert.Equal(t, Stats{Interned: 2, Returned: 2}, window.Delta())
+	assert.Equal(t, 0.5, window.HitRate())
+
+	internAll(table, "a", "b", "c", "d")
+	window.Sample()
+	assert.Equal(t, Stats{Interned: 2, Returned: 6}, window.Delta())
+	assert.Equal(t, 0.75, window.HitRate())
+
+	// The first interval has now left the window
+	internAll(table, "a", "b", "c", "d")
+	window.Sample()
+	assert.Equal(t, Stats{Returned: 8}, window.Delta())
+	assert.Equal(t, 1.0, window.HitRate())
+}
+
+// Demonstrate that samples taken before the counters are reset are discarded
+func TestStatsWindow_ResetStats(t *testing.T) {
+	table := NewSymbolTable(Config{})
+	window := NewStatsWindow(table, 4)
+
+	window.Sample()
+	internAll(table, "a", "b", "a", "b")
+	window.Sample()
+	assert.Equal(t, Stats{Interned: 2, Returned: 2}, window.Delta())
+
+	table.ResetStats()
+	assert.Equal(t, Stats{}, table.GetStats().Total)
+	window.Sample()
+	assert.Equal(t, Stats{}, window.Delta())
+
+	internAll(table, "a", "c")
+	window.Sample()
+	assert.Equal(t, Stats{Interned: 1, Returned: 1}, window.Delta())
+}
+
+func TestStatsWindow_InvalidIntervalsPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStatsWindow(NewSymbolTable(Config{}), 0)
+	})
+}
+
+// Demonstrate that the bytes and entries in each shard are tracked, and are
+// not reset along with the counters
+func TestStatsSummary_Usage(t *testing.T) {
+	table := NewSymbolTable(Config{Shards: 4})
+
+	for i := range 1000 {
+		table.Intern(strconv.Itoa(i))
+	}
+
+	// 10 strings of length 1, 90 of length 2 and 900 of length 3
+	expected := ShardUsage{Entries: 1000, Bytes: 10 + 180 + 2700}
+
+	summary := table.GetStats()
+	require.Len(t, summary.Usage, 4)
+	assert.Equal(t, expected, sumUsage(summary.Usage))
+	assert.Greater(t, summary.ShardSkew(), 1.0)
+	assert.Less(t, summary.ShardSkew(), 1.5)
+
+	table.ResetStats()
+	assert.Equal(t, expected, sumUsage(table.GetStats().Usage))
+}
+
+func sumUsage(usage []ShardUsage) ShardUsage {
+	total := ShardUsage{}
+	for _, shardUsage := range usage {
+		total.Entries += shardUsage.Entries
+		total.Bytes += shardUsage.Bytes
+	}
+	return total
+}
+
+func TestStatsSummary_ShardSkew(t *testing.T) {
+	assert.Equal(t, 0.0, StatsSummary{}.ShardSkew())
+	assert.Equal(t, 0.0, StatsSummary{Usage: make([]ShardUsage, 4)}.ShardSkew())
+	assert.Equal(t, 1.0, StatsSummary{Usage: []ShardUsage{{Entries: 5}, {Entries: 5}}}.ShardSkew())
+	assert.Equal(t, 4.0, StatsSummary{Usage: []ShardUsage{{Entries: 5}, {}, {}, {}}}.ShardSkew())
+}
+
+func TestPublishExpvar(t *testing.T) {
+	table := NewSymbolTable(Config{Shards: 2})
+	window := NewStatsWindow(table, 1)
+
+	internAll(table, "a", "b")
+	window.Sample()
+	internAll(table, "a", "b", "c", "d")
+	window.Sample()
+
+	PublishExpvar("TestPublishExpvar", window)
+
+	published := ExpvarStats{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("TestPublishExpvar").String()), &published))
+	assert.Equal(t, NewExpvarStats(window), published)
+
+	assert.Equal(t, Stats{Interned: 4, Returned: 2}, published.Total)
+	assert.Equal(t, 2.0/6.0, published.HitRate)
+	assert.Equal(t, Stats{Interned: 2, Returned: 2}, published.Window)
+	assert.Equal(t, 0.5, published.WindowHitRate)
+	assert.Len(t, published.Usage, 2)
+}
@@ -0,0 +1,359 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	xxhash "github.com/cespare/xxhash/v2"
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A SymbolTable assigns a uint32 id to each distinct string interned in it,
+// and can retrieve the string for any assigned id.
+//
+// Ids are dense, the first string interned is assigned 0, the next 1 and so
+// on. Ids are stable, once assigned an id always identifies the same string
+// for the life of the SymbolTable. Because of this a SymbolTable can't be
+// configured with an eviction policy.
+//
+// The strings, and the indexes used to find them, are all stored in an
+// offheap.Store.
+type SymbolTable struct {
+	indexMask  uint64
+	controller *internController
+	store      *offheap.Store
+	ids        *symbolIds
+	shards     []symbolTableShard
+}
+
+// Construct a new SymbolTable with the provided config.
+//
+// Panics if config has an Eviction policy.
+func NewSymbolTable(config Config) *SymbolTable {
+	if config.Eviction != nil {
+		panic(fmt.Errorf("symbol table cannot be configured with an eviction policy, ids must be stable"))
+	}
+
+	controller := newController(config.getMaxLen(), config.getMaxBytes())
+	store := config.getStore()
+	shardCount := config.getShards()
+	ids := newSymbolIds(store)
+
+	shards := make([]symbolTableShard, shardCount)
+	for i := range shards {
+		shards[i] = newSymbolTableShard(controller, store, ids)
+	}
+
+	return &SymbolTable{
+		indexMask:  uint64(shardCount - 1),
+		controller: controller,
+		store:      store,
+		ids:        ids,
+		shards:     shards,
+	}
+}
+
+// Returns the id for str, assigning a new id if str has not been interned
+// before.
+//
+// If str can't be interned, because it is longer than MaxLen or because
+// MaxBytes has been reached, then false is returned.
+func (t *SymbolTable) Intern(str string) (uint32, bool) {
+	return t.InternBytes(unsafe.Slice(unsafe.StringData(str), len(str)))
+}
+
+// Returns the id for the string value of bytes, assigning a new id if it has
+// not been interned before.
+//
+// If the string can't be interned, because it is longer than MaxLen or
+// because MaxBytes has been reached, then false is returned.
+func (t *SymbolTable) InternBytes(bytes []byte) (uint32, bool) {
+	hash := xxhash.Sum64(bytes)
+	idx := hash & t.indexMask
+	return t.shards[idx].intern(hash, bytes)
+}
+
+// Returns the string identified by id. Lookup never takes a lock, so it
+// doesn't contend with concurrent calls to Intern.
+//
+// Panics if id has not been assigned.
+func (t *SymbolTable) Lookup(id uint32) string {
+	return t.ids.get(id)
+}
+
+// Returns the number of ids assigned.
+func (t *SymbolTable) Len() int {
+	return t.ids.len()
+}
+
+// Frees every interned string, and the indexes used to find them. After this
+// call returns the SymbolTable, and every string returned by Lookup, must
+// never be used again.
+func (t *SymbolTable) Free() {
+	for idx := range t.shards {
+		t.shards[idx].free()
+	}
+	t.ids.free()
+}
+
+// Retrieves the summarised stats for interned strings
+func (t *SymbolTable) GetStats() StatsSummary {
+	shards := make([]Stats, 0, len(t.shards))
+	usage := make([]ShardUsage, 0, len(t.shards))
+	for idx := range t.shards {
+		shards = append(shards, t.shards[idx].getStats())
+		usage = append(usage, t.shards[idx].getUsage())
+	}
+	summary := MakeSummary(shards, t.controller.getUsedBytes())
+	summary.Usage = usage
+	return summary
+}
+
+// Resets every counter in Stats to zero. The ShardUsage in subsequent
+// summaries is unaffected.
+func (t *SymbolTable) ResetStats() {
+	for idx := range t.shards {
+		t.shards[idx].resetStats()
+	}
+}
+
+// The number of ids held in each chunk of symbolIds
+const symbolIdsChunkSize = 1 << 12
+
+// The strings of a SymbolTable, indexed by id. The strings are held in
+// fixed size offheap chunks, so assigning new ids never moves existing
+// entries.
+//
+// Ids are only ever appended, so strings can be retrieved without taking a
+// lock. A new id's string is written before count is incremented, and a new
+// chunk is published in the chunks directory before count reaches it. A
+// reader which loads count can therefore safely read any id below it.
+type symbolIds struct {
+	store *offheap.Store
+	// Serialises the assignment of new ids, never taken by readers
+	lock   sync.Mutex
+	chunks atomic.Pointer[[]offheap.RefSlice[offheap.RefString]]
+	count  atomic.Int64
+}
+
+func newSymbolIds(store *offheap.Store) *symbolIds {
+	ids := &symbolIds{
+		store: store,
+	}
+	ids.chunks.Store(&[]offheap.RefSlice[offheap.RefString]{})
+	return ids
+}
+
+// Assigns the next id to refString.
+func (s *symbolIds) add(refString offheap.RefString) uint32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := s.count.Load()
+	if id > math.MaxUint32 {
+		panic(fmt.Errorf("symbol table is full, %d ids have been assigned", id))
+	}
+
+	chunks := *s.chunks.Load()
+	if id%symbolIdsChunkSize == 0 {
+		// Readers holding the old directory never read past its
+		// length, so appending may safely share its backing array
+		chunk := offheap.AllocSlice[offheap.RefString](s.store, symbolIdsChunkSize, symbolIdsChunkSize)
+		chunks = append(chunks, chunk)
+		s.chunks.Store(&chunks)
+	}
+
+	chunks[id/symbolIdsChunkSize].Value()[id%symbolIdsChunkSize] = refString
+	s.count.Store(id + 1)
+	return uint32(id)
+}
+
+// Returns the string identified by id.
+func (s *symbolIds) get(id uint32) string {
+	count := s.count.Load()
+	if int64(id) >= count {
+		panic(fmt.Errorf("symbol id %d has not been assigned, %d ids have been assigned", id, count))
+	}
+
+	chunks := *s.chunks.Load()
+	refString := chunks[id/symbolIdsChunkSize].Value()[id%symbolIdsChunkSize]
+	return refString.Value()
+}
+
+func (s *symbolIds) len() int {
+	return int(s.count.Load())
+}
+
+// Frees every string, and the chunks holding them.
+func (s *symbolIds) free() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	count := s.count.Load()
+	for idx, chunk := range *s.chunks.Load() {
+		strs := chunk.Value()
+		if remaining := count - int64(idx*symbolIdsChunkSize); remaining < symbolIdsChunkSize {
+			strs = strs[:remaining]
+		}
+		for _, refString := range strs {
+			offheap.FreeString(s.store, refString)
+		}
+		offheap.FreeSlice(s.store, chunk)
+	}
+	s.chunks.Store(&[]offheap.RefSlice[offheap.RefString]{})
+	s.count.Store(0)
+}
+
+type symbolTableShard struct {
+	controller *internController
+	store      *offheap.Store
+	ids        *symbolIds
+	//
+	lock    sync.RWMutex
+	symbols table[symbol]
+	stats   Stats
+	usage   ShardUsage
+	// Count ids returned, and their hash collisions, while holding the
+	// read lock
+	sharedReturned      atomic.Int64
+	sharedHashCollision atomic.Int64
+}
+
+// An interned string's id, along with the hash of the string. Like
+// probingMap, strings with colliding hashes are resolved by linear probing
+// over the keys. Ids are never removed so no tombstones are needed.
+type symbol struct {
+	hash uint64
+	id   uint32
+}
+
+func newSymbolTableShard(controller *internController, store *offheap.Store, ids *symbolIds) symbolTableShard {
+	return symbolTableShard{
+		controller: controller,
+		store:      store,
+		ids:        ids,
+		//
+		symbols: newTable[symbol](store),
+	}
+}
+
+func (s *symbolTableShard) intern(hash uint64, bytes []byte) (uint32, bool) {
+	unsafeStr := unsafe.String(unsafe.SliceData(bytes), len(bytes))
+
+	if id, ok := s.internShared(hash, unsafeStr); ok {
+		return id, true
+	}
+	return s.internExclusive(hash, bytes, unsafeStr)
+}
+
+// Looks up an already assigned id while holding only the read lock, so that
+// concurrent lookups don't block each other.
+func (s *symbolTableShard) internShared(hash uint64, unsafeStr string) (uint32, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, id, found, collisions := s.find(hash, unsafeStr)
+	if !found {
+		return 0, false
+	}
+	s.sharedHashCollision.Add(int64(collisions))
+	s.sharedReturned.Add(1)
+	return id, true
+}
+
+func (s *symbolTableShard) internExclusive(hash uint64, bytes []byte, unsafeStr string) (uint32, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, id, found, collisions := s.find(hash, unsafeStr)
+	s.stats.HashCollision += collisions
+
+	if found {
+		s.stats.Returned++
+		return id, true
+	}
+
+	if !s.controller.canInternMaxLen(unsafeStr) {
+		s.stats.MaxLenExceeded++
+		return 0, false
+	}
+
+	if !s.controller.canInternUsedBytes(unsafeStr) {
+		s.stats.UsedBytesExceeded++
+		return 0, false
+	}
+
+	id = s.ids.add(offheap.AllocStringFromBytes(s.store, bytes))
+	s.symbols.put(key, symbol{hash: hash, id: id})
+
+	s.stats.Interned++
+	s.usage.Entries++
+	s.usage.Bytes += len(bytes)
+	return id, true
+}
+
+// Searches for str, whose hash is hash.
+//
+// If str is found its key and id are returned, with found set to true.
+// Otherwise the key where str should be inserted is returned.
+//
+// The number of different strings found with exactly the same hash as str is
+// also returned.
+func (s *symbolTableShard) find(hash uint64, str string) (key uint64, id uint32, found bool, collisions int) {
+	for key = hash; ; key++ {
+		sym, ok := s.symbols.get(key)
+		if !ok {
+			return key, 0, false, collisions
+		}
+
+		if sym.hash != hash {
+			// This string was probed here from a different hash
+			continue
+		}
+
+		if s.ids.get(sym.id) == str {
+			return key, sym.id, true, collisions
+		}
+		collisions++
+	}
+}
+
+func (s *symbolTableShard) free() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.symbols.free()
+}
+
+func (s *symbolTableShard) getStats() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := s.stats
+	stats.Returned += int(s.sharedReturned.Load())
+	stats.HashCollision += int(s.sharedHashCollision.Load())
+	return stats
+}
+
+func (s *symbolTableShard) getUsage() ShardUsage {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.usage
+}
+
+func (s *symbolTableShard) resetStats() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stats = Stats{}
+	s.sharedReturned.Store(0)
+	s.sharedHashCollision.Store(0)
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Demonstrate that ids are dense, stable and can be looked up
+func TestSymbolTable_InternAndLookup(t *testing.T) {
+	table := NewSymbolTable(Config{Shards: 4})
+
+	// Enough strings to fill several chunks of ids
+	const count = symbolIdsChunkSize*3 + 10
+
+	for i := range count {
+		id, ok := table.Intern(strconv.Itoa(i))
+		require.True(t, ok)
+		assert.Equal(t, uint32(i), id)
+	}
+	assert.Equal(t, count, table.Len())
+
+	for i := range count {
+		id, ok := table.InternBytes([]byte(strconv.Itoa(i)))
+		require.True(t, ok)
+		assert.Equal(t, uint32(i), id)
+		assert.Equal(t, strconv.Itoa(i), table.Lookup(id))
+	}
+
+	assert.Equal(t, Stats{Interned: count, Returned: count}, table.GetStats().Total)
+}
+
+func TestSymbolTable_EmptyString(t *testing.T) {
+	table := NewSymbolTable(Config{})
+
+	id, ok := table.Intern("")
+	assert.True(t, ok)
+	assert.Equal(t, "", table.Lookup(id))
+
+	id2, ok := table.InternBytes(nil)
+	assert.True(t, ok)
+	assert.Equal(t, id, id2)
+}
+
+func TestSymbolTable_MaxLen(t *testing.T) {
+	table := NewSymbolTable(Config{MaxLen: 3})
+
+	_, ok := table.Intern("long")
+	assert.False(t, ok)
+	assert.Equal(t, 0, table.Len())
+	assert.Equal(t, Stats{MaxLenExceeded: 1}, table.GetStats().Total)
+}
+
+func TestSymbolTable_MaxBytes(t *testing.T) {
+	table := NewSymbolTable(Config{MaxBytes: 4})
+
+	_, ok := table.Intern("abc")
+	assert.True(t, ok)
+
+	_, ok = table.Intern("de")
+	assert.False(t, ok)
+
+	// Already interned strings are still found
+	id, ok := table.Intern("abc")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(0), id)
+
+	assert.Equal(t, Stats{Interned: 1, Returned: 1, UsedBytesExceeded: 1}, table.GetStats().Total)
+}
+
+func TestSymbolTable_LookupUnassignedPanics(t *testing.T) {
+	table := NewSymbolTable(Config{})
+	table.Intern("a")
+
+	assert.Panics(t, func() { table.Lookup(1) })
+}
+
+func TestSymbolTable_EvictionPanics(t *testing.T) {
+	assert.Panics(t, func() { NewSymbolTable(Config{Eviction: NewClockPolicy}) })
+}
+
+// Demonstrate that concurrently interning the same strings assigns each
+// string exactly one id
+func TestSymbolTable_Concurrent(t *testing.T) {
+	table := NewSymbolTable(Config{})
+
+	const goroutines = 8
+	const count = 10_000
+
+	results := make([][]uint32, goroutines)
+	wg := sync.WaitGroup{}
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range count {
+				id, _ := table.Intern(strconv.Itoa(i))
+				results[g] = append(results[g], id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, count, table.Len())
+	for _, ids := range results {
+		assert.Equal(t, results[0], ids)
+	}
+	for i, id := range results[0] {
+		assert.Equal(t, strconv.Itoa(i), table.Lookup(id))
+	}
+}
+
+// Demonstrate that strings can be looked up while new ids are being assigned
+func TestSymbolTable_ConcurrentLookup(t *testing.T) {
+	table := NewSymbolTable(Config{})
+
+	// Enough strings to fill several chunks of ids
+	const count = symbolIdsChunkSize*3 + 10
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range count {
+			table.Intern(strconv.Itoa(i))
+		}
+	}()
+
+	for {
+		assigned := table.Len()
+		for id := range assigned {
+			require.Equal(t, strconv.Itoa(id), table.Lookup(uint32(id)))
+		}
+		if assigned == count {
+			break
+		}
+	}
+	<-done
+
+	// Lookups don't wait for the assignment of new ids
+	table.ids.lock.Lock()
+	assert.Equal(t, "0", table.Lookup(0))
+	table.ids.lock.Unlock()
+}
+
+// Demonstrate that freeing the symbol table releases all of its allocations
+func TestSymbolTable_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	table := NewSymbolTable(Config{Shards: 4, Store: store})
+	for i := range symbolIdsChunkSize*3 + 10 {
+		table.Intern(strconv.Itoa(i))
+	}
+
+	table.Free()
+
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
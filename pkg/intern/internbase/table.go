@@ -0,0 +1,156 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The number of slots in a newly created table
+const initialTableSize = 16
+
+// An open-addressed hash table, keyed by uint64, whose slots are allocated in
+// an offheap.Store. The values must not contain any pointers.
+//
+// Unlike a Go map the table is invisible to the garbage collector, so a shard
+// can index a very large number of interned strings without adding to the
+// cost of garbage collection.
+//
+// Collisions are resolved by linear probing. Removed slots are filled by
+// shifting later slots in the probe sequence backwards, so no tombstones are
+// needed.
+type table[V any] struct {
+	store *offheap.Store
+	slots offheap.RefSlice[tableSlot[V]]
+	mask  uint64
+	count int
+}
+
+type tableSlot[V any] struct {
+	key   uint64
+	value V
+	used  bool
+}
+
+func newTable[V any](store *offheap.Store) table[V] {
+	return table[V]{
+		store: store,
+		slots: allocTableSlots[V](store, initialTableSize),
+		mask:  initialTableSize - 1,
+	}
+}
+
+func allocTableSlots[V any](store *offheap.Store, size int) offheap.RefSlice[tableSlot[V]] {
+	slots := offheap.AllocSlice[tableSlot[V]](store, size, size)
+	clear(slots.Value())
+	return slots
+}
+
+// Returns the value for key, and true, if key is in the table. Otherwise the
+// zero value and false are returned.
+func (t *table[V]) get(key uint64) (V, bool) {
+	slots := t.slots.Value()
+	for idx := t.home(key); ; idx = (idx + 1) & t.mask {
+		slot := &slots[idx]
+		if !slot.used {
+			var zero V
+			return zero, false
+		}
+		if slot.key == key {
+			return slot.value, true
+		}
+	}
+}
+
+// Returns true if key is in the table.
+func (t *table[V]) contains(key uint64) bool {
+	_, ok := t.get(key)
+	return ok
+}
+
+// Sets the value for key, replacing any existing value.
+func (t *table[V]) put(key uint64, value V) {
+	if (t.count+1)*4 > len(t.slots.Value())*3 {
+		t.grow()
+	}
+
+	slots := t.slots.Value()
+	for idx := t.home(key); ; idx = (idx + 1) & t.mask {
+		slot := &slots[idx]
+		if !slot.used {
+			*slot = tableSlot[V]{key: key, value: value, used: true}
+			t.count++
+			return
+		}
+		if slot.key == key {
+			slot.value = value
+			return
+		}
+	}
+}
+
+// Removes key from the table, if it is present.
+func (t *table[V]) remove(key uint64) {
+	slots := t.slots.Value()
+
+	idx := t.home(key)
+	for {
+		slot := &slots[idx]
+		if !slot.used {
+			// key is not in the table
+			return
+		}
+		if slot.key == key {
+			break
+		}
+		idx = (idx + 1) & t.mask
+	}
+
+	// Shift later slots in the probe sequence back to fill the gap
+	empty := idx
+	for next := (empty + 1) & t.mask; slots[next].used; next = (next + 1) & t.mask {
+		home := t.home(slots[next].key)
+		// The slot at next can be moved into the empty slot, unless its
+		// home lies cyclically in (empty, next]
+		if (next-home)&t.mask >= (next-empty)&t.mask {
+			slots[empty] = slots[next]
+			empty = next
+		}
+	}
+	slots[empty] = tableSlot[V]{}
+	t.count--
+}
+
+// Returns the number of keys in the table.
+func (t *table[V]) len() int {
+	return t.count
+}
+
+// Frees the table's slots. After this call returns the table must never be
+// used again.
+func (t *table[V]) free() {
+	offheap.FreeSlice(t.store, t.slots)
+}
+
+func (t *table[V]) home(key uint64) uint64 {
+	return mix64(key) & t.mask
+}
+
+// Doubles the number of slots in the table
+func (t *table[V]) grow() {
+	oldSlots := t.slots
+	size := len(oldSlots.Value()) * 2
+
+	t.slots = allocTableSlots[V](t.store, size)
+	t.mask = uint64(size - 1)
+	t.count = 0
+
+	for _, slot := range oldSlots.Value() {
+		if slot.used {
+			t.put(slot.key, slot.value)
+		}
+	}
+	offheap.FreeSlice(t.store, oldSlots)
+}
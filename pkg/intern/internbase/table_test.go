@@ -0,0 +1,98 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package internbase
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+// Demonstrate that the table behaves like a map through many puts, removes and
+// resizes
+func TestTable_MatchesMap(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	tbl := newTable[uint64](store)
+	expected := map[uint64]uint64{}
+
+	r := rand.New(rand.NewSource(1))
+	for i := range 100_000 {
+		// A small key space forces plenty of overwrites and removes
+		key := uint64(r.Intn(5_000))
+		if r.Intn(3) == 0 {
+			tbl.remove(key)
+			delete(expected, key)
+		} else {
+			tbl.put(key, uint64(i))
+			expected[key] = uint64(i)
+		}
+	}
+
+	assert.Equal(t, len(expected), tbl.len())
+	for key := range uint64(5_000) {
+		value, ok := tbl.get(key)
+		expectedValue, expectedOk := expected[key]
+		assert.Equal(t, expectedOk, ok)
+		assert.Equal(t, expectedValue, value)
+	}
+}
+
+// Demonstrate that removing a key from the middle of a run of colliding keys
+// leaves the rest of the run reachable
+func TestTable_RemoveFromRun(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	tbl := newTable[uint64](store)
+
+	// Find keys which all share the same home slot
+	keys := []uint64{}
+	home := tbl.home(0)
+	for key := uint64(0); len(keys) < 4; key++ {
+		if tbl.home(key) == home {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		tbl.put(key, key+1)
+	}
+
+	tbl.remove(keys[1])
+	assert.False(t, tbl.contains(keys[1]))
+	for _, key := range []uint64{keys[0], keys[2], keys[3]} {
+		value, ok := tbl.get(key)
+		assert.True(t, ok)
+		assert.Equal(t, key+1, value)
+	}
+	assert.Equal(t, 3, tbl.len())
+}
+
+// Demonstrate that growing the table frees the old slots
+func TestTable_GrowFreesSlots(t *testing.T) {
+	store := offheap.New()
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	tbl := newTable[uint64](store)
+	for key := range uint64(10_000) {
+		tbl.put(key, key)
+	}
+
+	live := 0
+	for _, stats := range store.Stats() {
+		live += stats.Live
+	}
+	assert.Equal(t, 1, live)
+}
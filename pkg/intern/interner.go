@@ -4,9 +4,33 @@
 
 package intern
 
-import "github.com/fmstephe/memorymanager/pkg/intern/internbase"
+import (
+	"unsafe"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
 
 type Interner[T any] interface {
 	Get(t T) string
+	// Returns the same string as Get, as a []byte. The []byte is a view of
+	// the string's memory and must not be modified.
+	GetBytes(t T) []byte
+	// Appends the same string returned by Get to dst. If dst has enough
+	// capacity then no heap allocation is made, even if the string was not
+	// already interned.
+	AppendTo(dst []byte, t T) []byte
 	GetStats() internbase.StatsSummary
+	// Resets the counters reported by GetStats to zero. The interned
+	// strings, and the ShardUsage reporting them, are unaffected.
+	ResetStats()
+	// Frees evicted strings whose grace period has ended. Calling this
+	// indicates that strings returned by Get before the previous call to
+	// AdvanceEpoch are no longer in use. Has no effect if the interner is
+	// not configured with an eviction policy.
+	AdvanceEpoch()
+}
+
+// Returns a read-only view of the bytes of str.
+func stringBytes(str string) []byte {
+	return unsafe.Slice(unsafe.StringData(str), len(str))
 }
@@ -36,6 +36,26 @@ func DoTestGenericInterner_Interned[T any](t *testing.T, interner Interner[T], v
 	expectedStats = internbase.Stats{Interned: 1, Returned: 1}
 	stats = interner.GetStats()
 	assert.Equal(t, expectedStats, stats.Total)
+	assert.Equal(t, internbase.ShardUsage{Entries: 1, Bytes: len(strVal)}, sumShardUsage(stats.Usage))
+
+	// Resetting the stats leaves the string interned
+	interner.ResetStats()
+	stats = interner.GetStats()
+	assert.Equal(t, internbase.Stats{}, stats.Total)
+	assert.Equal(t, internbase.ShardUsage{Entries: 1, Bytes: len(strVal)}, sumShardUsage(stats.Usage))
+
+	internedVal3 := interner.Get(val)
+	assert.Same(t, unsafe.StringData(internedVal), unsafe.StringData(internedVal3))
+	assert.Equal(t, internbase.Stats{Returned: 1}, interner.GetStats().Total)
+}
+
+func sumShardUsage(usage []internbase.ShardUsage) internbase.ShardUsage {
+	total := internbase.ShardUsage{}
+	for _, shardUsage := range usage {
+		total.Entries += shardUsage.Entries
+		total.Bytes += shardUsage.Bytes
+	}
+	return total
 }
 
 func DoTestGenericInterner_NotInternedMaxLen[T any](t *testing.T, interner Interner[T], val T, strVal string) {
@@ -175,6 +195,18 @@ func TestGenericInterner_Complex(t *testing.T) {
 }
 */
 
+// Interners built on InternerWithCompositeId pool the buffers passed to
+// Identity, so they only avoid allocating while the pool keeps its buffers.
+// The race detector makes sync.Pool drop buffers at random, so their
+// allocations can't be tested in race builds.
+func skipPooledAllocationsUnderRace(t *testing.T) {
+	t.Helper()
+
+	if raceEnabled {
+		t.Skip("sync.Pool drops buffers at random under the race detector")
+	}
+}
+
 // Assert that getting a string, where the value has already been interned,
 // does not allocate
 func DoTestGenericInterner_NoAllocations[T any](t *testing.T, interner Interner[T], vals []T) {
@@ -193,3 +225,34 @@ func DoTestGenericInterner_NoAllocations[T any](t *testing.T, interner Interner[
 	// allocate
 	assert.Equal(t, 0.0, avgAllocs)
 }
+
+// Assert that AppendTo and GetBytes produce the same string as Get, and that
+// AppendTo does not allocate whether or not the value has already been
+// interned
+func DoTestGenericInterner_AppendTo[T any](t *testing.T, interner Interner[T], vals []T) {
+	t.Helper()
+
+	dst := make([]byte, 0, 1024)
+
+	// The first run of AllocsPerRun is a warm up, so each value is
+	// appended for the first time during a measured run
+	next := 0
+	avgAllocs := testing.AllocsPerRun(len(vals)-1, func() {
+		dst = interner.AppendTo(dst[:0], vals[next])
+		next++
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+
+	next = 0
+	avgAllocs = testing.AllocsPerRun(len(vals)-1, func() {
+		dst = interner.AppendTo(dst[:0], vals[next])
+		next++
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+
+	for _, val := range vals {
+		expected := interner.Get(val)
+		assert.Equal(t, "prefix"+expected, string(interner.AppendTo([]byte("prefix"), val)))
+		assert.Equal(t, expected, string(interner.GetBytes(val)))
+	}
+}
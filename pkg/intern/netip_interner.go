@@ -0,0 +1,168 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+type addrInterner struct {
+	interner internbase.InternerWithUint128Id[addrConverter]
+}
+
+// Interns netip.Addr values formatted using netip.Addr.String().
+//
+// IPv4 and IPv6 addresses are identified by their 16 byte representation.
+// This representation can't distinguish an IPv4 address from the same address
+// mapped into IPv6, or carry an IPv6 zone. So IPv4-mapped IPv6 addresses,
+// addresses with a zone, and the zero Addr are formatted without being
+// interned.
+func NewAddrInterner(config internbase.Config) Interner[netip.Addr] {
+	return &addrInterner{
+		interner: internbase.NewInternerWithUint128Id[addrConverter](config),
+	}
+}
+
+func (i *addrInterner) Get(value netip.Addr) string {
+	if !isInternableAddr(value) {
+		return value.String()
+	}
+	return i.interner.Get(newAddrConverter(value))
+}
+
+func (i *addrInterner) GetBytes(value netip.Addr) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *addrInterner) AppendTo(dst []byte, value netip.Addr) []byte {
+	if !isInternableAddr(value) {
+		return value.AppendTo(dst)
+	}
+	return i.interner.AppendTo(dst, newAddrConverter(value))
+}
+
+// Returns true if value can be identified by its 16 byte representation
+func isInternableAddr(value netip.Addr) bool {
+	return value.IsValid() && !value.Is4In6() && value.Zone() == ""
+}
+
+func (i *addrInterner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *addrInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *addrInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithUint128Id = addrConverter{}
+
+// A converter for netip.Addr values. Here the identity is the 16 byte
+// representation of the address.
+type addrConverter struct {
+	value netip.Addr
+}
+
+func newAddrConverter(value netip.Addr) addrConverter {
+	return addrConverter{
+		value: value,
+	}
+}
+
+func (c addrConverter) Identity() [16]byte {
+	return c.value.As16()
+}
+
+func (c addrConverter) String() string {
+	return c.value.String()
+}
+
+func (c addrConverter) AppendString(dst []byte) []byte {
+	return c.value.AppendTo(dst)
+}
+
+type addrPortInterner struct {
+	interner internbase.InternerWithCompositeId[addrPortConverter]
+}
+
+// Interns netip.AddrPort values formatted using netip.AddrPort.String().
+func NewAddrPortInterner(config internbase.Config) Interner[netip.AddrPort] {
+	return &addrPortInterner{
+		interner: internbase.NewInternerWithCompositeId[addrPortConverter](config),
+	}
+}
+
+func (i *addrPortInterner) Get(value netip.AddrPort) string {
+	return i.interner.Get(newAddrPortConverter(value))
+}
+
+func (i *addrPortInterner) GetBytes(value netip.AddrPort) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *addrPortInterner) AppendTo(dst []byte, value netip.AddrPort) []byte {
+	return i.interner.AppendTo(dst, newAddrPortConverter(value))
+}
+
+func (i *addrPortInterner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *addrPortInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *addrPortInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithCompositeId = addrPortConverter{}
+
+// A converter for netip.AddrPort values. The identity is the address family,
+// the 16 byte representation of the address, the port and then the address's
+// zone.
+type addrPortConverter struct {
+	value netip.AddrPort
+}
+
+func newAddrPortConverter(value netip.AddrPort) addrPortConverter {
+	return addrPortConverter{
+		value: value,
+	}
+}
+
+func (c addrPortConverter) Identity(buf []byte) []byte {
+	addr := c.value.Addr()
+
+	// Distinguishes IPv4 addresses from IPv4-mapped IPv6 addresses, and
+	// from the zero Addr
+	family := byte(0)
+	switch {
+	case addr.Is4():
+		family = 4
+	case addr.Is6():
+		family = 6
+	}
+
+	addr16 := addr.As16()
+	buf = append(buf, family)
+	buf = append(buf, addr16[:]...)
+	buf = binary.BigEndian.AppendUint16(buf, c.value.Port())
+	return append(buf, addr.Zone()...)
+}
+
+func (c addrPortConverter) String() string {
+	return c.value.String()
+}
+
+func (c addrPortConverter) AppendString(dst []byte) []byte {
+	return c.value.AppendTo(dst)
+}
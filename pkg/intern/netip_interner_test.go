@@ -0,0 +1,146 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddrInterner_Interned(t *testing.T) {
+	for _, addr := range []string{"192.168.0.1", "2001:db8::1"} {
+		interner := NewAddrInterner(internbase.Config{MaxLen: 64, MaxBytes: 1024})
+		val := netip.MustParseAddr(addr)
+
+		DoTestGenericInterner_Interned(t, interner, val, addr)
+	}
+}
+
+func TestAddrInterner_NotInternedMaxLen(t *testing.T) {
+	interner := NewAddrInterner(internbase.Config{MaxLen: 3, MaxBytes: 1024})
+	val := netip.MustParseAddr("192.168.0.1")
+
+	DoTestGenericInterner_NotInternedMaxLen(t, interner, val, val.String())
+}
+
+func TestAddrInterner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewAddrInterner(internbase.Config{MaxLen: 64, MaxBytes: 3})
+	val := netip.MustParseAddr("192.168.0.1")
+
+	DoTestGenericInterner_NotInternedMaxBytes(t, interner, val, val.String())
+}
+
+// Demonstrate that addresses which share a 16 byte representation with
+// another address are still formatted correctly
+func TestAddrInterner_Ambiguous(t *testing.T) {
+	interner := NewAddrInterner(internbase.Config{})
+
+	for _, addr := range []netip.Addr{
+		netip.MustParseAddr("1.2.3.4"),
+		netip.MustParseAddr("::ffff:1.2.3.4"),
+		netip.MustParseAddr("fe80::1"),
+		netip.MustParseAddr("fe80::1%eth0"),
+		{},
+	} {
+		assert.Equal(t, addr.String(), interner.Get(addr))
+	}
+	assert.Equal(t, internbase.Stats{Interned: 2}, interner.GetStats().Total)
+}
+
+func TestAddrInterner_NoAllocations(t *testing.T) {
+	interner := NewAddrInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]netip.Addr, 10_000)
+	for i := range vals {
+		vals[i] = netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestAddrPortInterner_Interned(t *testing.T) {
+	interner := NewAddrPortInterner(internbase.Config{MaxLen: 64, MaxBytes: 1024})
+	val := netip.MustParseAddrPort("192.168.0.1:8080")
+
+	DoTestGenericInterner_Interned(t, interner, val, val.String())
+}
+
+func TestAddrPortInterner_NotInternedMaxLen(t *testing.T) {
+	interner := NewAddrPortInterner(internbase.Config{MaxLen: 3, MaxBytes: 1024})
+	val := netip.MustParseAddrPort("192.168.0.1:8080")
+
+	DoTestGenericInterner_NotInternedMaxLen(t, interner, val, val.String())
+}
+
+func TestAddrPortInterner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewAddrPortInterner(internbase.Config{MaxLen: 64, MaxBytes: 3})
+	val := netip.MustParseAddrPort("192.168.0.1:8080")
+
+	DoTestGenericInterner_NotInternedMaxBytes(t, interner, val, val.String())
+}
+
+// Demonstrate that the same address with different ports, and addresses
+// which share a 16 byte representation, are formatted correctly
+func TestAddrPortInterner_Distinct(t *testing.T) {
+	interner := NewAddrPortInterner(internbase.Config{})
+
+	vals := []netip.AddrPort{
+		netip.MustParseAddrPort("1.2.3.4:80"),
+		netip.MustParseAddrPort("1.2.3.4:443"),
+		netip.MustParseAddrPort("[::ffff:1.2.3.4]:80"),
+		netip.MustParseAddrPort("[2001:db8::1]:80"),
+		netip.MustParseAddrPort("[fe80::1%eth0]:80"),
+		netip.MustParseAddrPort("[fe80::1%eth1]:80"),
+		{},
+	}
+	for _, val := range vals {
+		assert.Equal(t, val.String(), interner.Get(val))
+	}
+	for _, val := range vals {
+		assert.Equal(t, val.String(), interner.Get(val))
+	}
+	assert.Equal(t, internbase.Stats{Interned: len(vals), Returned: len(vals)}, interner.GetStats().Total)
+}
+
+func TestAddrPortInterner_NoAllocations(t *testing.T) {
+	skipPooledAllocationsUnderRace(t)
+
+	interner := NewAddrPortInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]netip.AddrPort, 10_000)
+	for i := range vals {
+		vals[i] = netip.AddrPortFrom(netip.MustParseAddr("2001:db8::1"), uint16(i))
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestAddrInterner_AppendTo(t *testing.T) {
+	interner := NewAddrInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]netip.Addr, 10_000)
+	for i := range vals {
+		vals[i] = netip.AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 14: byte(i >> 8), 15: byte(i)})
+	}
+	// Addresses which are formatted without being interned
+	vals[0] = netip.MustParseAddr("::ffff:1.2.3.4")
+	vals[1] = netip.MustParseAddr("fe80::1%eth0")
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
+
+func TestAddrPortInterner_AppendTo(t *testing.T) {
+	interner := NewAddrPortInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]netip.AddrPort, 10_000)
+	for i := range vals {
+		vals[i] = netip.AddrPortFrom(netip.MustParseAddr("2001:db8::1"), uint16(i))
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -0,0 +1,9 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+//go:build !race
+
+package intern
+
+const raceEnabled = false
@@ -24,10 +24,26 @@ func (i *stringInterner) Get(str string) string {
 	return i.interner.Get(newStringConverter(str))
 }
 
+func (i *stringInterner) GetBytes(str string) []byte {
+	return stringBytes(i.Get(str))
+}
+
+func (i *stringInterner) AppendTo(dst []byte, str string) []byte {
+	return i.interner.AppendTo(dst, newStringConverter(str))
+}
+
 func (i *stringInterner) GetStats() internbase.StatsSummary {
 	return i.interner.GetStats()
 }
 
+func (i *stringInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *stringInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
 var _ internbase.ConverterWithBytesId = stringConverter{}
 
 type stringConverter struct {
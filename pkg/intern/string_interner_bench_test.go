@@ -75,3 +75,14 @@ func BenchmarkStringInterner_AllInterned10K(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkStringInterner_ParallelInterned10K(b *testing.B) {
+	interner := NewStringInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	strings := make([]string, 10_000)
+	for i := range strings {
+		strings[i] = strconv.Itoa(i)
+	}
+
+	benchmarkParallelInterned(b, interner, strings)
+}
@@ -45,10 +45,10 @@ func TestStringInterner_NotInternedMaxBytes(t *testing.T) {
 	DoTestGenericInterner_NotInternedMaxBytes(t, interner, str, str)
 }
 
-func TestStringInterner_NotInternedHashCollision(t *testing.T) {
-	// Right now I don't know of any xxhash collisions When we find a
-	// colliding pair of a manageable sized strings we can complete this
-	// test
+func TestStringInterner_HashCollision(t *testing.T) {
+	// Right now I don't know of any xxhash collisions. Colliding strings
+	// are tested in the internbase package, by calling the interner shard
+	// directly with a chosen hash.
 }
 
 // This test demonstrates that the interner can handle passing through a
@@ -233,3 +233,14 @@ func TestStringInterner_NoAllocations(t *testing.T) {
 
 	DoTestGenericInterner_NoAllocations(t, interner, strings)
 }
+
+func TestStringInterner_AppendTo(t *testing.T) {
+	interner := NewStringInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([]string, 10_000)
+	for i := range vals {
+		vals[i] = strconv.Itoa(i)
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -5,56 +5,160 @@
 package intern
 
 import (
+	"encoding/binary"
 	"time"
 
 	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
 )
 
 type timeInterner struct {
-	interner internbase.InternerWithUint64Id[timeConverter]
-	format   string
+	interner internbase.InternerWithCompositeId[timeConverter]
+	layout   *timeLayout
 }
 
+// Interns time.Time values formatted using format.
+//
+// Times are identified by their wall clock time, truncated to the finest unit
+// that format renders, so e.g. with the format "2006-01-02T15:04:05" every
+// time within the same second shares a single interned string. If format
+// doesn't render the time's zone then times with the same wall clock time in
+// different locations also share a single interned string.
 func NewTimeInterner(config internbase.Config, format string) Interner[time.Time] {
 	return &timeInterner{
-		interner: internbase.NewInternerWithUint64Id[timeConverter](config),
-		format:   format,
+		interner: internbase.NewInternerWithCompositeId[timeConverter](config),
+		layout:   newTimeLayout(format),
 	}
 }
 
 func (i *timeInterner) Get(value time.Time) string {
-	return i.interner.Get(newTimeConverter(value, i.format))
+	return i.interner.Get(newTimeConverter(value, i.layout))
+}
+
+func (i *timeInterner) GetBytes(value time.Time) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *timeInterner) AppendTo(dst []byte, value time.Time) []byte {
+	return i.interner.AppendTo(dst, newTimeConverter(value, i.layout))
 }
 
 func (i *timeInterner) GetStats() internbase.StatsSummary {
 	return i.interner.GetStats()
 }
 
-var _ internbase.ConverterWithUint64Id = timeConverter{}
+func (i *timeInterner) ResetStats() {
+	i.interner.ResetStats()
+}
 
-// Converter for time.Time. The int64 UnixNano() value is used to uniquely
-// identify each time.Time. If time.Time values are used with different time
-// zones but which have the same nanosecond values, this converter will
-// consider them to be the same and may produce unexpected output.
-//
-// Having a converter/interner per timezone is currently the best way to handle
-// this.
+func (i *timeInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+// The precisions a time can be truncated to, coarsest first. Coarser units,
+// like months, don't have a fixed duration so can't be truncated to.
+var timePrecisions = []time.Duration{
+	24 * time.Hour,
+	time.Hour,
+	time.Minute,
+	time.Second,
+	100 * time.Millisecond,
+	10 * time.Millisecond,
+	time.Millisecond,
+	100 * time.Microsecond,
+	10 * time.Microsecond,
+	time.Microsecond,
+	100 * time.Nanosecond,
+	10 * time.Nanosecond,
+}
+
+// Describes which parts of a time are rendered by a format.
+type timeLayout struct {
+	format string
+	// The finest unit rendered by format
+	precision time.Duration
+	// True if format renders the time's zone name or offset
+	rendersZone bool
+}
+
+// Determines which parts of a time format renders, by formatting reference
+// times which differ only in a single part.
+func newTimeLayout(format string) *timeLayout {
+	// Every field finer than a day is at its maximum value, so truncating
+	// to any precision changes every field finer than that precision
+	ref := time.Date(2009, time.November, 10, 23, 59, 59, 999_999_999, time.UTC)
+
+	precision := time.Nanosecond
+	for _, p := range timePrecisions {
+		if ref.Format(format) == ref.Truncate(p).Format(format) {
+			precision = p
+			break
+		}
+	}
+
+	zoned := time.Date(2009, time.November, 10, 23, 59, 59, 0, time.FixedZone("AAA", 3600))
+	otherOffset := time.Date(2009, time.November, 10, 23, 59, 59, 0, time.FixedZone("AAA", 7200))
+	otherName := time.Date(2009, time.November, 10, 23, 59, 59, 0, time.FixedZone("BBB", 3600))
+	rendersZone := zoned.Format(format) != otherOffset.Format(format) || zoned.Format(format) != otherName.Format(format)
+
+	return &timeLayout{
+		format:      format,
+		precision:   precision,
+		rendersZone: rendersZone,
+	}
+}
+
+var _ internbase.ConverterWithCompositeId = timeConverter{}
+
+// Converter for time.Time. The identity is the time's wall clock time,
+// truncated to the precision of the layout. If the layout renders the time's
+// zone the zone's offset and name are included in the identity.
 type timeConverter struct {
 	value  time.Time
-	format string
+	layout *timeLayout
 }
 
-func newTimeConverter(value time.Time, format string) timeConverter {
+func newTimeConverter(value time.Time, layout *timeLayout) timeConverter {
 	return timeConverter{
 		value:  value,
-		format: format,
+		layout: layout,
 	}
 }
 
-func (c timeConverter) Identity() uint64 {
-	return uint64(c.value.UnixNano())
+func (c timeConverter) Identity(buf []byte) []byte {
+	name, offset := c.value.Zone()
+	wallSeconds := c.value.Unix() + int64(offset)
+	nanos := int64(c.value.Nanosecond())
+
+	precision := c.layout.precision
+	if precision >= time.Second {
+		wallSeconds = floorMultiple(wallSeconds, int64(precision/time.Second))
+		nanos = 0
+	} else {
+		nanos = floorMultiple(nanos, int64(precision))
+	}
+
+	buf = binary.BigEndian.AppendUint64(buf, uint64(wallSeconds))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(nanos))
+	if c.layout.rendersZone {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(offset))
+		buf = append(buf, name...)
+	}
+	return buf
 }
 
 func (c timeConverter) String() string {
-	return c.value.Format(c.format)
+	return c.value.Format(c.layout.format)
+}
+
+func (c timeConverter) AppendString(dst []byte) []byte {
+	return c.value.AppendFormat(dst, c.layout.format)
+}
+
+// Returns the largest multiple of unit which is <= value
+func floorMultiple(value, unit int64) int64 {
+	remainder := value % unit
+	if remainder < 0 {
+		remainder += unit
+	}
+	return value - remainder
 }
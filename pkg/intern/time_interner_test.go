@@ -5,10 +5,13 @@
 package intern
 
 import (
+	"math/rand"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTimeInterner_Interned(t *testing.T) {
@@ -128,6 +131,8 @@ func TestTimeInterner_Complex(t *testing.T) {
 // Assert that getting a string, where the value has already been interned,
 // does not allocate
 func TestTimeInterner_NoAllocations(t *testing.T) {
+	skipPooledAllocationsUnderRace(t)
+
 	interner := NewTimeInterner(internbase.Config{MaxLen: 0, MaxBytes: 0}, time.RFC1123)
 
 	timestamps := make([]time.Time, 10_000)
@@ -138,3 +143,97 @@ func TestTimeInterner_NoAllocations(t *testing.T) {
 
 	DoTestGenericInterner_NoAllocations(t, interner, timestamps)
 }
+
+func TestTimeLayout(t *testing.T) {
+	for _, tc := range []struct {
+		format      string
+		precision   time.Duration
+		rendersZone bool
+	}{
+		{time.RFC3339Nano, time.Nanosecond, true},
+		{time.RFC3339, time.Second, true},
+		{time.RFC1123, time.Second, true},
+		{time.StampMilli, time.Millisecond, false},
+		{"2006-01-02T15:04:05", time.Second, false},
+		{"2006-01-02T15:04:05.0", 100 * time.Millisecond, false},
+		{"15:04", time.Minute, false},
+		{"3PM", time.Hour, false},
+		{time.DateOnly, 24 * time.Hour, false},
+		{"Jan 2 MST", 24 * time.Hour, true},
+	} {
+		layout := newTimeLayout(tc.format)
+		assert.Equal(t, tc.precision, layout.precision, tc.format)
+		assert.Equal(t, tc.rendersZone, layout.rendersZone, tc.format)
+	}
+}
+
+// Demonstrate that times within the same second, and in different zones with
+// the same wall clock time, share an interned string when the format renders
+// neither
+func TestTimeInterner_SharedAcrossZones(t *testing.T) {
+	interner := NewTimeInterner(internbase.Config{}, "2006-01-02T15:04:05")
+
+	first := interner.Get(time.Date(2024, time.March, 1, 12, 30, 15, 0, time.UTC))
+	assert.Equal(t, "2024-03-01T12:30:15", first)
+
+	for _, timestamp := range []time.Time{
+		time.Date(2024, time.March, 1, 12, 30, 15, 999_999_999, time.UTC),
+		time.Date(2024, time.March, 1, 12, 30, 15, 1, time.FixedZone("IST", 19800)),
+		time.Date(2024, time.March, 1, 12, 30, 15, 500, time.FixedZone("", -3600)),
+	} {
+		internedTime := interner.Get(timestamp)
+		assert.Same(t, unsafe.StringData(first), unsafe.StringData(internedTime))
+	}
+	assert.Equal(t, internbase.Stats{Interned: 1, Returned: 3}, interner.GetStats().Total)
+}
+
+// Demonstrate that times with the same wall clock time in different zones are
+// interned separately when the format renders the zone
+func TestTimeInterner_ZoneRendered(t *testing.T) {
+	interner := NewTimeInterner(internbase.Config{}, time.RFC1123Z)
+
+	utc := time.Date(2024, time.March, 1, 12, 30, 15, 0, time.UTC)
+	ist := time.Date(2024, time.March, 1, 12, 30, 15, 0, time.FixedZone("IST", 19800))
+
+	assert.Equal(t, utc.Format(time.RFC1123Z), interner.Get(utc))
+	assert.Equal(t, ist.Format(time.RFC1123Z), interner.Get(ist))
+	assert.Equal(t, internbase.Stats{Interned: 2}, interner.GetStats().Total)
+}
+
+// Demonstrate that interned times are always formatted correctly across a
+// range of formats, zones and times
+func TestTimeInterner_Formats(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	zones := []*time.Location{
+		time.UTC,
+		time.FixedZone("IST", 19800),
+		time.FixedZone("NST", -12600),
+		time.FixedZone("LMT", 1172),
+		time.FixedZone("", -3600),
+	}
+
+	for _, format := range []string{time.RFC3339Nano, time.RFC1123, time.Kitchen, time.StampMicro, time.DateTime, "2006-01-02 3PM MST"} {
+		interner := NewTimeInterner(internbase.Config{}, format)
+
+		for range 10_000 {
+			// Times within a few days of each other, so that values
+			// are frequently shared, both before and after 1970
+			base := []int64{-1_000_000_000, 1_700_000_000}[r.Intn(2)]
+			nanos := r.Int63n(int64(72 * time.Hour))
+			timestamp := time.Unix(base, nanos).In(zones[r.Intn(len(zones))])
+			assert.Equal(t, timestamp.Format(format), interner.Get(timestamp), format)
+		}
+	}
+}
+
+func TestTimeInterner_AppendTo(t *testing.T) {
+	interner := NewTimeInterner(internbase.Config{MaxLen: 0, MaxBytes: 0}, time.RFC3339Nano)
+
+	vals := make([]time.Time, 10_000)
+	for i := range vals {
+		vals[i] = time.Unix(1_700_000_000, int64(i)*1_001)
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"strconv"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+type uint64Interner struct {
+	interner internbase.InternerWithUint64Id[uint64Converter]
+	base     int
+}
+
+// Interns uint64 values formatted in base e.g. base 16 for hexadecimal.
+func NewUint64Interner(config internbase.Config, base int) Interner[uint64] {
+	return &uint64Interner{
+		interner: internbase.NewInternerWithUint64Id[uint64Converter](config),
+		base:     base,
+	}
+}
+
+func (i *uint64Interner) Get(value uint64) string {
+	return i.interner.Get(newUint64Converter(value, i.base))
+}
+
+func (i *uint64Interner) GetBytes(value uint64) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *uint64Interner) AppendTo(dst []byte, value uint64) []byte {
+	return i.interner.AppendTo(dst, newUint64Converter(value, i.base))
+}
+
+func (i *uint64Interner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *uint64Interner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *uint64Interner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithUint64Id = uint64Converter{}
+
+// A converter for uint64 values. Here the identity is just the value itself.
+type uint64Converter struct {
+	value uint64
+	base  int
+}
+
+func newUint64Converter(value uint64, base int) uint64Converter {
+	return uint64Converter{
+		value: value,
+		base:  base,
+	}
+}
+
+func (c uint64Converter) Identity() uint64 {
+	return c.value
+}
+
+func (c uint64Converter) String() string {
+	return strconv.FormatUint(c.value, c.base)
+}
+
+func (c uint64Converter) AppendString(dst []byte) []byte {
+	return strconv.AppendUint(dst, c.value, c.base)
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+func TestUint64Interner_Interned(t *testing.T) {
+	interner := NewUint64Interner(internbase.Config{MaxLen: 64, MaxBytes: 1024}, 16)
+	val := uint64(0xdeadbeef)
+	internedVal := strconv.FormatUint(val, 16)
+
+	DoTestGenericInterner_Interned(t, interner, val, internedVal)
+}
+
+func TestUint64Interner_NotInternedMaxLen(t *testing.T) {
+	interner := NewUint64Interner(internbase.Config{MaxLen: 3, MaxBytes: 1024}, 16)
+	val := uint64(0xdeadbeef)
+	internedVal := strconv.FormatUint(val, 16)
+
+	DoTestGenericInterner_NotInternedMaxLen(t, interner, val, internedVal)
+}
+
+func TestUint64Interner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewUint64Interner(internbase.Config{MaxLen: 64, MaxBytes: 3}, 16)
+	val := uint64(0xdeadbeef)
+	internedVal := strconv.FormatUint(val, 16)
+
+	DoTestGenericInterner_NotInternedMaxBytes(t, interner, val, internedVal)
+}
+
+// Assert that getting a string, where the value has already been interned,
+// does not allocate
+func TestUint64Interner_NoAllocations(t *testing.T) {
+	interner := NewUint64Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 16)
+
+	vals := make([]uint64, 10_000)
+	for i := range vals {
+		vals[i] = uint64(i) << 40
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestUint64Interner_AppendTo(t *testing.T) {
+	interner := NewUint64Interner(internbase.Config{MaxLen: 0, MaxBytes: 0}, 16)
+
+	vals := make([]uint64, 10_000)
+	for i := range vals {
+		vals[i] = uint64(i) << 40
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
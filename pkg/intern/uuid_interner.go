@@ -0,0 +1,81 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"encoding/hex"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+type uuidInterner struct {
+	interner internbase.InternerWithUint128Id[uuidConverter]
+}
+
+// Interns 16 byte UUIDs formatted in the canonical lower case form
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func NewUUIDInterner(config internbase.Config) Interner[[16]byte] {
+	return &uuidInterner{
+		interner: internbase.NewInternerWithUint128Id[uuidConverter](config),
+	}
+}
+
+func (i *uuidInterner) Get(value [16]byte) string {
+	return i.interner.Get(newUUIDConverter(value))
+}
+
+func (i *uuidInterner) GetBytes(value [16]byte) []byte {
+	return stringBytes(i.Get(value))
+}
+
+func (i *uuidInterner) AppendTo(dst []byte, value [16]byte) []byte {
+	return i.interner.AppendTo(dst, newUUIDConverter(value))
+}
+
+func (i *uuidInterner) GetStats() internbase.StatsSummary {
+	return i.interner.GetStats()
+}
+
+func (i *uuidInterner) ResetStats() {
+	i.interner.ResetStats()
+}
+
+func (i *uuidInterner) AdvanceEpoch() {
+	i.interner.AdvanceEpoch()
+}
+
+var _ internbase.ConverterWithUint128Id = uuidConverter{}
+
+// A converter for UUIDs. Here the identity is just the UUID itself.
+type uuidConverter struct {
+	value [16]byte
+}
+
+func newUUIDConverter(value [16]byte) uuidConverter {
+	return uuidConverter{
+		value: value,
+	}
+}
+
+func (c uuidConverter) Identity() [16]byte {
+	return c.value
+}
+
+func (c uuidConverter) String() string {
+	buf := [36]byte{}
+	return string(c.AppendString(buf[:0]))
+}
+
+func (c uuidConverter) AppendString(dst []byte) []byte {
+	dst = hex.AppendEncode(dst, c.value[0:4])
+	dst = append(dst, '-')
+	dst = hex.AppendEncode(dst, c.value[4:6])
+	dst = append(dst, '-')
+	dst = hex.AppendEncode(dst, c.value[6:8])
+	dst = append(dst, '-')
+	dst = hex.AppendEncode(dst, c.value[8:10])
+	dst = append(dst, '-')
+	return hex.AppendEncode(dst, c.value[10:16])
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package intern
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/pkg/intern/internbase"
+)
+
+func TestUUIDInterner_Interned(t *testing.T) {
+	interner := NewUUIDInterner(internbase.Config{MaxLen: 64, MaxBytes: 1024})
+	val := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	internedVal := "123e4567-e89b-12d3-a456-426614174000"
+
+	DoTestGenericInterner_Interned(t, interner, val, internedVal)
+}
+
+func TestUUIDInterner_NotInternedMaxLen(t *testing.T) {
+	interner := NewUUIDInterner(internbase.Config{MaxLen: 3, MaxBytes: 1024})
+	val := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	internedVal := "123e4567-e89b-12d3-a456-426614174000"
+
+	DoTestGenericInterner_NotInternedMaxLen(t, interner, val, internedVal)
+}
+
+func TestUUIDInterner_NotInternedMaxBytes(t *testing.T) {
+	interner := NewUUIDInterner(internbase.Config{MaxLen: 64, MaxBytes: 3})
+	val := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	internedVal := "123e4567-e89b-12d3-a456-426614174000"
+
+	DoTestGenericInterner_NotInternedMaxBytes(t, interner, val, internedVal)
+}
+
+// Assert that getting a string, where the value has already been interned,
+// does not allocate
+func TestUUIDInterner_NoAllocations(t *testing.T) {
+	interner := NewUUIDInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([][16]byte, 10_000)
+	for i := range vals {
+		binary.BigEndian.PutUint64(vals[i][8:], uint64(i))
+	}
+
+	DoTestGenericInterner_NoAllocations(t, interner, vals)
+}
+
+func TestUUIDInterner_AppendTo(t *testing.T) {
+	interner := NewUUIDInterner(internbase.Config{MaxLen: 0, MaxBytes: 0})
+
+	vals := make([][16]byte, 10_000)
+	for i := range vals {
+		binary.BigEndian.PutUint64(vals[i][8:], uint64(i))
+	}
+
+	DoTestGenericInterner_AppendTo(t, interner, vals)
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The keyhash package hashes comparable keys using their in-memory
+// representation.
+//
+// This is only correct if keys which are equal, as compared by ==, always
+// have the same in-memory representation. CheckKey reports key types for which
+// this doesn't hold.
+package keyhash
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	xxhash "github.com/cespare/xxhash/v2"
+)
+
+// Returns an error if two equal values of K can have different in-memory
+// representations. This rules out
+//
+//   - structs containing padding, or blank (_) fields, whose bytes are
+//     ignored by ==
+//   - floating point and complex numbers, because 0.0 == -0.0
+//   - strings, interfaces, pointers and channels, because their memory holds
+//     pointers rather than the values compared
+//
+// Bools, integers and arrays and structs built from them are accepted.
+func CheckKey[K comparable]() error {
+	paths := []string{}
+	searchForNonCanonical(reflect.TypeFor[K](), "", &paths)
+	if len(paths) != 0 {
+		return fmt.Errorf("key type %s can't be hashed by its in-memory representation, found: %v", reflect.TypeFor[K](), paths)
+	}
+	return nil
+}
+
+func searchForNonCanonical(t reflect.Type, path string, paths *[]string) {
+	switch t.Kind() {
+	case reflect.Bool:
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+
+	case reflect.Array:
+		size := strconv.Itoa(t.Len())
+		searchForNonCanonical(t.Elem(), path+"["+size+"]", paths)
+
+	case reflect.Struct:
+		fieldsSize := uintptr(0)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Name == "_" {
+				*paths = append(*paths, path+"."+f.Name+"<blank field>")
+			}
+			fieldsSize += f.Type.Size()
+			searchForNonCanonical(f.Type, path+"."+f.Name, paths)
+		}
+		if fieldsSize != t.Size() {
+			*paths = append(*paths, path+"<padding>")
+		}
+
+	default:
+		*paths = append(*paths, path+"<"+t.String()+">")
+	}
+}
+
+// Returns the hash of the in-memory representation of key. K must be
+// accepted by CheckKey.
+func Hash[K comparable](key *K) uint64 {
+	bytes := unsafe.Slice((*byte)(unsafe.Pointer(key)), unsafe.Sizeof(*key))
+	return xxhash.Sum64(bytes)
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package keyhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type packedKey struct {
+	a int64
+	b [4]int32
+	c [8]byte
+}
+
+type paddedKey struct {
+	a int64
+	b byte
+}
+
+type nestedPaddedKey struct {
+	a [2]paddedKey
+}
+
+type blankFieldKey struct {
+	a int32
+	_ int32
+}
+
+type floatKey struct {
+	a int64
+	b float64
+}
+
+func TestCheckKey(t *testing.T) {
+	assert.NoError(t, CheckKey[int64]())
+	assert.NoError(t, CheckKey[bool]())
+	assert.NoError(t, CheckKey[uintptr]())
+	assert.NoError(t, CheckKey[[16]byte]())
+	assert.NoError(t, CheckKey[packedKey]())
+	assert.NoError(t, CheckKey[struct{}]())
+
+	assert.Error(t, CheckKey[paddedKey]())
+	assert.Error(t, CheckKey[nestedPaddedKey]())
+	assert.Error(t, CheckKey[blankFieldKey]())
+	assert.Error(t, CheckKey[float64]())
+	assert.Error(t, CheckKey[complex64]())
+	assert.Error(t, CheckKey[floatKey]())
+	assert.Error(t, CheckKey[string]())
+	assert.Error(t, CheckKey[*int]())
+	assert.Error(t, CheckKey[any]())
+}
+
+// Equal keys have equal hashes
+func TestHash(t *testing.T) {
+	a := packedKey{a: 1, b: [4]int32{2, 3, 4, 5}, c: [8]byte{6}}
+	b := a
+	assert.Equal(t, Hash(&a), Hash(&b))
+
+	b.c[7] = 1
+	assert.NotEqual(t, Hash(&a), Hash(&b))
+}
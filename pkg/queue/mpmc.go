@@ -0,0 +1,246 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package queue
+
+import (
+	"sync/atomic"
+
+	"github.com/fmstephe/flib/fmath"
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A slot in an MPMC queue. The sequence number tells producers and consumers
+// whether the slot is ready to be written, or read, for a given position.
+//
+// For the slot at position pos:
+// sequence == pos indicates the slot is empty and may be written
+// sequence == pos+1 indicates the slot is full and may be read
+type mpmcSlot[T any] struct {
+	sequence atomic.Uint64
+	value    T
+}
+
+type paddedIndex struct {
+	value atomic.Uint64
+	_     [cacheLineSize - 8]byte
+}
+
+// A bounded multi-producer multi-consumer queue of T.
+//
+// Any number of goroutines may enqueue and dequeue concurrently. Positions
+// are claimed with a compare-and-swap on the head or tail index, and each
+// slot carries a sequence number, so producers and consumers never wait on a
+// lock.
+//
+// T may not contain pointers. Creating an MPMC with an element type which
+// contains pointers will panic.
+type MPMC[T any] struct {
+	_    [cacheLineSize]byte
+	tail paddedIndex
+	head paddedIndex
+	// Immutable fields
+	mask   uint64
+	store  *offheap.Store
+	slots  offheap.RefSlice[mpmcSlot[T]]
+	closed atomic.Bool
+}
+
+// Creates a new empty MPMC queue whose slots will be allocated in store.
+// capacity is rounded up to the next power of two. Panics if capacity is not
+// positive.
+func NewMPMC[T any](store *offheap.Store, capacity int) *MPMC[T] {
+	checkCapacity(capacity)
+	capacity = int(fmath.NxtPowerOfTwo(int64(capacity)))
+
+	slots := offheap.AllocSlice[mpmcSlot[T]](store, capacity, capacity)
+	for i, slice := 0, slots.Value(); i < len(slice); i++ {
+		slice[i].sequence.Store(uint64(i))
+	}
+
+	return &MPMC[T]{
+		mask:  uint64(capacity - 1),
+		store: store,
+		slots: slots,
+	}
+}
+
+// Adds value to the queue, if there is space. Returns true if value was
+// enqueued, false if the queue was full.
+//
+// Panics if the queue has been closed.
+func (q *MPMC[T]) TryEnqueue(value T) bool {
+	checkOpen(&q.closed)
+
+	slots := q.slots.Value()
+	pos := q.tail.value.Load()
+	for {
+		slot := &slots[pos&q.mask]
+		diff := int64(slot.sequence.Load() - pos)
+		switch {
+		case diff == 0:
+			// The slot is empty, try to claim it
+			if q.tail.value.CompareAndSwap(pos, pos+1) {
+				slot.value = value
+				slot.sequence.Store(pos + 1)
+				return true
+			}
+			pos = q.tail.value.Load()
+		case diff < 0:
+			// The slot still holds the element from the previous
+			// lap, the queue is full
+			return false
+		default:
+			// Another producer claimed this position
+			pos = q.tail.value.Load()
+		}
+	}
+}
+
+// Adds value to the queue, waiting until there is space.
+//
+// Panics if the queue has been closed.
+func (q *MPMC[T]) Enqueue(value T) {
+	b := backoff{}
+	for !q.TryEnqueue(value) {
+		b.wait()
+	}
+}
+
+// Adds as many of values to the queue as there is space for, in order.
+// Returns the number of values enqueued.
+//
+// Each value is enqueued individually, so values enqueued by concurrent
+// producers may be interleaved with these values.
+//
+// Panics if the queue has been closed.
+func (q *MPMC[T]) TryEnqueueBatch(values []T) int {
+	for i := range values {
+		if !q.TryEnqueue(values[i]) {
+			return i
+		}
+	}
+	return len(values)
+}
+
+// Adds all of values to the queue, in order, waiting for space as needed.
+//
+// Each value is enqueued individually, so values enqueued by concurrent
+// producers may be interleaved with these values.
+//
+// Panics if the queue has been closed.
+func (q *MPMC[T]) EnqueueBatch(values []T) {
+	for i := range values {
+		q.Enqueue(values[i])
+	}
+}
+
+// Removes the oldest element from the queue and returns it, along with true.
+// If the queue is empty the zero value of T and false are returned.
+func (q *MPMC[T]) TryDequeue() (T, bool) {
+	slots := q.slots.Value()
+	pos := q.head.value.Load()
+	for {
+		slot := &slots[pos&q.mask]
+		diff := int64(slot.sequence.Load() - (pos + 1))
+		switch {
+		case diff == 0:
+			// The slot is full, try to claim it
+			if q.head.value.CompareAndSwap(pos, pos+1) {
+				value := slot.value
+				// Mark the slot empty for the next lap
+				slot.sequence.Store(pos + q.mask + 1)
+				return value, true
+			}
+			pos = q.head.value.Load()
+		case diff < 0:
+			// The slot has not been written, the queue is empty
+			var zero T
+			return zero, false
+		default:
+			// Another consumer claimed this position
+			pos = q.head.value.Load()
+		}
+	}
+}
+
+// Removes the oldest element from the queue and returns it, along with true,
+// waiting until an element is available. If the queue is closed, and empty,
+// the zero value of T and false are returned.
+func (q *MPMC[T]) Dequeue() (T, bool) {
+	b := backoff{}
+	for {
+		// Check closed before trying to dequeue, any elements enqueued
+		// before the queue was closed will be observed
+		closed := q.closed.Load()
+		if value, ok := q.TryDequeue(); ok || closed {
+			return value, ok
+		}
+		b.wait()
+	}
+}
+
+// Removes as many elements as are available, up to len(into), copying them
+// into into in order. Returns the number of elements dequeued.
+//
+// Each element is dequeued individually, so concurrent consumers may receive
+// elements interleaved with these elements.
+func (q *MPMC[T]) TryDequeueBatch(into []T) int {
+	for i := range into {
+		value, ok := q.TryDequeue()
+		if !ok {
+			return i
+		}
+		into[i] = value
+	}
+	return len(into)
+}
+
+// Removes up to len(into) elements, copying them into into in order, waiting
+// until at least one element is available. Returns the number of elements
+// dequeued. If the queue is closed, and empty, 0 is returned.
+func (q *MPMC[T]) DequeueBatch(into []T) int {
+	if len(into) == 0 {
+		return 0
+	}
+
+	value, ok := q.Dequeue()
+	if !ok {
+		return 0
+	}
+	into[0] = value
+	return 1 + q.TryDequeueBatch(into[1:])
+}
+
+// Closes the queue. Elements already in the queue can still be dequeued, but
+// no more elements may be enqueued.
+//
+// Producers which are concurrently enqueuing may, or may not, succeed. Close
+// should be called once all producers have finished.
+func (q *MPMC[T]) Close() {
+	q.closed.Store(true)
+}
+
+// Returns the number of elements in the queue. With concurrent producers and
+// consumers this value is approximate.
+func (q *MPMC[T]) Len() int {
+	head := q.head.value.Load()
+	tail := q.tail.value.Load()
+	if tail < head {
+		return 0
+	}
+	return int(tail - head)
+}
+
+// Returns the number of elements the queue can hold.
+func (q *MPMC[T]) Cap() int {
+	return int(q.mask + 1)
+}
+
+// Frees the queue's slots. Any elements still in the queue are discarded.
+// After this call returns the queue must never be used again.
+func (q *MPMC[T]) Free() {
+	offheap.FreeSlice(q.store, q.slots)
+	q.slots = offheap.RefSlice[mpmcSlot[T]]{}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package queue
+
+import (
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMPMC_TryEnqueueDequeue(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewMPMC[int](store, 8)
+
+	_, ok := q.TryDequeue()
+	assert.False(t, ok)
+
+	// Fill and drain the queue several times, so that we wrap around the
+	// slots
+	for lap := range 5 {
+		for i := range 8 {
+			require.True(t, q.TryEnqueue(lap*8+i))
+		}
+		require.False(t, q.TryEnqueue(-1))
+		require.Equal(t, 8, q.Len())
+
+		for i := range 8 {
+			value, ok := q.TryDequeue()
+			require.True(t, ok)
+			require.Equal(t, lap*8+i, value)
+		}
+		_, ok := q.TryDequeue()
+		require.False(t, ok)
+		require.Equal(t, 0, q.Len())
+	}
+}
+
+func TestMPMC_Batch(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewMPMC[int](store, 8)
+
+	// Only the first 8 values fit
+	assert.Equal(t, 8, q.TryEnqueueBatch([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+	assert.Equal(t, 0, q.TryEnqueueBatch([]int{10}))
+
+	into := make([]int, 5)
+	assert.Equal(t, 5, q.TryDequeueBatch(into))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, into)
+
+	assert.Equal(t, 5, q.TryEnqueueBatch([]int{8, 9, 10, 11, 12, 13}))
+
+	into = make([]int, 10)
+	assert.Equal(t, 8, q.DequeueBatch(into))
+	assert.Equal(t, []int{5, 6, 7, 8, 9, 10, 11, 12}, into[:8])
+
+	assert.Equal(t, 0, q.TryDequeueBatch(into))
+}
+
+func TestMPMC_Close(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewMPMC[int](store, 8)
+
+	q.Enqueue(1)
+	q.EnqueueBatch([]int{2, 3})
+	q.Close()
+
+	assert.Panics(t, func() { q.Enqueue(4) })
+	assert.Panics(t, func() { q.TryEnqueueBatch([]int{4}) })
+
+	// Elements enqueued before Close are still dequeued
+	value, ok := q.Dequeue()
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	into := make([]int, 4)
+	assert.Equal(t, 2, q.DequeueBatch(into))
+	assert.Equal(t, []int{2, 3}, into[:2])
+
+	// Blocking dequeues return immediately once the queue is drained
+	_, ok = q.Dequeue()
+	assert.False(t, ok)
+	assert.Equal(t, 0, q.DequeueBatch(into))
+}
+
+// Assert that enqueuing and dequeuing does not allocate
+func TestMPMC_NoAllocations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewMPMC[int64](store, 1024)
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		for i := range int64(1000) {
+			q.TryEnqueue(i)
+		}
+		for range 1000 {
+			q.TryDequeue()
+		}
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The queue package provides bounded FIFO queues whose slots are allocated in
+// an offheap.Store. This allows very large numbers of in-flight elements to be
+// buffered without impacting garbage collection.
+//
+// SPSC is a single-producer single-consumer queue. It is the fastest option,
+// but exactly one goroutine may enqueue and exactly one goroutine may dequeue.
+//
+// MPMC is a multi-producer multi-consumer queue. Any number of goroutines may
+// enqueue and dequeue concurrently.
+//
+// Both queues offer non-blocking operations, prefixed with Try, and blocking
+// operations which wait until space, or an element, is available. Blocking
+// waits spin briefly before yielding the processor, and then sleeping, so
+// they are best suited to queues which are rarely full or empty for long.
+//
+// A queue can be closed by a producer. Blocking dequeues on a closed queue
+// return once the queue has been drained, and enqueuing onto a closed queue
+// panics.
+package queue
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// The size, in bytes, of a cache line on the architectures we care about.
+// Indices written by different goroutines are padded to this size to avoid
+// false sharing.
+const cacheLineSize = 64
+
+func checkCapacity(capacity int) {
+	if capacity <= 0 {
+		panic(fmt.Errorf("queue capacity must be positive, got %d", capacity))
+	}
+}
+
+func checkOpen(closed *atomic.Bool) {
+	if closed.Load() {
+		panic(fmt.Errorf("cannot enqueue onto a closed queue"))
+	}
+}
+
+// The number of waits which spin, yielding the processor, before we start
+// sleeping
+const maxSpins = 64
+
+const sleepDuration = 10 * time.Microsecond
+
+// Controls how a blocking operation waits between attempts.
+type backoff struct {
+	spins int
+}
+
+func (b *backoff) wait() {
+	if b.spins < maxSpins {
+		b.spins++
+		runtime.Gosched()
+		return
+	}
+	time.Sleep(sleepDuration)
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package queue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+const valuesPerProducer = 10_000
+const producers = 8
+const consumers = 8
+
+// Demonstrate that many producers and consumers can share an MPMC queue. Every
+// value enqueued is dequeued exactly once, and the values from each producer
+// are dequeued in the order they were enqueued.
+// This test should be run with -race
+func TestMPMC_ProducersConsumers_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewMPMC[int64](store, 64)
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+
+	producing := sync.WaitGroup{}
+	for p := range int64(producers) {
+		producing.Add(1)
+		go func() {
+			defer producing.Done()
+			barrier.Wait()
+			// Encode the producer in the high bits of each value
+			for i := range int64(valuesPerProducer) {
+				q.Enqueue(p<<32 | i)
+			}
+		}()
+	}
+
+	results := make([][]int64, consumers)
+	consuming := sync.WaitGroup{}
+	for c := range consumers {
+		consuming.Add(1)
+		go func() {
+			defer consuming.Done()
+			barrier.Wait()
+			into := make([]int64, 16)
+			for {
+				n := q.DequeueBatch(into)
+				if n == 0 {
+					return
+				}
+				results[c] = append(results[c], into[:n]...)
+			}
+		}()
+	}
+
+	barrier.Done()
+	producing.Wait()
+	q.Close()
+	consuming.Wait()
+
+	seen := map[int64]bool{}
+	for _, result := range results {
+		// Each consumer observes each producer's values in order
+		last := map[int64]int64{}
+		for _, value := range result {
+			p, i := value>>32, value&0xFFFFFFFF
+			if prev, ok := last[p]; ok {
+				assert.Less(t, prev, i)
+			}
+			last[p] = i
+
+			assert.False(t, seen[value])
+			seen[value] = true
+		}
+	}
+	assert.Equal(t, producers*valuesPerProducer, len(seen))
+}
+
+// Demonstrate that a producer and consumer on different goroutines can share
+// an SPSC queue.
+// This test should be run with -race
+func TestSPSC_ProducerConsumer_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int64](store, 64)
+
+	go func() {
+		for i := range int64(valuesPerProducer) {
+			q.Enqueue(i)
+		}
+		q.Close()
+	}()
+
+	expected := int64(0)
+	for {
+		value, ok := q.Dequeue()
+		if !ok {
+			break
+		}
+		assert.Equal(t, expected, value)
+		expected++
+	}
+	assert.Equal(t, int64(valuesPerProducer), expected)
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package queue
+
+import (
+	"sync/atomic"
+
+	"github.com/fmstephe/flib/fmath"
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The fields owned by the producer. The producer also caches the last head it
+// observed, so it only needs to read the consumer's cache line when the queue
+// appears to be full.
+type producerIndex struct {
+	tail       atomic.Uint64
+	cachedHead uint64
+	_          [cacheLineSize - 16]byte
+}
+
+// The fields owned by the consumer. The consumer also caches the last tail it
+// observed, so it only needs to read the producer's cache line when the queue
+// appears to be empty.
+type consumerIndex struct {
+	head       atomic.Uint64
+	cachedTail uint64
+	_          [cacheLineSize - 16]byte
+}
+
+// A bounded single-producer single-consumer queue of T.
+//
+// Only one goroutine may call the enqueue methods, and only one goroutine may
+// call the dequeue methods. These may be different goroutines. Close may only
+// be called by the producer.
+//
+// T may not contain pointers. Creating an SPSC with an element type which
+// contains pointers will panic.
+type SPSC[T any] struct {
+	_        [cacheLineSize]byte
+	producer producerIndex
+	consumer consumerIndex
+	// Immutable fields
+	mask   uint64
+	store  *offheap.Store
+	slots  offheap.RefSlice[T]
+	closed atomic.Bool
+}
+
+// Creates a new empty SPSC queue whose slots will be allocated in store.
+// capacity is rounded up to the next power of two. Panics if capacity is not
+// positive.
+func NewSPSC[T any](store *offheap.Store, capacity int) *SPSC[T] {
+	checkCapacity(capacity)
+	capacity = int(fmath.NxtPowerOfTwo(int64(capacity)))
+
+	return &SPSC[T]{
+		mask:  uint64(capacity - 1),
+		store: store,
+		slots: offheap.AllocSlice[T](store, capacity, capacity),
+	}
+}
+
+// Adds value to the queue, if there is space. Returns true if value was
+// enqueued, false if the queue was full.
+//
+// Panics if the queue has been closed.
+func (q *SPSC[T]) TryEnqueue(value T) bool {
+	return q.TryEnqueueBatch([]T{value}) == 1
+}
+
+// Adds value to the queue, waiting until there is space.
+//
+// Panics if the queue has been closed.
+func (q *SPSC[T]) Enqueue(value T) {
+	q.EnqueueBatch([]T{value})
+}
+
+// Adds as many of values to the queue as there is space for, in order.
+// Returns the number of values enqueued.
+//
+// Panics if the queue has been closed.
+func (q *SPSC[T]) TryEnqueueBatch(values []T) int {
+	checkOpen(&q.closed)
+
+	tail := q.producer.tail.Load()
+	free := q.capacity() - (tail - q.producer.cachedHead)
+	if free < uint64(len(values)) {
+		// Refresh our view of the consumer's progress
+		q.producer.cachedHead = q.consumer.head.Load()
+		free = q.capacity() - (tail - q.producer.cachedHead)
+	}
+
+	count := min(free, uint64(len(values)))
+	slots := q.slots.Value()
+	for i := range count {
+		slots[(tail+i)&q.mask] = values[i]
+	}
+
+	// Publish all of the new elements to the consumer at once
+	q.producer.tail.Store(tail + count)
+	return int(count)
+}
+
+// Adds all of values to the queue, in order, waiting for space as needed.
+//
+// Panics if the queue has been closed.
+func (q *SPSC[T]) EnqueueBatch(values []T) {
+	b := backoff{}
+	for len(values) > 0 {
+		count := q.TryEnqueueBatch(values)
+		values = values[count:]
+		if count == 0 {
+			b.wait()
+		}
+	}
+}
+
+// Removes the oldest element from the queue and returns it, along with true.
+// If the queue is empty the zero value of T and false are returned.
+func (q *SPSC[T]) TryDequeue() (T, bool) {
+	var value [1]T
+	if q.TryDequeueBatch(value[:]) == 0 {
+		return value[0], false
+	}
+	return value[0], true
+}
+
+// Removes the oldest element from the queue and returns it, along with true,
+// waiting until an element is available. If the queue is closed, and empty,
+// the zero value of T and false are returned.
+func (q *SPSC[T]) Dequeue() (T, bool) {
+	var value [1]T
+	if q.DequeueBatch(value[:]) == 0 {
+		return value[0], false
+	}
+	return value[0], true
+}
+
+// Removes as many elements as are available, up to len(into), copying them
+// into into in order. Returns the number of elements dequeued.
+func (q *SPSC[T]) TryDequeueBatch(into []T) int {
+	head := q.consumer.head.Load()
+	available := q.consumer.cachedTail - head
+	if available < uint64(len(into)) {
+		// Refresh our view of the producer's progress
+		q.consumer.cachedTail = q.producer.tail.Load()
+		available = q.consumer.cachedTail - head
+	}
+
+	count := min(available, uint64(len(into)))
+	slots := q.slots.Value()
+	for i := range count {
+		into[i] = slots[(head+i)&q.mask]
+	}
+
+	// Release all of the consumed slots to the producer at once
+	q.consumer.head.Store(head + count)
+	return int(count)
+}
+
+// Removes up to len(into) elements, copying them into into in order, waiting
+// until at least one element is available. Returns the number of elements
+// dequeued. If the queue is closed, and empty, 0 is returned.
+func (q *SPSC[T]) DequeueBatch(into []T) int {
+	if len(into) == 0 {
+		return 0
+	}
+
+	b := backoff{}
+	for {
+		// Check closed before trying to dequeue, any elements enqueued
+		// before the queue was closed will be observed
+		closed := q.closed.Load()
+		if count := q.TryDequeueBatch(into); count > 0 || closed {
+			return count
+		}
+		b.wait()
+	}
+}
+
+// Closes the queue. Elements already in the queue can still be dequeued, but
+// no more elements may be enqueued. May only be called by the producer.
+func (q *SPSC[T]) Close() {
+	q.closed.Store(true)
+}
+
+// Returns the number of elements in the queue. With concurrent producers and
+// consumers this value is approximate.
+func (q *SPSC[T]) Len() int {
+	head := q.consumer.head.Load()
+	tail := q.producer.tail.Load()
+	return int(tail - head)
+}
+
+// Returns the number of elements the queue can hold.
+func (q *SPSC[T]) Cap() int {
+	return int(q.capacity())
+}
+
+// Frees the queue's slots. Any elements still in the queue are discarded.
+// After this call returns the queue must never be used again.
+func (q *SPSC[T]) Free() {
+	offheap.FreeSlice(q.store, q.slots)
+	q.slots = offheap.RefSlice[T]{}
+}
+
+func (q *SPSC[T]) capacity() uint64 {
+	return q.mask + 1
+}
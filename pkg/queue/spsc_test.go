@@ -0,0 +1,203 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package queue
+
+import (
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_BadCapacity_Panics(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() { NewSPSC[int](store, 0) })
+	assert.Panics(t, func() { NewMPMC[int](store, -1) })
+}
+
+func TestNew_Pointers_Panics(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() { NewSPSC[*int](store, 8) })
+	assert.Panics(t, func() { NewMPMC[*int](store, 8) })
+}
+
+func TestNew_CapacityRoundedUp(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Equal(t, 1, NewSPSC[int](store, 1).Cap())
+	assert.Equal(t, 16, NewSPSC[int](store, 10).Cap())
+	assert.Equal(t, 16, NewMPMC[int](store, 16).Cap())
+	assert.Equal(t, 32, NewMPMC[int](store, 17).Cap())
+}
+
+func TestSPSC_TryEnqueueDequeue(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int](store, 8)
+
+	_, ok := q.TryDequeue()
+	assert.False(t, ok)
+
+	// Fill and drain the queue several times, so that we wrap around the
+	// slots
+	for lap := range 5 {
+		for i := range 8 {
+			require.True(t, q.TryEnqueue(lap*8+i))
+		}
+		require.False(t, q.TryEnqueue(-1))
+		require.Equal(t, 8, q.Len())
+
+		for i := range 8 {
+			value, ok := q.TryDequeue()
+			require.True(t, ok)
+			require.Equal(t, lap*8+i, value)
+		}
+		_, ok := q.TryDequeue()
+		require.False(t, ok)
+		require.Equal(t, 0, q.Len())
+	}
+}
+
+func TestSPSC_Batch(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int](store, 8)
+
+	// Only the first 8 values fit
+	assert.Equal(t, 8, q.TryEnqueueBatch([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+	assert.Equal(t, 0, q.TryEnqueueBatch([]int{10}))
+
+	into := make([]int, 5)
+	assert.Equal(t, 5, q.TryDequeueBatch(into))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, into)
+
+	assert.Equal(t, 5, q.TryEnqueueBatch([]int{8, 9, 10, 11, 12, 13}))
+
+	into = make([]int, 10)
+	assert.Equal(t, 8, q.TryDequeueBatch(into))
+	assert.Equal(t, []int{5, 6, 7, 8, 9, 10, 11, 12}, into[:8])
+
+	assert.Equal(t, 0, q.TryDequeueBatch(into))
+}
+
+func TestSPSC_Close(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int](store, 8)
+
+	q.Enqueue(1)
+	q.Enqueue(2)
+	q.Close()
+
+	assert.Panics(t, func() { q.Enqueue(3) })
+	assert.Panics(t, func() { q.TryEnqueue(3) })
+
+	// Elements enqueued before Close are still dequeued
+	value, ok := q.Dequeue()
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	into := make([]int, 4)
+	assert.Equal(t, 1, q.DequeueBatch(into))
+	assert.Equal(t, 2, into[0])
+
+	// Blocking dequeues return immediately once the queue is drained
+	_, ok = q.Dequeue()
+	assert.False(t, ok)
+	assert.Equal(t, 0, q.DequeueBatch(into))
+}
+
+// Show that a blocking producer and consumer can pass many more elements than
+// the queue's capacity through the queue, in order
+func TestSPSC_Blocking(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int64](store, 16)
+
+	const count = 100_000
+	go func() {
+		batch := make([]int64, 0, 7)
+		for i := range int64(count) {
+			if i%3 == 0 {
+				q.EnqueueBatch(batch)
+				batch = batch[:0]
+				q.Enqueue(i)
+				continue
+			}
+			batch = append(batch, i)
+			if len(batch) == cap(batch) {
+				q.EnqueueBatch(batch)
+				batch = batch[:0]
+			}
+		}
+		q.EnqueueBatch(batch)
+		q.Close()
+	}()
+
+	expected := int64(0)
+	into := make([]int64, 5)
+	for {
+		n := q.DequeueBatch(into)
+		if n == 0 {
+			break
+		}
+		for _, value := range into[:n] {
+			require.Equal(t, expected, value)
+			expected++
+		}
+	}
+	assert.Equal(t, int64(count), expected)
+}
+
+// Show that freeing the queue releases its slots
+func TestSPSC_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int](store, 1024)
+	q.Enqueue(1)
+
+	q.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Assert that enqueuing and dequeuing does not allocate
+func TestSPSC_NoAllocations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	q := NewSPSC[int64](store, 1024)
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		for i := range int64(1000) {
+			q.TryEnqueue(i)
+		}
+		for range 1000 {
+			q.TryDequeue()
+		}
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package radix
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A node in the radix tree. The key for a node is the concatenation of the
+// labels of every node on the path from the root to that node, including
+// the node's own label.
+type node[V any] struct {
+	// The label of the edge from this node's parent to this node. Only the
+	// root has an empty, nil, label.
+	label    offheap.RefString
+	hasValue bool
+	value    V
+	// Children are sorted by the first byte of their labels. No two
+	// children share the same first byte. A node with no children has a
+	// nil slice.
+	children offheap.RefSlice[offheap.RefObject[node[V]]]
+}
+
+func allocNode[V any](store *offheap.Store, label string) offheap.RefObject[node[V]] {
+	ref := offheap.AllocObject[node[V]](store)
+	n := ref.Value()
+	*n = node[V]{}
+	n.setLabel(store, label)
+	return ref
+}
+
+// Frees n, including its label and children slice. n's children are not
+// freed.
+func freeNode[V any](store *offheap.Store, ref offheap.RefObject[node[V]]) {
+	n := ref.Value()
+	n.setLabel(store, "")
+	if !n.children.IsNil() {
+		offheap.FreeSlice(store, n.children)
+	}
+	offheap.FreeObject(store, ref)
+}
+
+// Replaces the label of n, freeing the existing label. Because label may be a
+// view of the existing label, the new label is allocated before the existing
+// label is freed.
+func (n *node[V]) setLabel(store *offheap.Store, label string) {
+	oldLabel := n.label
+	n.label = offheap.RefString{}
+	if len(label) > 0 {
+		n.label = offheap.AllocStringFromString(store, label)
+	}
+	if !oldLabel.IsNil() {
+		offheap.FreeString(store, oldLabel)
+	}
+}
+
+// Returns the children of n. A node with no children returns a nil slice.
+func (n *node[V]) childSlice() []offheap.RefObject[node[V]] {
+	if n.children.IsNil() {
+		return nil
+	}
+	return n.children.Value()
+}
+
+func (n *node[V]) childCount() int {
+	return len(n.childSlice())
+}
+
+// Returns the index of the child whose label starts with b, and true. If
+// there is no such child, the index where it would be inserted and false are
+// returned.
+func (n *node[V]) findChild(b byte) (int, bool) {
+	return slices.BinarySearchFunc(n.childSlice(), b, func(ref offheap.RefObject[node[V]], b byte) int {
+		return cmp.Compare(ref.Value().label.Value()[0], b)
+	})
+}
+
+func (n *node[V]) insertChild(store *offheap.Store, idx int, child offheap.RefObject[node[V]]) {
+	if n.children.IsNil() {
+		n.children = offheap.AllocSlice[offheap.RefObject[node[V]]](store, 0, 1)
+	}
+	n.children = offheap.InsertAt(store, n.children, idx, child)
+}
+
+func (n *node[V]) removeChild(store *offheap.Store, idx int) {
+	n.children = offheap.DeleteAt(store, n.children, idx)
+	if n.childCount() == 0 {
+		offheap.FreeSlice(store, n.children)
+		n.children = offheap.RefSlice[offheap.RefObject[node[V]]]{}
+	}
+}
+
+// Merges n with its only child. The child's label is appended to n's label
+// and n takes the child's value and children. The child is freed.
+func (n *node[V]) mergeChild(store *offheap.Store) {
+	childRef := n.childSlice()[0]
+	child := childRef.Value()
+
+	merged := offheap.ConcatStrings(store, n.label.Value(), child.label.Value())
+	if !n.label.IsNil() {
+		offheap.FreeString(store, n.label)
+	}
+	n.label = merged
+
+	offheap.FreeSlice(store, n.children)
+	n.children = child.children
+	n.hasValue = child.hasValue
+	n.value = child.value
+
+	// The child's children now belong to n
+	child.children = offheap.RefSlice[offheap.RefObject[node[V]]]{}
+	freeNode(store, childRef)
+}
+
+// Returns the length of the longest common prefix of a and b.
+func commonPrefix(a, b string) int {
+	length := min(len(a), len(b))
+	for i := range length {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return length
+}
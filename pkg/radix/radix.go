@@ -0,0 +1,286 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The radix package provides a radix tree, a compressed trie, mapping string
+// keys to values. Nodes are allocated in an offheap.Store, with the label of
+// each edge stored as an offheap.RefString. This allows very large sets of
+// strings, such as URLs or metric names, to be indexed for prefix queries
+// without impacting garbage collection.
+//
+// The tree is not safe for concurrent use.
+package radix
+
+import (
+	"strings"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A radix tree mapping string keys to V.
+//
+// V may not contain pointers. Creating a Tree with a value type which
+// contains pointers will panic.
+type Tree[V any] struct {
+	store  *offheap.Store
+	root   offheap.RefObject[node[V]]
+	length int
+}
+
+// Creates a new empty Tree whose nodes and labels will be allocated in store.
+func New[V any](store *offheap.Store) *Tree[V] {
+	return &Tree[V]{
+		store: store,
+		root:  allocNode[V](store, ""),
+	}
+}
+
+// Associates value with key, replacing any existing value.
+func (t *Tree[V]) Insert(key string, value V) {
+	n := t.root.Value()
+	search := key
+	for {
+		if len(search) == 0 {
+			if !n.hasValue {
+				t.length++
+			}
+			n.hasValue = true
+			n.value = value
+			return
+		}
+
+		idx, found := n.findChild(search[0])
+		if !found {
+			// No edge shares a prefix with search, add a new leaf
+			t.insertLeaf(n, idx, search, value)
+			return
+		}
+
+		childRef := n.childSlice()[idx]
+		child := childRef.Value()
+		label := child.label.Value()
+		common := commonPrefix(search, label)
+		if common == len(label) {
+			// Follow the edge
+			n = child
+			search = search[common:]
+			continue
+		}
+
+		// search diverges part way along the edge. Split the edge,
+		// inserting a new node at the point where they diverge.
+		midRef := allocNode[V](t.store, label[:common])
+		mid := midRef.Value()
+		// Must be done after the mid label is allocated, because
+		// label is a view of the child's label
+		child.setLabel(t.store, label[common:])
+		mid.insertChild(t.store, 0, childRef)
+		n.childSlice()[idx] = midRef
+
+		search = search[common:]
+		if len(search) == 0 {
+			mid.hasValue = true
+			mid.value = value
+			t.length++
+			return
+		}
+		leafIdx, _ := mid.findChild(search[0])
+		t.insertLeaf(mid, leafIdx, search, value)
+		return
+	}
+}
+
+func (t *Tree[V]) insertLeaf(parent *node[V], idx int, label string, value V) {
+	leafRef := allocNode[V](t.store, label)
+	leaf := leafRef.Value()
+	leaf.hasValue = true
+	leaf.value = value
+	parent.insertChild(t.store, idx, leafRef)
+	t.length++
+}
+
+// Returns the value associated with key, and true. If key is not in the tree
+// the zero value of V and false are returned.
+func (t *Tree[V]) Get(key string) (V, bool) {
+	n := t.root.Value()
+	search := key
+	for len(search) > 0 {
+		idx, found := n.findChild(search[0])
+		if !found {
+			var zero V
+			return zero, false
+		}
+		child := n.childSlice()[idx].Value()
+		label := child.label.Value()
+		if !strings.HasPrefix(search, label) {
+			var zero V
+			return zero, false
+		}
+		n = child
+		search = search[len(label):]
+	}
+	return n.value, n.hasValue
+}
+
+// Removes key from the tree. Returns true if key was in the tree, false
+// otherwise.
+//
+// Nodes which are no longer needed are freed, and nodes left with a single
+// child are merged with that child, so the tree remains compressed.
+func (t *Tree[V]) Delete(key string) bool {
+	var parent *node[V]
+	parentIdx := 0
+	n := t.root.Value()
+	search := key
+	for len(search) > 0 {
+		idx, found := n.findChild(search[0])
+		if !found {
+			return false
+		}
+		child := n.childSlice()[idx].Value()
+		label := child.label.Value()
+		if !strings.HasPrefix(search, label) {
+			return false
+		}
+		parent, parentIdx = n, idx
+		n = child
+		search = search[len(label):]
+	}
+
+	if !n.hasValue {
+		return false
+	}
+	var zero V
+	n.hasValue = false
+	n.value = zero
+	t.length--
+
+	if parent == nil {
+		// The root is never removed or merged
+		return true
+	}
+
+	switch n.childCount() {
+	case 0:
+		freeNode(t.store, parent.childSlice()[parentIdx])
+		parent.removeChild(t.store, parentIdx)
+		// The parent may now be a redundant node with one child
+		if parent != t.root.Value() && !parent.hasValue && parent.childCount() == 1 {
+			parent.mergeChild(t.store)
+		}
+	case 1:
+		n.mergeChild(t.store)
+	}
+	return true
+}
+
+// Returns the number of keys in the tree.
+func (t *Tree[V]) Len() int {
+	return t.length
+}
+
+// Calls fun for each key/value in the tree in ascending lexicographic order of
+// key. If fun returns false the iteration stops.
+//
+// The tree must not be modified during the iteration.
+func (t *Tree[V]) Walk(fun func(key string, value V) bool) {
+	t.walk(t.root.Value(), nil, fun)
+}
+
+// Calls fun for each key/value in the tree, where key starts with prefix, in
+// ascending lexicographic order of key. If fun returns false the iteration
+// stops.
+//
+// The tree must not be modified during the iteration.
+func (t *Tree[V]) WalkPrefix(prefix string, fun func(key string, value V) bool) {
+	n := t.root.Value()
+	key := []byte{}
+	search := prefix
+	for len(search) > 0 {
+		idx, found := n.findChild(search[0])
+		if !found {
+			return
+		}
+		child := n.childSlice()[idx].Value()
+		label := child.label.Value()
+		switch {
+		case strings.HasPrefix(search, label):
+			search = search[len(label):]
+		case strings.HasPrefix(label, search):
+			// The prefix ends part way along this edge, every key
+			// below child starts with prefix
+			search = ""
+		default:
+			return
+		}
+		key = append(key, label...)
+		n = child
+	}
+	t.walk(n, key, fun)
+}
+
+// Calls fun for n, and every node below n, in lexicographic order. key is the
+// key for n. Returns false if the iteration was stopped.
+func (t *Tree[V]) walk(n *node[V], key []byte, fun func(key string, value V) bool) bool {
+	if n.hasValue && !fun(string(key), n.value) {
+		return false
+	}
+	for _, childRef := range n.childSlice() {
+		child := childRef.Value()
+		if !t.walk(child, append(key, child.label.Value()...), fun) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the longest key in the tree which is a prefix of s, along with its
+// value and true. If no key in the tree is a prefix of s then false is
+// returned.
+func (t *Tree[V]) LongestPrefix(s string) (string, V, bool) {
+	var (
+		matchedKey   string
+		matchedValue V
+		matched      bool
+	)
+
+	n := t.root.Value()
+	consumed := 0
+	for {
+		if n.hasValue {
+			matchedKey, matchedValue, matched = s[:consumed], n.value, true
+		}
+		search := s[consumed:]
+		if len(search) == 0 {
+			break
+		}
+		idx, found := n.findChild(search[0])
+		if !found {
+			break
+		}
+		child := n.childSlice()[idx].Value()
+		label := child.label.Value()
+		if !strings.HasPrefix(search, label) {
+			break
+		}
+		n = child
+		consumed += len(label)
+	}
+
+	return matchedKey, matchedValue, matched
+}
+
+// Frees all of the nodes, and labels, in the tree. After this call returns
+// the tree must never be used again.
+func (t *Tree[V]) Free() {
+	t.free(t.root)
+	t.root = offheap.RefObject[node[V]]{}
+	t.length = 0
+}
+
+func (t *Tree[V]) free(ref offheap.RefObject[node[V]]) {
+	for _, childRef := range ref.Value().childSlice() {
+		t.free(childRef)
+	}
+	freeNode(t.store, ref)
+}
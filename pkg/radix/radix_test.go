@@ -0,0 +1,363 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package radix
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Show that an empty tree behaves sensibly
+func TestTree_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	assert.Equal(t, 0, tree.Len())
+
+	_, ok := tree.Get("")
+	assert.False(t, ok)
+	_, ok = tree.Get("a")
+	assert.False(t, ok)
+
+	assert.False(t, tree.Delete(""))
+	assert.False(t, tree.Delete("a"))
+
+	_, _, ok = tree.LongestPrefix("abc")
+	assert.False(t, ok)
+
+	tree.Walk(func(key string, value int) bool {
+		t.Errorf("unexpected key %q", key)
+		return true
+	})
+}
+
+func TestTree_Pointers_Panic(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() {
+		New[*int](store)
+	})
+}
+
+// Insert keys which force edges to be split in various ways
+func TestTree_InsertGet(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	keys := []string{
+		"romane",
+		"romanus",
+		"romulus",
+		"rubens",
+		"ruber",
+		"rubicon",
+		"rubicundus",
+		"rom",
+		"r",
+		"",
+		"x",
+	}
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+	assert.Equal(t, len(keys), tree.Len())
+
+	for i, key := range keys {
+		value, ok := tree.Get(key)
+		require.True(t, ok, key)
+		require.Equal(t, i, value, key)
+	}
+
+	for _, key := range []string{"ro", "roman", "romanes", "rubi", "xx", "y"} {
+		_, ok := tree.Get(key)
+		assert.False(t, ok, key)
+	}
+
+	// Replacing a value does not change the length
+	tree.Insert("rom", 100)
+	value, ok := tree.Get("rom")
+	assert.True(t, ok)
+	assert.Equal(t, 100, value)
+	assert.Equal(t, len(keys), tree.Len())
+}
+
+func TestTree_Delete(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	keys := []string{"romane", "romanus", "romulus", "rom", "rubens", "ruber", ""}
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+
+	assert.False(t, tree.Delete("roman"))
+	assert.False(t, tree.Delete("romanes"))
+
+	for i, key := range keys {
+		require.True(t, tree.Delete(key), key)
+		require.False(t, tree.Delete(key), key)
+		require.Equal(t, len(keys)-i-1, tree.Len())
+
+		// The remaining keys are all still present
+		for j, remaining := range keys[i+1:] {
+			value, ok := tree.Get(remaining)
+			require.True(t, ok, remaining)
+			require.Equal(t, i+j+1, value)
+		}
+	}
+
+	// Only the root node remains
+	live := 0
+	for _, stats := range store.Stats() {
+		live += stats.Live
+	}
+	assert.Equal(t, 1, live)
+}
+
+// Show that deleting keys merges redundant nodes, so the tree stays
+// compressed
+func TestTree_Delete_Merges(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	tree.Insert("test", 1)
+	tree.Insert("team", 2)
+	tree.Insert("toast", 3)
+
+	require.True(t, tree.Delete("team"))
+	require.True(t, tree.Delete("toast"))
+
+	// A single edge from the root to "test"
+	root := tree.root.Value()
+	require.Equal(t, 1, root.childCount())
+	child := root.childSlice()[0].Value()
+	assert.Equal(t, "test", child.label.Value())
+	assert.Equal(t, 0, child.childCount())
+}
+
+func TestTree_Walk(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	r := rand.New(rand.NewSource(1))
+	keys := []string{}
+	for i := range 1000 {
+		keys = append(keys, fmt.Sprintf("%x", r.Int63n(1<<20)))
+		tree.Insert(keys[i], i)
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	walked := []string{}
+	tree.Walk(func(key string, value int) bool {
+		walked = append(walked, key)
+		return true
+	})
+	assert.Equal(t, keys, walked)
+
+	// Iteration stops when fun returns false
+	count := 0
+	tree.Walk(func(key string, value int) bool {
+		count++
+		return count < 10
+	})
+	assert.Equal(t, 10, count)
+}
+
+func TestTree_WalkPrefix(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	keys := []string{
+		"/api/users",
+		"/api/users/1",
+		"/api/users/2",
+		"/api/orders",
+		"/static/app.js",
+		"/",
+	}
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+
+	collect := func(prefix string) []string {
+		found := []string{}
+		tree.WalkPrefix(prefix, func(key string, value int) bool {
+			found = append(found, key)
+			require.Equal(t, keys[slices.Index(keys, key)], key)
+			return true
+		})
+		return found
+	}
+
+	assert.Equal(t, []string{"/api/users", "/api/users/1", "/api/users/2"}, collect("/api/users"))
+	assert.Equal(t, []string{"/api/users", "/api/users/1", "/api/users/2"}, collect("/api/u"))
+	assert.Equal(t, []string{"/api/orders", "/api/users", "/api/users/1", "/api/users/2"}, collect("/api/"))
+	assert.Equal(t, []string{"/static/app.js"}, collect("/st"))
+	assert.Equal(t, []string{}, collect("/missing"))
+	assert.Equal(t, []string{}, collect("/api/users/3"))
+	assert.Equal(t, len(keys), len(collect("")))
+}
+
+func TestTree_LongestPrefix(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	tree.Insert("/", 1)
+	tree.Insert("/api", 2)
+	tree.Insert("/api/users", 3)
+	tree.Insert("/static/", 4)
+
+	for _, tc := range []struct {
+		s        string
+		key      string
+		value    int
+		expected bool
+	}{
+		{"/api/users/1", "/api/users", 3, true},
+		{"/api/users", "/api/users", 3, true},
+		{"/api/user", "/api", 2, true},
+		{"/api", "/api", 2, true},
+		{"/static/app.js", "/static/", 4, true},
+		{"/static", "/", 1, true},
+		{"/other", "/", 1, true},
+		{"other", "", 0, false},
+		{"", "", 0, false},
+	} {
+		key, value, ok := tree.LongestPrefix(tc.s)
+		assert.Equal(t, tc.expected, ok, tc.s)
+		assert.Equal(t, tc.key, key, tc.s)
+		assert.Equal(t, tc.value, value, tc.s)
+	}
+}
+
+// Perform a long sequence of random inserts and deletes, comparing the tree
+// with a conventional map after every operation.
+func TestTree_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int64](store)
+	expected := map[string]int64{}
+
+	// Short keys over a small alphabet produce lots of shared prefixes
+	randomKey := func() string {
+		b := strings.Builder{}
+		for range r.Intn(8) {
+			b.WriteByte("abc"[r.Intn(3)])
+		}
+		return b.String()
+	}
+
+	for range 50_000 {
+		key := randomKey()
+		if r.Intn(3) == 0 {
+			_, inMap := expected[key]
+			delete(expected, key)
+			require.Equal(t, inMap, tree.Delete(key))
+		} else {
+			value := r.Int63()
+			expected[key] = value
+			tree.Insert(key, value)
+		}
+		require.Equal(t, len(expected), tree.Len())
+	}
+
+	for key, expectedValue := range expected {
+		value, ok := tree.Get(key)
+		require.True(t, ok)
+		require.Equal(t, expectedValue, value)
+	}
+
+	keys := []string{}
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	walked := []string{}
+	tree.Walk(func(key string, value int64) bool {
+		walked = append(walked, key)
+		require.Equal(t, expected[key], value)
+		return true
+	})
+	assert.Equal(t, keys, walked)
+
+	checkCompressed(t, tree)
+}
+
+// Show that freeing the tree releases all of its nodes and labels
+func TestTree_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	tree := New[int](store)
+
+	for i := range 1000 {
+		tree.Insert(fmt.Sprintf("key-%d", i), i)
+	}
+
+	tree.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Checks that every node, other than the root, either has a value or at least
+// two children, and that no two children share the same first byte.
+func checkCompressed[V any](t *testing.T, tree *Tree[V]) {
+	t.Helper()
+
+	var check func(n *node[V], isRoot bool)
+	check = func(n *node[V], isRoot bool) {
+		if !isRoot {
+			require.False(t, n.label.IsNil())
+			require.True(t, n.hasValue || n.childCount() >= 2)
+		}
+		prev := -1
+		for _, childRef := range n.childSlice() {
+			child := childRef.Value()
+			first := int(child.label.Value()[0])
+			require.Less(t, prev, first)
+			prev = first
+			check(child, false)
+		}
+	}
+
+	check(tree.root.Value(), true)
+}
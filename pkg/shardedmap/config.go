@@ -0,0 +1,47 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package shardedmap
+
+import (
+	"runtime"
+
+	"github.com/fmstephe/flib/fmath"
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+type Config struct {
+	// Defines the number shards used internally to determine the level of
+	// available concurrency for the map.
+	//
+	// Important to note that this is not an exactly configurable
+	// parameter. The number of shards must always be a power of two, and
+	// the value provided here may be rounded up if necessary.
+	//
+	// <= 0 indicates that the map should determine the number of shards
+	// automatically.
+	Shards int
+
+	// Defines the offheap store to use for allocating map entries.
+	//
+	// If nil then a new store will be created internally. Only needed if
+	// you want to share a single offheap store across multiple maps, or
+	// other offheap datastructures.
+	Store *offheap.Store
+}
+
+func (c *Config) getShards() int {
+	if c.Shards <= 0 {
+		c.Shards = runtime.NumCPU()
+	}
+
+	return int(fmath.NxtPowerOfTwo(int64(c.Shards)))
+}
+
+func (c *Config) getStore() *offheap.Store {
+	if c.Store == nil {
+		c.Store = offheap.New()
+	}
+	return c.Store
+}
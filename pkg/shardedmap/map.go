@@ -0,0 +1,311 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The shardedmap package provides a concurrent hash map whose entries are
+// allocated in an offheap.Store.
+//
+// The map is split into a power of two number of shards, each protected by
+// its own sync.RWMutex. Reads of a shard can proceed concurrently, and writes
+// only block readers and writers of the same shard. This makes the map well
+// suited to read-heavy workloads from many goroutines.
+package shardedmap
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/fmstephe/memorymanager/pkg/internal/keyhash"
+)
+
+const initialBuckets = 16
+
+// A concurrent map from K to V.
+//
+// Keys are hashed using their in-memory representation, so K must be built
+// from bools and integers, possibly in arrays and structs, and must not contain
+// padding. Creating a Map with any other key type will panic. V may not
+// contain pointers, creating a Map with a value type which contains pointers
+// will panic.
+type Map[K comparable, V any] struct {
+	indexMask uint64
+	store     *offheap.Store
+	shards    []shard[K, V]
+}
+
+// Construct a new Map with the provided config.
+func New[K comparable, V any](config Config) *Map[K, V] {
+	if err := keyhash.CheckKey[K](); err != nil {
+		panic(fmt.Errorf("cannot create map %w", err))
+	}
+
+	if err := offheap.CheckNoPointers[entry[K, V]](); err != nil {
+		panic(fmt.Errorf("cannot create map %w", err))
+	}
+
+	store := config.getStore()
+	shardCount := config.getShards()
+
+	shards := make([]shard[K, V], shardCount)
+	for i := range shards {
+		shards[i] = newShard[K, V](store)
+	}
+
+	return &Map[K, V]{
+		indexMask: uint64(shardCount - 1),
+		store:     store,
+		shards:    shards,
+	}
+}
+
+// Returns the value stored in the map for key, and true. If key is not in the
+// map the zero value of V and false are returned.
+func (m *Map[K, V]) Load(key K) (V, bool) {
+	hash := keyhash.Hash(&key)
+	return m.shards[m.getIndex(hash)].load(hash, key)
+}
+
+// Sets the value for key, replacing any existing value.
+func (m *Map[K, V]) Store(key K, value V) {
+	hash := keyhash.Hash(&key)
+	m.shards[m.getIndex(hash)].store(hash, key, value)
+}
+
+// Returns the existing value for key, and true, if key is in the map.
+// Otherwise value is stored and returned, along with false.
+func (m *Map[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	hash := keyhash.Hash(&key)
+	return m.shards[m.getIndex(hash)].loadOrStore(hash, key, value)
+}
+
+// Removes key from the map. Returns true if key was in the map, false
+// otherwise.
+func (m *Map[K, V]) Delete(key K) bool {
+	hash := keyhash.Hash(&key)
+	return m.shards[m.getIndex(hash)].delete(hash, key)
+}
+
+// Calls fun for each key/value in the map. If fun returns false the iteration
+// stops.
+//
+// Each shard is read locked while its entries are visited, so fun must not
+// modify the map. Range does not represent a consistent snapshot of the map,
+// concurrent modifications to shards which have not yet been visited will be
+// observed.
+func (m *Map[K, V]) Range(fun func(key K, value V) bool) {
+	for i := range m.shards {
+		if !m.shards[i].rangeEntries(fun) {
+			return
+		}
+	}
+}
+
+// Returns the number of entries in the map. Each shard is counted
+// independently so, with concurrent modifications, this value is approximate.
+func (m *Map[K, V]) Len() int {
+	total := 0
+	for i := range m.shards {
+		total += m.shards[i].len()
+	}
+	return total
+}
+
+// Frees every entry in the map, and the map's buckets. After this call returns
+// the map must never be used again.
+func (m *Map[K, V]) Free() {
+	for i := range m.shards {
+		m.shards[i].free()
+	}
+}
+
+func (m *Map[K, V]) getIndex(hash uint64) uint64 {
+	return m.indexMask & hash
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	hash  uint64
+	next  offheap.RefObject[entry[K, V]]
+}
+
+type shard[K comparable, V any] struct {
+	entryStore *offheap.Store
+	//
+	lock    sync.RWMutex
+	buckets offheap.RefSlice[offheap.RefObject[entry[K, V]]]
+	count   int
+}
+
+func newShard[K comparable, V any](store *offheap.Store) shard[K, V] {
+	return shard[K, V]{
+		entryStore: store,
+		buckets:    allocBuckets[K, V](store, initialBuckets),
+	}
+}
+
+func allocBuckets[K comparable, V any](store *offheap.Store, size int) offheap.RefSlice[offheap.RefObject[entry[K, V]]] {
+	buckets := offheap.AllocSlice[offheap.RefObject[entry[K, V]]](store, size, size)
+	clear(buckets.Value())
+	return buckets
+}
+
+func (s *shard[K, V]) load(hash uint64, key K) (V, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if e := s.find(hash, key); e != nil {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (s *shard[K, V]) store(hash uint64, key K, value V) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if e := s.find(hash, key); e != nil {
+		e.value = value
+		return
+	}
+	s.insert(hash, key, value)
+}
+
+func (s *shard[K, V]) loadOrStore(hash uint64, key K, value V) (V, bool) {
+	// Optimistically try to load the value with only a read lock
+	if existing, ok := s.load(hash, key); ok {
+		return existing, true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The key may have been stored since we released the read lock
+	if e := s.find(hash, key); e != nil {
+		return e.value, true
+	}
+	s.insert(hash, key, value)
+	return value, false
+}
+
+func (s *shard[K, V]) delete(hash uint64, key K) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	buckets := s.buckets.Value()
+	idx := s.bucketIndex(hash)
+
+	var prev *entry[K, V]
+	for ref := buckets[idx]; !ref.IsNil(); {
+		e := ref.Value()
+		if e.hash == hash && e.key == key {
+			if prev == nil {
+				buckets[idx] = e.next
+			} else {
+				prev.next = e.next
+			}
+			offheap.FreeObject(s.entryStore, ref)
+			s.count--
+			return true
+		}
+		prev = e
+		ref = e.next
+	}
+	return false
+}
+
+func (s *shard[K, V]) rangeEntries(fun func(key K, value V) bool) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, ref := range s.buckets.Value() {
+		for !ref.IsNil() {
+			e := ref.Value()
+			if !fun(e.key, e.value) {
+				return false
+			}
+			ref = e.next
+		}
+	}
+	return true
+}
+
+func (s *shard[K, V]) free() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, ref := range s.buckets.Value() {
+		for !ref.IsNil() {
+			next := ref.Value().next
+			offheap.FreeObject(s.entryStore, ref)
+			ref = next
+		}
+	}
+	offheap.FreeSlice(s.entryStore, s.buckets)
+	s.count = 0
+}
+
+func (s *shard[K, V]) len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.count
+}
+
+// The low bits of the hash are used to select the shard, so we use the high
+// bits to select the bucket within the shard.
+func (s *shard[K, V]) bucketIndex(hash uint64) int {
+	return int((hash >> 32) & uint64(len(s.buckets.Value())-1))
+}
+
+func (s *shard[K, V]) find(hash uint64, key K) *entry[K, V] {
+	buckets := s.buckets.Value()
+	for ref := buckets[s.bucketIndex(hash)]; !ref.IsNil(); {
+		e := ref.Value()
+		if e.hash == hash && e.key == key {
+			return e
+		}
+		ref = e.next
+	}
+	return nil
+}
+
+func (s *shard[K, V]) insert(hash uint64, key K, value V) {
+	ref := offheap.AllocObject[entry[K, V]](s.entryStore)
+	e := ref.Value()
+	e.key = key
+	e.value = value
+	e.hash = hash
+
+	buckets := s.buckets.Value()
+	idx := s.bucketIndex(hash)
+	e.next = buckets[idx]
+	buckets[idx] = ref
+	s.count++
+
+	if s.count > len(buckets) {
+		s.grow()
+	}
+}
+
+// Doubles the number of buckets in this shard, and redistributes the entries.
+func (s *shard[K, V]) grow() {
+	oldBuckets := s.buckets
+	s.buckets = allocBuckets[K, V](s.entryStore, len(oldBuckets.Value())*2)
+	buckets := s.buckets.Value()
+
+	for _, ref := range oldBuckets.Value() {
+		for !ref.IsNil() {
+			e := ref.Value()
+			next := e.next
+			idx := s.bucketIndex(e.hash)
+			e.next = buckets[idx]
+			buckets[idx] = ref
+			ref = next
+		}
+	}
+
+	offheap.FreeSlice(s.entryStore, oldBuckets)
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package shardedmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+const keysPerGoroutine = 1000
+const goroutines = 100
+
+// Demonstrate that multiple goroutines can store/load/delete disjoint sets of
+// keys on a shared Map
+// This test should be run with -race
+func TestSeparateKeys_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	m := New[int64, int64](Config{Shards: 4, Store: store})
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+
+	complete := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		complete.Add(1)
+		go func() {
+			defer complete.Done()
+			storeLoadAndDelete(t, m, &barrier, int64(i))
+		}()
+	}
+
+	barrier.Done()
+
+	complete.Wait()
+
+	assert.Equal(t, 0, m.Len())
+}
+
+func storeLoadAndDelete(t *testing.T, m *Map[int64, int64], barrier *sync.WaitGroup, goroutine int64) {
+	barrier.Wait()
+
+	first := goroutine * keysPerGoroutine
+	for key := first; key < first+keysPerGoroutine; key++ {
+		m.Store(key, key*2)
+	}
+	for key := first; key < first+keysPerGoroutine; key++ {
+		value, ok := m.Load(key)
+		assert.True(t, ok)
+		assert.Equal(t, key*2, value)
+	}
+	for key := first; key < first+keysPerGoroutine; key++ {
+		assert.True(t, m.Delete(key))
+	}
+}
+
+// Demonstrate that multiple goroutines can race to LoadOrStore the same keys.
+// For each key exactly one goroutine stores its value, and every goroutine
+// sees that value.
+// This test should be run with -race
+func TestLoadOrStoreShared_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	m := New[int64, int64](Config{Shards: 4, Store: store})
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+	stored := atomic.Int64{}
+
+	complete := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		complete.Add(1)
+		go func() {
+			defer complete.Done()
+			loadOrStoreShared(t, m, &barrier, int64(i), &stored)
+		}()
+	}
+
+	barrier.Done()
+
+	complete.Wait()
+
+	assert.Equal(t, int64(keysPerGoroutine), stored.Load())
+	assert.Equal(t, keysPerGoroutine, m.Len())
+}
+
+func loadOrStoreShared(t *testing.T, m *Map[int64, int64], barrier *sync.WaitGroup, goroutine int64, stored *atomic.Int64) {
+	barrier.Wait()
+
+	for key := range int64(keysPerGoroutine) {
+		value, loaded := m.LoadOrStore(key, goroutine)
+		if !loaded {
+			stored.Add(1)
+		}
+
+		// Every later load sees the value which won the race
+		loadedValue, ok := m.Load(key)
+		assert.True(t, ok)
+		assert.Equal(t, value, loadedValue)
+	}
+}
+
+// Demonstrate that readers can Range over the map while writers modify it
+// This test should be run with -race
+func TestRangeWhileWriting_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	m := New[int64, int64](Config{Shards: 4, Store: store})
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+
+	complete := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		complete.Add(1)
+		go func() {
+			defer complete.Done()
+			barrier.Wait()
+			if i%2 == 0 {
+				first := int64(i) * keysPerGoroutine
+				for key := first; key < first+keysPerGoroutine; key++ {
+					m.Store(key, key)
+				}
+				return
+			}
+			m.Range(func(key, value int64) bool {
+				assert.Equal(t, key, value)
+				return true
+			})
+		}()
+	}
+
+	barrier.Done()
+
+	complete.Wait()
+
+	assert.Equal(t, goroutines/2*keysPerGoroutine, m.Len())
+}
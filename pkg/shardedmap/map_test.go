@@ -0,0 +1,223 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package shardedmap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap_Shards(t *testing.T) {
+	for _, tc := range []struct {
+		requested int
+		expected  int
+	}{
+		{1, 1},
+		{2, 2},
+		{3, 4},
+		{8, 8},
+		{9, 16},
+	} {
+		m := New[int64, int64](Config{Shards: tc.requested})
+		assert.Equal(t, tc.expected, len(m.shards))
+		assert.Equal(t, uint64(tc.expected-1), m.indexMask)
+	}
+
+	// Automatically chosen shard counts are always a power of two
+	m := New[int64, int64](Config{})
+	assert.Equal(t, 0, len(m.shards)&(len(m.shards)-1))
+}
+
+func TestMap_StoreLoad(t *testing.T) {
+	m := New[int64, int64](Config{Shards: 4})
+
+	for i := range int64(10_000) {
+		m.Store(i, i*2)
+	}
+	assert.Equal(t, 10_000, m.Len())
+
+	for i := range int64(10_000) {
+		value, ok := m.Load(i)
+		require.True(t, ok)
+		require.Equal(t, i*2, value)
+	}
+
+	_, ok := m.Load(-1)
+	assert.False(t, ok)
+
+	// Replacing values does not change the length
+	m.Store(1, 1)
+	value, ok := m.Load(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), value)
+	assert.Equal(t, 10_000, m.Len())
+}
+
+func TestMap_LoadOrStore(t *testing.T) {
+	m := New[int64, int64](Config{Shards: 4})
+
+	value, loaded := m.LoadOrStore(1, 10)
+	assert.False(t, loaded)
+	assert.Equal(t, int64(10), value)
+
+	value, loaded = m.LoadOrStore(1, 20)
+	assert.True(t, loaded)
+	assert.Equal(t, int64(10), value)
+
+	value, ok := m.Load(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), value)
+}
+
+func TestMap_Delete(t *testing.T) {
+	m := New[int64, int64](Config{Shards: 4})
+
+	for i := range int64(1000) {
+		m.Store(i, i)
+	}
+
+	for i := int64(0); i < 1000; i += 2 {
+		assert.True(t, m.Delete(i))
+		assert.False(t, m.Delete(i))
+	}
+	assert.Equal(t, 500, m.Len())
+
+	for i := range int64(1000) {
+		_, ok := m.Load(i)
+		assert.Equal(t, i%2 == 1, ok)
+	}
+}
+
+func TestMap_Range(t *testing.T) {
+	m := New[int64, int64](Config{Shards: 4})
+
+	expected := map[int64]int64{}
+	for i := range int64(1000) {
+		m.Store(i, -i)
+		expected[i] = -i
+	}
+
+	actual := map[int64]int64{}
+	m.Range(func(key, value int64) bool {
+		actual[key] = value
+		return true
+	})
+	assert.Equal(t, expected, actual)
+
+	// Range stops when fun returns false
+	count := 0
+	m.Range(func(key, value int64) bool {
+		count++
+		return count < 10
+	})
+	assert.Equal(t, 10, count)
+}
+
+// Perform a long sequence of random operations, comparing the map with a
+// conventional map.
+func TestMap_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m := New[int64, int64](Config{Shards: 8})
+	expected := map[int64]int64{}
+
+	for range 100_000 {
+		key := r.Int63n(10_000)
+		switch r.Intn(4) {
+		case 0:
+			_, inMap := expected[key]
+			delete(expected, key)
+			require.Equal(t, inMap, m.Delete(key))
+		case 1:
+			value := r.Int63()
+			expected[key] = value
+			m.Store(key, value)
+		case 2:
+			value := r.Int63()
+			expectedValue, inMap := expected[key]
+			if !inMap {
+				expected[key] = value
+				expectedValue = value
+			}
+			actual, loaded := m.LoadOrStore(key, value)
+			require.Equal(t, inMap, loaded)
+			require.Equal(t, expectedValue, actual)
+		case 3:
+			expectedValue, inMap := expected[key]
+			value, ok := m.Load(key)
+			require.Equal(t, inMap, ok)
+			require.Equal(t, expectedValue, value)
+		}
+		require.Equal(t, len(expected), m.Len())
+	}
+}
+
+// Show that freeing the map releases all of its allocations
+func TestMap_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	m := New[int64, int64](Config{Shards: 4, Store: store})
+	for i := range int64(10_000) {
+		m.Store(i, i)
+	}
+
+	m.Free()
+
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+func TestMap_Pointers_Panic(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	assert.Panics(t, func() {
+		New[int64, *int](Config{Store: store})
+	})
+	// The pointer check is made without allocating
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Allocs)
+	}
+}
+
+// Keys which are equal, but whose in-memory representation may differ, are
+// rejected
+func TestMap_NonCanonicalKey_Panic(t *testing.T) {
+	type paddedKey struct {
+		a int64
+		b byte
+	}
+
+	assert.Panics(t, func() {
+		New[paddedKey, int64](Config{})
+	})
+	assert.Panics(t, func() {
+		New[float64, int64](Config{})
+	})
+}
+
+// Assert that loading values does not allocate
+func TestMap_Load_NoAllocations(t *testing.T) {
+	m := New[int64, int64](Config{})
+	for i := range int64(1000) {
+		m.Store(i, i)
+	}
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		for i := range int64(1000) {
+			m.Load(i)
+		}
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
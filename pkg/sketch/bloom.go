@@ -0,0 +1,173 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package sketch
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A Bloom filter. Items which have been added will always test positive, items
+// which have not been added will test positive with a probability determined
+// by the number of bits, the number of hashes and the number of items added.
+type Bloom struct {
+	store  *offheap.Store
+	bits   uint64
+	hashes int
+	words  offheap.RefSlice[uint64]
+}
+
+// Creates a new empty Bloom filter with the given number of bits, using the
+// given number of hash functions. The filter's bits will be allocated in
+// store. Panics if bits or hashes is not positive.
+func NewBloom(store *offheap.Store, bits uint64, hashes int) *Bloom {
+	if bits == 0 {
+		panic(fmt.Errorf("bloom filter must have at least one bit"))
+	}
+	if hashes <= 0 {
+		panic(fmt.Errorf("bloom filter must have at least one hash, got %d", hashes))
+	}
+
+	return &Bloom{
+		store:  store,
+		bits:   bits,
+		hashes: hashes,
+		words:  allocWords(store, wordsForBits(bits)),
+	}
+}
+
+// Creates a new empty Bloom filter sized to hold expectedItems with a false
+// positive rate of falsePositiveRate. Panics if expectedItems is not positive
+// or falsePositiveRate is not in the range (0, 1).
+func NewBloomWithEstimates(store *offheap.Store, expectedItems uint64, falsePositiveRate float64) *Bloom {
+	if expectedItems == 0 {
+		panic(fmt.Errorf("bloom filter must expect at least one item"))
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		panic(fmt.Errorf("bloom filter false positive rate must be in the range (0, 1), got %f", falsePositiveRate))
+	}
+
+	n := float64(expectedItems)
+	bits := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(bits / n * math.Ln2)
+	return NewBloom(store, uint64(bits), max(1, int(hashes)))
+}
+
+func wordsForBits(bits uint64) int {
+	return int((bits + 63) / 64)
+}
+
+func allocWords(store *offheap.Store, length int) offheap.RefSlice[uint64] {
+	words := offheap.AllocSlice[uint64](store, length, length)
+	clear(words.Value())
+	return words
+}
+
+// Adds data to the filter.
+func (b *Bloom) Add(data []byte) {
+	b.add(hashBytes(data))
+}
+
+// Adds data to the filter.
+func (b *Bloom) AddString(data string) {
+	b.add(hashString(data))
+}
+
+// Returns true if data may have been added to the filter. Returns false if
+// data has definitely not been added.
+func (b *Bloom) Test(data []byte) bool {
+	return b.test(hashBytes(data))
+}
+
+// Returns true if data may have been added to the filter. Returns false if
+// data has definitely not been added.
+func (b *Bloom) TestString(data string) bool {
+	return b.test(hashString(data))
+}
+
+func (b *Bloom) add(h1, h2 uint64) {
+	words := b.words.Value()
+	for i := range uint64(b.hashes) {
+		bit := (h1 + i*h2) % b.bits
+		words[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *Bloom) test(h1, h2 uint64) bool {
+	words := b.words.Value()
+	for i := range uint64(b.hashes) {
+		bit := (h1 + i*h2) % b.bits
+		if words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds every item in other to b. The filters must have the same number of bits
+// and hashes, otherwise an error is returned and b is unchanged.
+func (b *Bloom) Merge(other *Bloom) error {
+	if b.bits != other.bits || b.hashes != other.hashes {
+		return fmt.Errorf("cannot merge bloom filter (bits %d hashes %d) into bloom filter (bits %d hashes %d)", other.bits, other.hashes, b.bits, b.hashes)
+	}
+
+	words := b.words.Value()
+	for i, word := range other.words.Value() {
+		words[i] |= word
+	}
+	return nil
+}
+
+// Returns the number of bits in the filter.
+func (b *Bloom) Bits() uint64 {
+	return b.bits
+}
+
+// Returns the number of hash functions used by the filter.
+func (b *Bloom) Hashes() int {
+	return b.hashes
+}
+
+// Writes the serialised filter to w. Returns the number of bytes written.
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	n, err := writeHeader(w, bloomMagic, b.bits, uint64(b.hashes))
+	if err != nil {
+		return n, err
+	}
+	wordsN, err := writeWords(w, b.words.Value())
+	return n + wordsN, err
+}
+
+// Reads a filter, written by Bloom.WriteTo, from r. The filter's bits will be
+// allocated in store. Filters larger than 1GiB can't be read.
+func ReadBloom(store *offheap.Store, r io.Reader) (*Bloom, error) {
+	bits, hashes, err := readHeader(r, bloomMagic)
+	if err != nil {
+		return nil, err
+	}
+	if bits == 0 || hashes == 0 || hashes > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid bloom filter (bits %d hashes %d)", bits, hashes)
+	}
+	if err := checkReadWords(r, bits/64+min(bits%64, 1)); err != nil {
+		return nil, fmt.Errorf("invalid bloom filter (bits %d hashes %d): %w", bits, hashes, err)
+	}
+
+	b := NewBloom(store, bits, int(hashes))
+	if err := readWords(r, b.words.Value()); err != nil {
+		b.Free()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Frees the memory used by the filter. After this call returns the filter must
+// never be used again.
+func (b *Bloom) Free() {
+	offheap.FreeSlice(b.store, b.words)
+	b.words = offheap.RefSlice[uint64]{}
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package sketch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloom_BadConfig_Panics(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() { NewBloom(store, 0, 1) })
+	assert.Panics(t, func() { NewBloom(store, 64, 0) })
+	assert.Panics(t, func() { NewBloomWithEstimates(store, 0, 0.01) })
+	assert.Panics(t, func() { NewBloomWithEstimates(store, 100, 0) })
+	assert.Panics(t, func() { NewBloomWithEstimates(store, 100, 1) })
+}
+
+func TestBloom_Estimates(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewBloomWithEstimates(store, 1_000_000, 0.01)
+
+	// ~9.6 bits per item and 7 hashes for a 1% false positive rate
+	assert.Equal(t, uint64(9_585_059), b.Bits())
+	assert.Equal(t, 7, b.Hashes())
+}
+
+// Show that there are no false negatives, and that the false positive rate is
+// close to the configured rate
+func TestBloom_AddTest(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewBloomWithEstimates(store, 100_000, 0.01)
+
+	for i := range 100_000 {
+		b.AddString(fmt.Sprintf("item-%d", i))
+	}
+
+	for i := range 100_000 {
+		require.True(t, b.TestString(fmt.Sprintf("item-%d", i)))
+		require.True(t, b.Test([]byte(fmt.Sprintf("item-%d", i))))
+	}
+
+	falsePositives := 0
+	for i := range 100_000 {
+		if b.TestString(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 1500)
+}
+
+func TestBloom_Merge(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	a := NewBloom(store, 1<<16, 4)
+	b := NewBloom(store, 1<<16, 4)
+
+	for i := range 1000 {
+		a.AddString(fmt.Sprintf("a-%d", i))
+		b.AddString(fmt.Sprintf("b-%d", i))
+	}
+
+	require.NoError(t, a.Merge(b))
+	for i := range 1000 {
+		require.True(t, a.TestString(fmt.Sprintf("a-%d", i)))
+		require.True(t, a.TestString(fmt.Sprintf("b-%d", i)))
+	}
+
+	// Filters with different dimensions can't be merged
+	assert.Error(t, a.Merge(NewBloom(store, 1<<16, 3)))
+	assert.Error(t, a.Merge(NewBloom(store, 1<<15, 4)))
+}
+
+func TestBloom_Serialization(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewBloom(store, 100_003, 5)
+	for i := range 1000 {
+		b.AddString(fmt.Sprintf("item-%d", i))
+	}
+
+	buf := bytes.Buffer{}
+	n, err := b.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	read, err := ReadBloom(store, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, b.Bits(), read.Bits())
+	assert.Equal(t, b.Hashes(), read.Hashes())
+	assert.Equal(t, b.words.Value(), read.words.Value())
+
+	// Truncated input is rejected
+	_, err = ReadBloom(store, bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Error(t, err)
+
+	// A count-min sketch can't be read as a Bloom filter
+	cmBuf := bytes.Buffer{}
+	_, err = NewCountMin(store, 10, 2).WriteTo(&cmBuf)
+	require.NoError(t, err)
+	_, err = ReadBloom(store, &cmBuf)
+	assert.Error(t, err)
+}
+
+// Show that headers requesting more memory than is reasonable, or more words
+// than the input holds, are rejected without allocating
+func TestBloom_ReadOversized(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	// Larger than the maximum, read from a reader which doesn't report its
+	// length
+	header := bytes.Buffer{}
+	_, err := writeHeader(&header, bloomMagic, math.MaxUint64, 2)
+	require.NoError(t, err)
+	_, err = ReadBloom(store, io.MultiReader(&header))
+	assert.Error(t, err)
+
+	// A small header, but the input holds fewer words than it describes
+	header.Reset()
+	_, err = writeHeader(&header, bloomMagic, 64*1000, 2)
+	require.NoError(t, err)
+	_, err = ReadBloom(store, bytes.NewReader(header.Bytes()))
+	assert.Error(t, err)
+
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Allocs)
+	}
+}
+
+// Show that freeing a filter releases its memory
+func TestBloom_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewBloom(store, 1<<20, 3)
+
+	b.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Assert that adding and testing items does not allocate
+func TestBloom_NoAllocations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	b := NewBloom(store, 1<<20, 3)
+	data := []byte("some item")
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		b.Add(data)
+		b.Test(data)
+		b.AddString("some string")
+		b.TestString("some string")
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
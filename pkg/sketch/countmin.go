@@ -0,0 +1,164 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package sketch
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// A count-min sketch. The sketch is a table of counters with depth rows and
+// width columns. Each item is counted in one column of every row, and its
+// frequency is estimated as the smallest of those counters.
+type CountMin struct {
+	store    *offheap.Store
+	width    uint64
+	depth    uint64
+	counters offheap.RefSlice[uint64]
+}
+
+// Creates a new empty count-min sketch with the given width and depth. The
+// sketch's counters will be allocated in store. Panics if width or depth is
+// not positive.
+func NewCountMin(store *offheap.Store, width, depth int) *CountMin {
+	if width <= 0 || depth <= 0 {
+		panic(fmt.Errorf("count-min sketch must have positive width and depth, got width %d depth %d", width, depth))
+	}
+
+	return &CountMin{
+		store:    store,
+		width:    uint64(width),
+		depth:    uint64(depth),
+		counters: allocWords(store, width*depth),
+	}
+}
+
+// Creates a new empty count-min sketch whose estimates exceed the true
+// frequency by no more than epsilon * (the total of all counts), with
+// probability 1 - delta. Panics if epsilon or delta is not in the range
+// (0, 1).
+func NewCountMinWithEstimates(store *offheap.Store, epsilon, delta float64) *CountMin {
+	if epsilon <= 0 || epsilon >= 1 {
+		panic(fmt.Errorf("count-min sketch epsilon must be in the range (0, 1), got %f", epsilon))
+	}
+	if delta <= 0 || delta >= 1 {
+		panic(fmt.Errorf("count-min sketch delta must be in the range (0, 1), got %f", delta))
+	}
+
+	width := math.Ceil(math.E / epsilon)
+	depth := math.Ceil(math.Log(1 / delta))
+	return NewCountMin(store, int(width), int(depth))
+}
+
+// Adds count to the frequency of data.
+func (c *CountMin) Add(data []byte, count uint64) {
+	h1, h2 := hashBytes(data)
+	c.add(h1, h2, count)
+}
+
+// Adds count to the frequency of data.
+func (c *CountMin) AddString(data string, count uint64) {
+	h1, h2 := hashString(data)
+	c.add(h1, h2, count)
+}
+
+// Returns the estimated frequency of data. The estimate is never less than the
+// true frequency.
+func (c *CountMin) Estimate(data []byte) uint64 {
+	return c.estimate(hashBytes(data))
+}
+
+// Returns the estimated frequency of data. The estimate is never less than the
+// true frequency.
+func (c *CountMin) EstimateString(data string) uint64 {
+	return c.estimate(hashString(data))
+}
+
+func (c *CountMin) add(h1, h2, count uint64) {
+	counters := c.counters.Value()
+	for row := range c.depth {
+		counters[c.index(row, h1, h2)] += count
+	}
+}
+
+func (c *CountMin) estimate(h1, h2 uint64) uint64 {
+	counters := c.counters.Value()
+	estimate := uint64(math.MaxUint64)
+	for row := range c.depth {
+		estimate = min(estimate, counters[c.index(row, h1, h2)])
+	}
+	return estimate
+}
+
+// Returns the index of the counter for an item in row
+func (c *CountMin) index(row, h1, h2 uint64) uint64 {
+	return row*c.width + (h1+row*h2)%c.width
+}
+
+// Adds the counts in other to c. The sketches must have the same width and
+// depth, otherwise an error is returned and c is unchanged.
+func (c *CountMin) Merge(other *CountMin) error {
+	if c.width != other.width || c.depth != other.depth {
+		return fmt.Errorf("cannot merge count-min sketch (width %d depth %d) into count-min sketch (width %d depth %d)", other.width, other.depth, c.width, c.depth)
+	}
+
+	counters := c.counters.Value()
+	for i, counter := range other.counters.Value() {
+		counters[i] += counter
+	}
+	return nil
+}
+
+// Returns the number of counters in each row of the sketch.
+func (c *CountMin) Width() int {
+	return int(c.width)
+}
+
+// Returns the number of rows in the sketch.
+func (c *CountMin) Depth() int {
+	return int(c.depth)
+}
+
+// Writes the serialised sketch to w. Returns the number of bytes written.
+func (c *CountMin) WriteTo(w io.Writer) (int64, error) {
+	n, err := writeHeader(w, countMinMagic, c.width, c.depth)
+	if err != nil {
+		return n, err
+	}
+	countersN, err := writeWords(w, c.counters.Value())
+	return n + countersN, err
+}
+
+// Reads a sketch, written by CountMin.WriteTo, from r. The sketch's counters
+// will be allocated in store. Sketches larger than 1GiB can't be read.
+func ReadCountMin(store *offheap.Store, r io.Reader) (*CountMin, error) {
+	width, depth, err := readHeader(r, countMinMagic)
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 || depth == 0 || width > math.MaxInt32 || depth > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid count-min sketch (width %d depth %d)", width, depth)
+	}
+	if err := checkReadWords(r, width*depth); err != nil {
+		return nil, fmt.Errorf("invalid count-min sketch (width %d depth %d): %w", width, depth, err)
+	}
+
+	c := NewCountMin(store, int(width), int(depth))
+	if err := readWords(r, c.counters.Value()); err != nil {
+		c.Free()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Frees the memory used by the sketch. After this call returns the sketch must
+// never be used again.
+func (c *CountMin) Free() {
+	offheap.FreeSlice(c.store, c.counters)
+	c.counters = offheap.RefSlice[uint64]{}
+}
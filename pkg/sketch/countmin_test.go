@@ -0,0 +1,186 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package sketch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountMin_BadConfig_Panics(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() { NewCountMin(store, 0, 1) })
+	assert.Panics(t, func() { NewCountMin(store, 1, 0) })
+	assert.Panics(t, func() { NewCountMinWithEstimates(store, 0, 0.01) })
+	assert.Panics(t, func() { NewCountMinWithEstimates(store, 0.01, 1) })
+}
+
+func TestCountMin_Estimates(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	c := NewCountMinWithEstimates(store, 0.001, 0.01)
+
+	assert.Equal(t, 2719, c.Width())
+	assert.Equal(t, 5, c.Depth())
+}
+
+// Show that estimates are never below the true count, and are within the
+// configured error bound
+func TestCountMin_AddEstimate(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	c := NewCountMinWithEstimates(store, 0.001, 0.01)
+
+	total := uint64(0)
+	for i := range 10_000 {
+		count := uint64(i%10 + 1)
+		c.AddString(fmt.Sprintf("item-%d", i), count)
+		total += count
+	}
+
+	maxError := uint64(0.001 * float64(total))
+	for i := range 10_000 {
+		expected := uint64(i%10 + 1)
+		estimate := c.EstimateString(fmt.Sprintf("item-%d", i))
+		require.GreaterOrEqual(t, estimate, expected)
+		require.LessOrEqual(t, estimate, expected+maxError)
+		require.Equal(t, estimate, c.Estimate([]byte(fmt.Sprintf("item-%d", i))))
+	}
+
+	// Items which were never added have small estimates
+	assert.LessOrEqual(t, c.EstimateString("never-added"), maxError)
+}
+
+func TestCountMin_Merge(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	a := NewCountMin(store, 1000, 4)
+	b := NewCountMin(store, 1000, 4)
+
+	a.AddString("shared", 3)
+	b.AddString("shared", 4)
+	b.Add([]byte("only-b"), 5)
+
+	require.NoError(t, a.Merge(b))
+	assert.Equal(t, uint64(7), a.EstimateString("shared"))
+	assert.Equal(t, uint64(5), a.EstimateString("only-b"))
+
+	// Sketches with different dimensions can't be merged
+	assert.Error(t, a.Merge(NewCountMin(store, 1000, 3)))
+	assert.Error(t, a.Merge(NewCountMin(store, 999, 4)))
+	assert.Equal(t, uint64(7), a.EstimateString("shared"))
+}
+
+func TestCountMin_Serialization(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	c := NewCountMin(store, 1001, 3)
+	for i := range 1000 {
+		c.AddString(fmt.Sprintf("item-%d", i), uint64(i))
+	}
+
+	buf := bytes.Buffer{}
+	n, err := c.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	read, err := ReadCountMin(store, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, c.Width(), read.Width())
+	assert.Equal(t, c.Depth(), read.Depth())
+	for i := range 1000 {
+		item := fmt.Sprintf("item-%d", i)
+		require.Equal(t, c.EstimateString(item), read.EstimateString(item))
+	}
+
+	// Truncated input is rejected
+	_, err = ReadCountMin(store, bytes.NewReader(buf.Bytes()[:headerSize+10]))
+	assert.Error(t, err)
+
+	// A Bloom filter can't be read as a count-min sketch
+	bloomBuf := bytes.Buffer{}
+	_, err = NewBloom(store, 64, 2).WriteTo(&bloomBuf)
+	require.NoError(t, err)
+	_, err = ReadCountMin(store, &bloomBuf)
+	assert.Error(t, err)
+}
+
+// Show that headers requesting more memory than is reasonable, or more words
+// than the input holds, are rejected without allocating
+func TestCountMin_ReadOversized(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+
+	// Larger than the maximum, read from a reader which doesn't report its
+	// length
+	header := bytes.Buffer{}
+	_, err := writeHeader(&header, countMinMagic, math.MaxInt32, math.MaxInt32)
+	require.NoError(t, err)
+	_, err = ReadCountMin(store, io.MultiReader(&header))
+	assert.Error(t, err)
+
+	// A small header, but the input holds fewer words than it describes
+	header.Reset()
+	_, err = writeHeader(&header, countMinMagic, 1000, 4)
+	require.NoError(t, err)
+	_, err = ReadCountMin(store, bytes.NewReader(header.Bytes()))
+	assert.Error(t, err)
+
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Allocs)
+	}
+}
+
+// Show that freeing a sketch releases its memory
+func TestCountMin_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	c := NewCountMin(store, 1<<16, 4)
+
+	c.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Assert that adding and estimating items does not allocate
+func TestCountMin_NoAllocations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	c := NewCountMin(store, 1<<16, 4)
+	data := []byte("some item")
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		c.Add(data, 1)
+		c.Estimate(data)
+		c.AddString("some string", 1)
+		c.EstimateString("some string")
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}
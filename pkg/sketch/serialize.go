@@ -0,0 +1,113 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The serialised form of both structures is a header followed by the words of
+// the structure. All values are little endian.
+//
+//	magic   [4]byte
+//	version uint32
+//	a       uint64 // Bloom: bits,   CountMin: width
+//	b       uint64 // Bloom: hashes, CountMin: depth
+//	words   [n]uint64
+const serialVersion = 1
+
+var (
+	bloomMagic    = [4]byte{'M', 'M', 'B', 'F'}
+	countMinMagic = [4]byte{'M', 'M', 'C', 'M'}
+)
+
+const headerSize = 4 + 4 + 8 + 8
+
+// The number of bytes buffered when writing or reading words
+const wordBufferSize = 4096
+
+// The largest number of words, 1GiB, which will be read. The words are
+// allocated before they are read, so without a limit a corrupt header could
+// request an allocation of any size.
+const maxReadWords = 1 << 27
+
+// Returns an error if words can't be read from r. Either because words is
+// larger than maxReadWords or because r, which like bytes.Reader reports the
+// number of unread bytes it holds, is too short.
+func checkReadWords(r io.Reader, words uint64) error {
+	if words > maxReadWords {
+		return fmt.Errorf("cannot read %d words, larger than the maximum of %d", words, maxReadWords)
+	}
+	if lenReader, ok := r.(interface{ Len() int }); ok && uint64(lenReader.Len()) < words*8 {
+		return fmt.Errorf("cannot read %d words, only %d bytes remain", words, lenReader.Len())
+	}
+	return nil
+}
+
+func writeHeader(w io.Writer, magic [4]byte, a, b uint64) (int64, error) {
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic[:]...)
+	header = binary.LittleEndian.AppendUint32(header, serialVersion)
+	header = binary.LittleEndian.AppendUint64(header, a)
+	header = binary.LittleEndian.AppendUint64(header, b)
+
+	n, err := w.Write(header)
+	return int64(n), err
+}
+
+func readHeader(r io.Reader, magic [4]byte) (a, b uint64, err error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, fmt.Errorf("cannot read header: %w", err)
+	}
+
+	if [4]byte(header[:4]) != magic {
+		return 0, 0, fmt.Errorf("bad magic %q, expected %q", header[:4], magic[:])
+	}
+	if version := binary.LittleEndian.Uint32(header[4:8]); version != serialVersion {
+		return 0, 0, fmt.Errorf("unsupported version %d, expected %d", version, serialVersion)
+	}
+
+	a = binary.LittleEndian.Uint64(header[8:16])
+	b = binary.LittleEndian.Uint64(header[16:24])
+	return a, b, nil
+}
+
+func writeWords(w io.Writer, words []uint64) (int64, error) {
+	total := int64(0)
+	buf := make([]byte, 0, wordBufferSize)
+	for len(words) > 0 {
+		count := min(len(words), wordBufferSize/8)
+		buf = buf[:0]
+		for _, word := range words[:count] {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+		words = words[count:]
+
+		n, err := w.Write(buf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func readWords(r io.Reader, words []uint64) error {
+	buf := make([]byte, wordBufferSize)
+	for len(words) > 0 {
+		count := min(len(words), wordBufferSize/8)
+		if _, err := io.ReadFull(r, buf[:count*8]); err != nil {
+			return fmt.Errorf("cannot read words: %w", err)
+		}
+		for i := range words[:count] {
+			words[i] = binary.LittleEndian.Uint64(buf[i*8:])
+		}
+		words = words[count:]
+	}
+	return nil
+}
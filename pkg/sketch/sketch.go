@@ -0,0 +1,56 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The sketch package provides probabilistic data structures whose bits and
+// counters are allocated in an offheap.Store. This allows very large
+// structures, sized in the gigabytes, to be used without impacting garbage
+// collection.
+//
+// Bloom is a Bloom filter, which tests set membership with a configurable
+// false positive rate and no false negatives.
+//
+// CountMin is a count-min sketch, which estimates the frequency of items. The
+// estimate is never less than the true frequency, and exceeds it by a bounded
+// amount with a configurable probability.
+//
+// Items are hashed with xxhash. Both structures can be merged with another
+// structure of the same dimensions, and can be written to, and read from, an
+// io.Writer/io.Reader.
+//
+// Neither type is safe for concurrent use.
+package sketch
+
+import (
+	xxhash "github.com/cespare/xxhash/v2"
+)
+
+// Returns a pair of hashes of data. Bloom filters and count-min sketches need
+// many hash functions, which are derived as h1 + i*h2 (Kirsch and Mitzenmacher
+// double hashing).
+//
+// The two hashes are not independent, h2 is derived from h1 by deriveHash.
+// So values whose h1 collide also have colliding h2, and share every derived
+// hash.
+func hashBytes(data []byte) (h1, h2 uint64) {
+	h1 = xxhash.Sum64(data)
+	return h1, deriveHash(h1)
+}
+
+// Returns the same hashes as hashBytes for the bytes of data.
+func hashString(data string) (h1, h2 uint64) {
+	h1 = xxhash.Sum64String(data)
+	return h1, deriveHash(h1)
+}
+
+// Derives a second hash from h using the splitmix64 finaliser. The result is
+// always odd, so that h1 + i*h2 never degenerates to the same value for every
+// i.
+func deriveHash(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h | 1
+}
@@ -0,0 +1,314 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+// The skiplist package provides a concurrent ordered map whose nodes, and
+// their towers of forward references, are allocated in an offheap.Store.
+//
+// Concurrency:
+//
+// Reads (Get, Ascend and AscendRange) and writes (Put) can all proceed
+// concurrently. Each node has its own lock which protects its value and its
+// tower. Readers only ever hold a read lock on a single node at a time, and a
+// writer only locks the nodes immediately preceding the node it is inserting.
+// This means readers can scan the list while writers insert into it.
+//
+// Delete is the exception. Because we have no way of knowing when a
+// concurrent reader has finished with a node, a node can only be freed when
+// there are no concurrent readers. Delete takes an exclusive lock on the
+// entire list, it will wait for all in-progress reads and writes to complete
+// and will block new ones until it is done.
+package skiplist
+
+import (
+	"cmp"
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fmstephe/memorymanager/offheap"
+)
+
+// The maximum height of any tower. With a branching factor of 4 this
+// comfortably supports lists with billions of entries.
+const maxHeight = 16
+
+type node[K cmp.Ordered, V any] struct {
+	// lock protects value and the references in tower. The key, and the
+	// height of the tower, never change once the node is in the list.
+	lock  sync.RWMutex
+	key   K
+	value V
+	tower offheap.RefSlice[offheap.RefObject[node[K, V]]]
+}
+
+// Returns the reference to the next node at level. Takes a read lock on n.
+func (n *node[K, V]) next(level int) offheap.RefObject[node[K, V]] {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.tower.Value()[level]
+}
+
+// Returns the value of n. Takes a read lock on n.
+func (n *node[K, V]) getValue() V {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.value
+}
+
+// Sets the value of n. Takes a write lock on n.
+func (n *node[K, V]) setValue(value V) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.value = value
+}
+
+// A concurrent ordered map from K to V.
+//
+// Neither K nor V may contain pointers. In particular string keys are not
+// supported. Creating a SkipList with a key or value type which contains
+// pointers will panic.
+type SkipList[K cmp.Ordered, V any] struct {
+	store *offheap.Store
+	// Puts and reads hold the read lock, Delete holds the write lock
+	deleteLock sync.RWMutex
+	head       offheap.RefObject[node[K, V]]
+	length     atomic.Int64
+}
+
+// Creates a new empty SkipList whose nodes will be allocated in store.
+func New[K cmp.Ordered, V any](store *offheap.Store) *SkipList[K, V] {
+	return &SkipList[K, V]{
+		store: store,
+		head:  allocNode[K, V](store, maxHeight),
+	}
+}
+
+func allocNode[K cmp.Ordered, V any](store *offheap.Store, height int) offheap.RefObject[node[K, V]] {
+	ref := offheap.AllocObject[node[K, V]](store)
+	n := ref.Value()
+	n.lock = sync.RWMutex{}
+	n.tower = offheap.AllocSlice[offheap.RefObject[node[K, V]]](store, height, height)
+	clear(n.tower.Value())
+	return ref
+}
+
+func freeNode[K cmp.Ordered, V any](store *offheap.Store, ref offheap.RefObject[node[K, V]]) {
+	offheap.FreeSlice(store, ref.Value().tower)
+	offheap.FreeObject(store, ref)
+}
+
+// Returns a random tower height, each level is 1/4 as likely as the level
+// below it.
+func randomHeight() int {
+	height := 1 + bits.TrailingZeros64(rand.Uint64())/2
+	return min(height, maxHeight)
+}
+
+// Returns the value associated with key, and true. If key is not in the list
+// the zero value of V and false are returned.
+func (l *SkipList[K, V]) Get(key K) (V, bool) {
+	l.deleteLock.RLock()
+	defer l.deleteLock.RUnlock()
+
+	var preds [maxHeight]offheap.RefObject[node[K, V]]
+	if found := l.findPreds(key, &preds); !found.IsNil() {
+		return found.Value().getValue(), true
+	}
+	var zero V
+	return zero, false
+}
+
+// Associates value with key, replacing any existing value.
+func (l *SkipList[K, V]) Put(key K, value V) {
+	l.deleteLock.RLock()
+	defer l.deleteLock.RUnlock()
+
+	var preds [maxHeight]offheap.RefObject[node[K, V]]
+	if found := l.findPreds(key, &preds); !found.IsNil() {
+		found.Value().setValue(value)
+		return
+	}
+
+	height := randomHeight()
+	newRef := allocNode[K, V](l.store, height)
+	newNode := newRef.Value()
+	newNode.key = key
+	newNode.value = value
+
+	// Link the new node into each level from the bottom up. Linking into
+	// level 0 is the moment the node becomes visible to readers.
+	for level := 0; level < height; level++ {
+		if !l.link(preds[level], newRef, newNode, level) {
+			// Another writer inserted key before us
+			freeNode(l.store, newRef)
+			return
+		}
+	}
+
+	l.length.Add(1)
+}
+
+// Links newNode into level after pred. Other writers may have inserted nodes
+// after pred since we found it, so we move forward until we find the correct
+// position.
+//
+// Returns false if, at level 0, we find that key has already been inserted by
+// another writer. In this case the existing node's value is updated and the
+// new node is not linked.
+func (l *SkipList[K, V]) link(predRef, newRef offheap.RefObject[node[K, V]], newNode *node[K, V], level int) bool {
+	for {
+		pred := predRef.Value()
+		pred.lock.Lock()
+
+		nextRef := pred.tower.Value()[level]
+		if !nextRef.IsNil() {
+			next := nextRef.Value()
+			if next.key < newNode.key {
+				// Move forward
+				pred.lock.Unlock()
+				predRef = nextRef
+				continue
+			}
+			if next.key == newNode.key {
+				// Only possible at level 0, because we
+				// link the new node into level 0 first
+				pred.lock.Unlock()
+				next.setValue(newNode.value)
+				return false
+			}
+		}
+
+		// newNode may already be visible to readers at lower levels
+		newNode.lock.Lock()
+		newNode.tower.Value()[level] = nextRef
+		newNode.lock.Unlock()
+
+		pred.tower.Value()[level] = newRef
+		pred.lock.Unlock()
+		return true
+	}
+}
+
+// Removes key from the list. Returns true if key was in the list, false
+// otherwise.
+//
+// Delete waits for all in-progress operations to complete, and blocks all
+// other operations until it is done.
+func (l *SkipList[K, V]) Delete(key K) bool {
+	l.deleteLock.Lock()
+	defer l.deleteLock.Unlock()
+
+	var preds [maxHeight]offheap.RefObject[node[K, V]]
+	foundRef := l.findPreds(key, &preds)
+	if foundRef.IsNil() {
+		return false
+	}
+
+	// We hold the exclusive lock, no node locks are needed
+	tower := foundRef.Value().tower.Value()
+	for level := range tower {
+		preds[level].Value().tower.Value()[level] = tower[level]
+	}
+	freeNode(l.store, foundRef)
+
+	l.length.Add(-1)
+	return true
+}
+
+// Returns the number of keys in the list.
+func (l *SkipList[K, V]) Len() int {
+	return int(l.length.Load())
+}
+
+// Calls fun for each key/value in the list in ascending order of key. If fun
+// returns false the iteration stops.
+//
+// Concurrent Puts may, or may not, be observed by the iteration. fun must not
+// call Delete, because Delete waits for this iteration to complete.
+func (l *SkipList[K, V]) Ascend(fun func(key K, value V) bool) {
+	l.deleteLock.RLock()
+	defer l.deleteLock.RUnlock()
+
+	l.ascendFrom(l.head.Value().next(0), func(key K) bool { return true }, fun)
+}
+
+// Calls fun for each key/value in the list, where from <= key < to, in
+// ascending order of key. If fun returns false the iteration stops.
+//
+// Concurrent Puts may, or may not, be observed by the iteration. fun must not
+// call Delete, because Delete waits for this iteration to complete.
+func (l *SkipList[K, V]) AscendRange(from, to K, fun func(key K, value V) bool) {
+	l.deleteLock.RLock()
+	defer l.deleteLock.RUnlock()
+
+	var preds [maxHeight]offheap.RefObject[node[K, V]]
+	start := l.findPreds(from, &preds)
+	if start.IsNil() {
+		start = preds[0].Value().next(0)
+	}
+	l.ascendFrom(start, func(key K) bool { return key < to }, fun)
+}
+
+func (l *SkipList[K, V]) ascendFrom(ref offheap.RefObject[node[K, V]], inRange func(key K) bool, fun func(key K, value V) bool) {
+	for !ref.IsNil() {
+		n := ref.Value()
+		if !inRange(n.key) {
+			return
+		}
+		if !fun(n.key, n.getValue()) {
+			return
+		}
+		ref = n.next(0)
+	}
+}
+
+// Finds the predecessor of key at every level, storing them in preds. If key
+// is in the list the reference to its node is returned, otherwise a nil
+// reference is returned.
+func (l *SkipList[K, V]) findPreds(key K, preds *[maxHeight]offheap.RefObject[node[K, V]]) offheap.RefObject[node[K, V]] {
+	var found offheap.RefObject[node[K, V]]
+
+	predRef := l.head
+	pred := predRef.Value()
+	for level := maxHeight - 1; level >= 0; level-- {
+		for {
+			nextRef := pred.next(level)
+			if nextRef.IsNil() {
+				break
+			}
+			next := nextRef.Value()
+			if next.key >= key {
+				if next.key == key {
+					found = nextRef
+				}
+				break
+			}
+			predRef = nextRef
+			pred = next
+		}
+		preds[level] = predRef
+	}
+
+	return found
+}
+
+// Frees all of the nodes in the list. After this call returns the list must
+// never be used again.
+func (l *SkipList[K, V]) Free() {
+	l.deleteLock.Lock()
+	defer l.deleteLock.Unlock()
+
+	ref := l.head
+	for !ref.IsNil() {
+		next := ref.Value().tower.Value()[0]
+		freeNode(l.store, ref)
+		ref = next
+	}
+	l.head = offheap.RefObject[node[K, V]]{}
+	l.length.Store(0)
+}
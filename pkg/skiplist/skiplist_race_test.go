@@ -0,0 +1,162 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package skiplist
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const keysPerGoroutine = 1000
+const goroutines = 50
+
+// Demonstrate that multiple goroutines can insert interleaved keys into a
+// shared list, while other goroutines scan it. Every scan must observe keys
+// in strictly ascending order.
+// This test should be run with -race
+func TestConcurrentPutAndScan_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int64, int64](store)
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+	writersDone := atomic.Bool{}
+
+	writers := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			barrier.Wait()
+			// Keys from each goroutine are interleaved with
+			// every other goroutine's keys
+			for k := int64(0); k < keysPerGoroutine; k++ {
+				key := k*goroutines + int64(i)
+				list.Put(key, key*2)
+			}
+		}()
+	}
+
+	readers := sync.WaitGroup{}
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			barrier.Wait()
+			for scans := 0; scans < 10 && !writersDone.Load(); scans++ {
+				scanInOrder(t, list)
+			}
+		}()
+	}
+
+	barrier.Done()
+	writers.Wait()
+	writersDone.Store(true)
+	readers.Wait()
+
+	assert.Equal(t, goroutines*keysPerGoroutine, list.Len())
+	assert.Equal(t, goroutines*keysPerGoroutine, scanInOrder(t, list))
+	for key := int64(0); key < goroutines*keysPerGoroutine; key++ {
+		value, ok := list.Get(key)
+		require.True(t, ok)
+		require.Equal(t, key*2, value)
+	}
+}
+
+// Demonstrate that multiple goroutines can race to Put the same keys. Each
+// key is inserted exactly once.
+// This test should be run with -race
+func TestSharedKeys_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int64, int64](store)
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+
+	complete := sync.WaitGroup{}
+	for range goroutines {
+		complete.Add(1)
+		go func() {
+			defer complete.Done()
+			barrier.Wait()
+			for key := int64(0); key < keysPerGoroutine; key++ {
+				list.Put(key, key)
+			}
+		}()
+	}
+
+	barrier.Done()
+	complete.Wait()
+
+	assert.Equal(t, keysPerGoroutine, list.Len())
+	assert.Equal(t, keysPerGoroutine, scanInOrder(t, list))
+}
+
+// Demonstrate that deletes can run alongside puts and scans. Each goroutine
+// inserts, scans and then deletes its own keys.
+// This test should be run with -race
+func TestConcurrentPutAndDelete_Race(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int64, int64](store)
+
+	barrier := sync.WaitGroup{}
+	barrier.Add(1)
+
+	complete := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		complete.Add(1)
+		go func() {
+			defer complete.Done()
+			barrier.Wait()
+			first := int64(i) * keysPerGoroutine
+			for key := first; key < first+keysPerGoroutine; key++ {
+				list.Put(key, key)
+			}
+			count := 0
+			list.AscendRange(first, first+keysPerGoroutine, func(key, value int64) bool {
+				assert.Equal(t, first+int64(count), key)
+				count++
+				return true
+			})
+			assert.Equal(t, keysPerGoroutine, count)
+			for key := first; key < first+keysPerGoroutine; key++ {
+				assert.True(t, list.Delete(key))
+			}
+		}()
+	}
+
+	barrier.Done()
+	complete.Wait()
+
+	assert.Equal(t, 0, list.Len())
+}
+
+// Scans the entire list, asserting that keys are strictly ascending. Returns
+// the number of keys observed.
+func scanInOrder(t *testing.T, list *SkipList[int64, int64]) int {
+	count := 0
+	prev := int64(-1)
+	list.Ascend(func(key, value int64) bool {
+		count++
+		assert.Less(t, prev, key)
+		prev = key
+		return true
+	})
+	return count
+}
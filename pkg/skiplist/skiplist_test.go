@@ -0,0 +1,251 @@
+// Copyright 2024 Francis Michael Stephens. All rights reserved.  Use of this
+// source code is governed by an MIT license that can be found in the LICENSE
+// file.
+
+package skiplist
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/fmstephe/memorymanager/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Show that an empty list behaves sensibly
+func TestSkipList_Empty(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int, int](store)
+
+	assert.Equal(t, 0, list.Len())
+
+	_, ok := list.Get(1)
+	assert.False(t, ok)
+
+	assert.False(t, list.Delete(1))
+
+	list.Ascend(func(key, value int) bool {
+		t.Errorf("unexpected key %d", key)
+		return true
+	})
+	list.AscendRange(0, 100, func(key, value int) bool {
+		t.Errorf("unexpected key %d", key)
+		return true
+	})
+}
+
+// Show that string keys are rejected, because they contain pointers
+func TestSkipList_StringKeys_Panic(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	assert.Panics(t, func() {
+		New[string, int](store)
+	})
+}
+
+func TestSkipList_PutGet(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int, int](store)
+
+	r := rand.New(rand.NewSource(1))
+	for _, key := range r.Perm(10_000) {
+		list.Put(key, key*2)
+	}
+	assert.Equal(t, 10_000, list.Len())
+
+	for i := range 10_000 {
+		value, ok := list.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i*2, value)
+	}
+
+	// Replacing values does not change the length
+	for i := range 10_000 {
+		list.Put(i, i*3)
+	}
+	assert.Equal(t, 10_000, list.Len())
+
+	for i := range 10_000 {
+		value, ok := list.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i*3, value)
+	}
+
+	_, ok := list.Get(-1)
+	assert.False(t, ok)
+	_, ok = list.Get(10_000)
+	assert.False(t, ok)
+}
+
+// Perform a long sequence of random puts and deletes, comparing the list with
+// a conventional map after every operation.
+func TestSkipList_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int64, int64](store)
+	expected := map[int64]int64{}
+
+	for range 50_000 {
+		key := r.Int63n(5_000)
+		if r.Intn(3) == 0 {
+			_, inMap := expected[key]
+			delete(expected, key)
+			require.Equal(t, inMap, list.Delete(key))
+		} else {
+			value := r.Int63()
+			expected[key] = value
+			list.Put(key, value)
+		}
+		require.Equal(t, len(expected), list.Len())
+	}
+
+	for key, expectedValue := range expected {
+		value, ok := list.Get(key)
+		require.True(t, ok)
+		require.Equal(t, expectedValue, value)
+	}
+
+	count := 0
+	prev := int64(-1)
+	list.Ascend(func(key, value int64) bool {
+		count++
+		require.Less(t, prev, key)
+		require.Equal(t, expected[key], value)
+		prev = key
+		return true
+	})
+	assert.Equal(t, len(expected), count)
+}
+
+func TestSkipList_Ascend(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int, int](store)
+
+	r := rand.New(rand.NewSource(1))
+	for _, key := range r.Perm(1000) {
+		list.Put(key, key)
+	}
+
+	ascending := []int{}
+	list.Ascend(func(key, value int) bool {
+		ascending = append(ascending, key)
+		return true
+	})
+	for i := range ascending {
+		require.Equal(t, i, ascending[i])
+	}
+	assert.Equal(t, 1000, len(ascending))
+
+	// Iteration stops when fun returns false
+	count := 0
+	list.Ascend(func(key, value int) bool {
+		count++
+		return key < 99
+	})
+	assert.Equal(t, 100, count)
+}
+
+func TestSkipList_AscendRange(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int, int](store)
+
+	// Insert only even keys
+	for i := 0; i < 2000; i += 2 {
+		list.Put(i, i)
+	}
+
+	for _, bounds := range [][2]int{
+		{0, 2000},
+		{-100, 5000},
+		{1, 2},
+		{1, 3},
+		{500, 501},
+		{501, 800},
+		{1500, 1000},
+		{1998, 1999},
+	} {
+		t.Run(fmt.Sprintf("from %d to %d", bounds[0], bounds[1]), func(t *testing.T) {
+			from, to := bounds[0], bounds[1]
+
+			expected := []int{}
+			for i := 0; i < 2000; i += 2 {
+				if i >= from && i < to {
+					expected = append(expected, i)
+				}
+			}
+
+			actual := []int{}
+			list.AscendRange(from, to, func(key, value int) bool {
+				actual = append(actual, key)
+				return true
+			})
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+// Show that deleting keys, and freeing the list, releases all of the node
+// and tower allocations
+func TestSkipList_Free(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int, int](store)
+
+	for i := range 10_000 {
+		list.Put(i, i)
+	}
+	for i := range 10_000 {
+		require.True(t, list.Delete(i))
+	}
+	// Only the head node, and its tower, remain
+	live := 0
+	for _, stats := range store.Stats() {
+		live += stats.Live
+	}
+	assert.Equal(t, 2, live)
+
+	list.Free()
+	for _, stats := range store.Stats() {
+		assert.Equal(t, 0, stats.Live)
+	}
+}
+
+// Assert that reading from the list does not allocate
+func TestSkipList_Get_NoAllocations(t *testing.T) {
+	store := offheap.NewSized(1 << 8)
+	defer func() {
+		assert.NoError(t, store.Destroy())
+	}()
+	list := New[int64, int64](store)
+	for i := range int64(1000) {
+		list.Put(i, i)
+	}
+
+	avgAllocs := testing.AllocsPerRun(100, func() {
+		for i := range int64(1000) {
+			list.Get(i)
+		}
+	})
+	assert.Equal(t, 0.0, avgAllocs)
+}